@@ -0,0 +1,331 @@
+// Command bookctl books and unbooks ArgoCD applications from the terminal or CI
+// by calling the booking backend through the ArgoCD extension proxy.
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/behavox/argocd-book-plugin/pkg/bookingclient"
+)
+
+// Exit codes. Scripts can rely on these to distinguish failure modes.
+const (
+	exitOK        = 0
+	exitError     = 1
+	exitUsage     = 2
+	exitConflict  = 3 // the application is booked by someone else
+	exitForbidden = 4 // the caller may not perform the action
+)
+
+const usage = `Usage: bookctl [flags] <command> [args]
+
+Commands:
+  status   <app>          Show the booking status of an application
+  book     [--wait D] [--reason R] [--duration D] <app>
+                          Book an application for the current user; with --wait,
+                          block up to D until it is free instead of failing;
+                          --duration releases the booking automatically
+  unbook   <app>          Unbook an application (holder or admin only)
+  transfer <app> <user>   Hand your booking over to another user
+  list     [--user U] [--project P] [--selector S] [--expires-within D] [--sort K]
+                          List booked applications
+  apps     [--state booked|free] [--project P] [--selector S]
+                          List all applications with booking, sync and health state
+
+<app> is "namespace:name" or "namespace/name"; a bare name uses the argocd namespace.
+The ArgoCD proxy authorises every request against an application, so list and apps
+need --app naming any application you can read.
+With --dry-run, book, unbook and transfer run every server-side check and exit as
+they would, without changing anything.
+
+Flags:
+`
+
+type options struct {
+	server    string
+	token     string
+	extension string
+	project   string
+	namespace string
+	app       string
+	output    string
+	insecure  bool
+	dryRun    bool
+	timeout   time.Duration
+}
+
+func main() {
+	os.Exit(run(os.Args[1:], os.Stdout, os.Stderr))
+}
+
+func run(args []string, stdout, stderr io.Writer) int {
+	var opts options
+	fs := flag.NewFlagSet("bookctl", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	fs.StringVar(&opts.server, "server", os.Getenv("ARGOCD_SERVER"), "ArgoCD server address (env ARGOCD_SERVER)")
+	fs.StringVar(&opts.token, "auth-token", os.Getenv("ARGOCD_AUTH_TOKEN"), "ArgoCD auth token (env ARGOCD_AUTH_TOKEN)")
+	fs.StringVar(&opts.extension, "extension", "booking", "name of the ArgoCD proxy extension")
+	fs.StringVar(&opts.project, "project", "default", "ArgoCD project of the application")
+	fs.StringVar(&opts.namespace, "namespace", "argocd", "namespace for list")
+	fs.StringVar(&opts.app, "app", "", "application the ArgoCD proxy authorises list and apps against")
+	fs.StringVar(&opts.output, "o", "table", "output format: table or json")
+	fs.BoolVar(&opts.insecure, "insecure", false, "skip TLS certificate verification")
+	fs.BoolVar(&opts.dryRun, "dry-run", false, "check book, unbook and transfer without changing anything")
+	fs.DurationVar(&opts.timeout, "timeout", 30*time.Second, "request timeout")
+	fs.Usage = func() {
+		fmt.Fprint(stderr, usage)
+		fs.PrintDefaults()
+	}
+
+	if err := fs.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return exitOK
+		}
+		return exitUsage
+	}
+	if opts.output != "table" && opts.output != "json" {
+		fmt.Fprintf(stderr, "invalid output format %q\n", opts.output)
+		return exitUsage
+	}
+	rest := fs.Args()
+	if len(rest) == 0 {
+		fs.Usage()
+		return exitUsage
+	}
+	if opts.server == "" || opts.token == "" {
+		fmt.Fprintln(stderr, "--server and --auth-token (or ARGOCD_SERVER and ARGOCD_AUTH_TOKEN) are required")
+		return exitUsage
+	}
+
+	clientOpts := []bookingclient.Option{
+		bookingclient.WithToken(opts.token),
+		bookingclient.WithProject(opts.project),
+		bookingclient.WithTimeout(opts.timeout),
+	}
+	if opts.insecure {
+		clientOpts = append(clientOpts, bookingclient.WithInsecureSkipVerify())
+	}
+	if opts.dryRun {
+		clientOpts = append(clientOpts, bookingclient.WithDryRun())
+	}
+	if opts.app != "" {
+		clientOpts = append(clientOpts, bookingclient.WithAnchorApp(appID(opts.app)))
+	}
+	c := bookingclient.New(bookingclient.ProxyURL(opts.server, opts.extension), clientOpts...)
+	ctx := context.Background()
+	cmd, cmdArgs := rest[0], rest[1:]
+
+	want := map[string]int{"status": 1, "book": 1, "unbook": 1, "transfer": 2, "list": 0, "apps": 0}
+	n, ok := want[cmd]
+	if !ok {
+		fmt.Fprintf(stderr, "unknown command %q\n", cmd)
+		return exitUsage
+	}
+	if (cmd == "list" || cmd == "apps") && opts.app == "" {
+		fmt.Fprintf(stderr, "%s requires --app: the ArgoCD proxy authorises every request against an application\n", cmd)
+		return exitUsage
+	}
+
+	var bookOpts bookingclient.BookOptions
+	if cmd == "book" {
+		bookFlags := flag.NewFlagSet("book", flag.ContinueOnError)
+		bookFlags.SetOutput(stderr)
+		bookFlags.DurationVar(&bookOpts.Wait, "wait", 0, "wait up to this long for the application to become free")
+		bookFlags.StringVar(&bookOpts.Reason, "reason", "", "why you need the application, shown to others")
+		bookFlags.DurationVar(&bookOpts.Duration, "duration", 0, "release the booking automatically after this long")
+		if err := bookFlags.Parse(cmdArgs); err != nil {
+			return exitUsage
+		}
+		cmdArgs = bookFlags.Args()
+	}
+	listOpts := bookingclient.ListOptions{Namespace: opts.namespace}
+	if cmd == "list" {
+		listFlags := flag.NewFlagSet("list", flag.ContinueOnError)
+		listFlags.SetOutput(stderr)
+		listFlags.StringVar(&listOpts.User, "user", "", "only bookings held by this user")
+		listFlags.StringVar(&listOpts.Project, "project", "", "only applications in this project")
+		listFlags.StringVar(&listOpts.Selector, "selector", "", "label selector on the Application labels")
+		listFlags.DurationVar(&listOpts.ExpiresWithin, "expires-within", 0, "only bookings expiring within this duration")
+		listFlags.StringVar(&listOpts.Sort, "sort", "", "bookedAt or appName, prefix with - to reverse")
+		if err := listFlags.Parse(cmdArgs); err != nil {
+			return exitUsage
+		}
+		cmdArgs = listFlags.Args()
+	}
+	appOpts := bookingclient.ApplicationOptions{ListOptions: bookingclient.ListOptions{Namespace: opts.namespace}}
+	if cmd == "apps" {
+		appFlags := flag.NewFlagSet("apps", flag.ContinueOnError)
+		appFlags.SetOutput(stderr)
+		appFlags.StringVar(&appOpts.State, "state", "", "booked or free")
+		appFlags.StringVar(&appOpts.Project, "project", "", "only applications in this project")
+		appFlags.StringVar(&appOpts.Selector, "selector", "", "label selector on the Application labels")
+		if err := appFlags.Parse(cmdArgs); err != nil {
+			return exitUsage
+		}
+		cmdArgs = appFlags.Args()
+	}
+	if len(cmdArgs) != n {
+		fmt.Fprintf(stderr, "%s expects %d argument(s), got %d\n", cmd, n, len(cmdArgs))
+		return exitUsage
+	}
+
+	var err error
+	switch cmd {
+	case "status":
+		var s bookingclient.Status
+		if s, err = c.Status(ctx, appID(cmdArgs[0])); err == nil {
+			printStatus(stdout, opts.output, appID(cmdArgs[0]), s)
+		}
+	case "book":
+		if err = c.Book(ctx, appID(cmdArgs[0]), bookOpts); err == nil {
+			printResult(stdout, opts, "booked", appID(cmdArgs[0]))
+		}
+	case "unbook":
+		if err = c.Unbook(ctx, appID(cmdArgs[0])); err == nil {
+			printResult(stdout, opts, "unbooked", appID(cmdArgs[0]))
+		}
+	case "transfer":
+		if err = c.Transfer(ctx, appID(cmdArgs[0]), cmdArgs[1]); err == nil {
+			printResult(stdout, opts, "transferred", appID(cmdArgs[0]))
+		}
+	case "list":
+		var bookings []bookingclient.Booking
+		if bookings, err = c.ListAll(ctx, listOpts); err == nil {
+			printList(stdout, opts.output, bookings)
+		}
+	case "apps":
+		var apps []bookingclient.Application
+		if apps, err = listApplications(ctx, c, appOpts); err == nil {
+			printApplications(stdout, opts.output, apps)
+		}
+	}
+	return exitCode(err, stderr)
+}
+
+// appID normalises an application reference to the "namespace:name" header format.
+func appID(ref string) string {
+	if strings.Contains(ref, ":") {
+		return ref
+	}
+	if ns, name, ok := strings.Cut(ref, "/"); ok {
+		return ns + ":" + name
+	}
+	return "argocd:" + ref
+}
+
+func exitCode(err error, stderr io.Writer) int {
+	if err == nil {
+		return exitOK
+	}
+	fmt.Fprintf(stderr, "error: %v\n", err)
+	switch {
+	case bookingclient.IsConflict(err):
+		return exitConflict
+	case bookingclient.IsForbidden(err):
+		return exitForbidden
+	}
+	return exitError
+}
+
+func printJSON(w io.Writer, v interface{}) {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	enc.Encode(v)
+}
+
+func printStatus(w io.Writer, output, app string, s bookingclient.Status) {
+	if output == "json" {
+		printJSON(w, s)
+		return
+	}
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "APP\tBOOKED\tBOOKED BY\tBOOKED AT")
+	fmt.Fprintf(tw, "%s\t%t\t%s\t%s\n", app, s.Booked, dash(s.BookedBy), dash(s.BookedAt))
+	tw.Flush()
+}
+
+func printResult(w io.Writer, opts options, result, app string) {
+	if opts.output == "json" {
+		out := map[string]interface{}{"status": result, "app": app}
+		if opts.dryRun {
+			out["dryRun"] = true
+		}
+		printJSON(w, out)
+		return
+	}
+	if opts.dryRun {
+		fmt.Fprintf(w, "%s would be %s (dry run)\n", app, result)
+		return
+	}
+	fmt.Fprintf(w, "%s %s\n", app, result)
+}
+
+func printList(w io.Writer, output string, bookings []bookingclient.Booking) {
+	if output == "json" {
+		if bookings == nil {
+			bookings = []bookingclient.Booking{}
+		}
+		printJSON(w, bookings)
+		return
+	}
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "NAMESPACE\tAPP\tPROJECT\tBOOKED BY\tBOOKED AT\tEXPIRES AT")
+	for _, b := range bookings {
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\t%s\n",
+			b.Namespace, b.AppName, dash(b.Project), b.BookedBy, dash(b.BookedAt), dash(b.ExpiresAt))
+	}
+	tw.Flush()
+}
+
+// listApplications follows continuations and returns every matching application.
+func listApplications(ctx context.Context, c *bookingclient.Client, opts bookingclient.ApplicationOptions) ([]bookingclient.Application, error) {
+	var all []bookingclient.Application
+	for {
+		page, err := c.Applications(ctx, opts)
+		if err != nil {
+			return nil, err
+		}
+		all = append(all, page.Items...)
+		if page.Continue == "" {
+			return all, nil
+		}
+		opts.Continue = page.Continue
+	}
+}
+
+func printApplications(w io.Writer, output string, apps []bookingclient.Application) {
+	if output == "json" {
+		if apps == nil {
+			apps = []bookingclient.Application{}
+		}
+		printJSON(w, apps)
+		return
+	}
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "NAMESPACE\tAPP\tPROJECT\tDESTINATION\tSYNC\tHEALTH\tBOOKED BY")
+	for _, a := range apps {
+		bookedBy := "-"
+		if a.Booking != nil {
+			bookedBy = a.Booking.BookedBy
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\t%s\t%s\n",
+			a.Namespace, a.Name, dash(a.Project), dash(a.Destination.Namespace), dash(a.SyncStatus), dash(a.HealthStatus), bookedBy)
+	}
+	tw.Flush()
+}
+
+func dash(s string) string {
+	if s == "" {
+		return "-"
+	}
+	return s
+}
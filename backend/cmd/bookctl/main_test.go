@@ -0,0 +1,156 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/behavox/argocd-book-plugin/pkg/bookingclient"
+)
+
+// fakeBackend emulates the booking backend behind the ArgoCD extension proxy.
+func fakeBackend(t *testing.T) *httptest.Server {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer tok" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		app := r.Header.Get("Argocd-Application-Name")
+		if app == "" {
+			// The proxy cannot authorise a request naming no application.
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		var body map[string]string
+		json.NewDecoder(r.Body).Decode(&body)
+		switch r.URL.Path {
+		case "/extensions/booking/api/v1/status":
+			json.NewEncoder(w).Encode(bookingclient.Status{Booked: true, BookedBy: "alice", BookedAt: "2026-01-15T10:00:00Z"})
+		case "/extensions/booking/api/v1/book":
+			if app == "argocd:taken" && body["wait"] != "1m0s" {
+				w.WriteHeader(http.StatusConflict)
+				json.NewEncoder(w).Encode(map[string]string{"error": "conflict: application already booked by alice"})
+				return
+			}
+			json.NewEncoder(w).Encode(map[string]string{"status": "booked"})
+		case "/extensions/booking/api/v1/unbook":
+			w.WriteHeader(http.StatusForbidden)
+			json.NewEncoder(w).Encode(map[string]string{"error": "forbidden: application is booked by alice"})
+		case "/extensions/booking/api/v1/transfer":
+			if body["to"] != "bob" {
+				w.WriteHeader(http.StatusBadRequest)
+				return
+			}
+			json.NewEncoder(w).Encode(map[string]string{"status": "transferred"})
+		case "/extensions/booking/api/v1/list":
+			if r.URL.Query().Get("continue") == "" {
+				json.NewEncoder(w).Encode(bookingclient.BookingList{
+					Items:    []bookingclient.Booking{{AppName: "app1", Namespace: "argocd", BookedBy: r.URL.Query().Get("user")}},
+					Continue: "page2",
+				})
+				return
+			}
+			json.NewEncoder(w).Encode(bookingclient.BookingList{Items: []bookingclient.Booking{{AppName: "app2", Namespace: "argocd", BookedBy: "bob"}}})
+		case "/extensions/booking/api/v1/applications":
+			json.NewEncoder(w).Encode(map[string]interface{}{
+				"items": []map[string]interface{}{
+					{"name": "qa-1", "namespace": "argocd", "healthStatus": "Healthy"},
+					{"name": "qa-2", "namespace": "argocd", "booking": map[string]string{"bookedBy": "alice"}},
+				},
+			})
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func TestRun_ExitCodes(t *testing.T) {
+	srv := fakeBackend(t)
+
+	tests := []struct {
+		name string
+		args []string
+		want int
+	}{
+		{"status", []string{"status", "my-app"}, exitOK},
+		{"book", []string{"book", "argocd/my-app"}, exitOK},
+		{"book conflict", []string{"book", "taken"}, exitConflict},
+		{"book wait", []string{"book", "--wait", "1m", "taken"}, exitOK},
+		{"book dry run conflict", []string{"--dry-run", "book", "taken"}, exitConflict},
+		{"book with reason", []string{"book", "--reason", "load test", "--duration", "2h", "argocd/my-app"}, exitOK},
+		{"unbook forbidden", []string{"unbook", "argocd:my-app"}, exitForbidden},
+		{"transfer", []string{"transfer", "my-app", "bob"}, exitOK},
+		{"list", []string{"--app", "my-app", "list"}, exitOK},
+		{"apps", []string{"--app", "my-app", "apps", "--state", "free"}, exitOK},
+		{"list without app", []string{"list"}, exitUsage},
+		{"unknown command", []string{"frobnicate"}, exitUsage},
+		{"missing argument", []string{"book"}, exitUsage},
+	}
+	for _, tt := range tests {
+		var stdout, stderr bytes.Buffer
+		args := append([]string{"--server", srv.URL, "--auth-token", "tok"}, tt.args...)
+		if got := run(args, &stdout, &stderr); got != tt.want {
+			t.Errorf("%s: expected exit %d, got %d (stderr: %s)", tt.name, tt.want, got, stderr.String())
+		}
+	}
+}
+
+func TestRun_BadToken(t *testing.T) {
+	srv := fakeBackend(t)
+
+	var stdout, stderr bytes.Buffer
+	if got := run([]string{"--server", srv.URL, "--auth-token", "wrong", "status", "my-app"}, &stdout, &stderr); got != exitError {
+		t.Fatalf("expected exit %d, got %d", exitError, got)
+	}
+}
+
+func TestRun_Output(t *testing.T) {
+	srv := fakeBackend(t)
+
+	var stdout, stderr bytes.Buffer
+	run([]string{"--server", srv.URL, "--auth-token", "tok", "--app", "my-app", "list", "--user", "alice"}, &stdout, &stderr)
+	if !strings.Contains(stdout.String(), "BOOKED BY") || !strings.Contains(stdout.String(), "alice") || !strings.Contains(stdout.String(), "app2") {
+		t.Fatalf("unexpected table output:\n%s", stdout.String())
+	}
+
+	stdout.Reset()
+	run([]string{"--server", srv.URL, "--auth-token", "tok", "--app", "my-app", "apps"}, &stdout, &stderr)
+	if !strings.Contains(stdout.String(), "HEALTH") || !strings.Contains(stdout.String(), "Healthy") || !strings.Contains(stdout.String(), "alice") {
+		t.Fatalf("unexpected apps output:\n%s", stdout.String())
+	}
+
+	stdout.Reset()
+	run([]string{"--server", srv.URL, "--auth-token", "tok", "--dry-run", "book", "my-app"}, &stdout, &stderr)
+	if got := stdout.String(); got != "argocd:my-app would be booked (dry run)\n" {
+		t.Fatalf("unexpected dry-run output %q", got)
+	}
+
+	stdout.Reset()
+	run([]string{"--server", srv.URL, "--auth-token", "tok", "-o", "json", "status", "my-app"}, &stdout, &stderr)
+	var s bookingclient.Status
+	if err := json.Unmarshal(stdout.Bytes(), &s); err != nil {
+		t.Fatalf("invalid json output: %v\n%s", err, stdout.String())
+	}
+	if !s.Booked || s.BookedBy != "alice" {
+		t.Fatalf("unexpected status: %+v", s)
+	}
+}
+
+func TestAppID(t *testing.T) {
+	tests := map[string]string{
+		"my-app":      "argocd:my-app",
+		"team/my-app": "team:my-app",
+		"team:my-app": "team:my-app",
+	}
+	for in, want := range tests {
+		if got := appID(in); got != want {
+			t.Errorf("appID(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
@@ -0,0 +1,221 @@
+// Command kubectl-book is a kubectl plugin that reads and writes bookings directly
+// on Application annotations using the caller's kubeconfig. It shares the k8s
+// package with the backend, so validation and conflict rules are identical, but
+// it does not read the backend configuration: policies, booking limits and
+// pauseAutoSync do not apply to its bookings. Releasing a booking still restores
+// automated sync paused by a policy.
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/user"
+	"text/tabwriter"
+	"time"
+
+	"github.com/behavox/argocd-book-plugin/internal/k8s"
+)
+
+// Exit codes, matching bookctl.
+const (
+	exitOK        = 0
+	exitError     = 1
+	exitUsage     = 2
+	exitConflict  = 3 // the application is booked by someone else
+	exitForbidden = 4 // the caller may not perform the action
+)
+
+const usage = `Usage: kubectl book <command> [flags] [args]
+
+Commands:
+  get <app>             Show the booking status of an application
+  book <app>            Book an application
+  release <app>         Release your booking
+  force-release <app>   Release a booking held by anyone
+  list [-A]             List bookings in the namespace, or all namespaces with -A
+
+<app> is "name", "namespace:name" or "namespace/name".
+
+Flags:
+`
+
+type options struct {
+	kubeconfig string
+	context    string
+	namespace  string
+	username   string
+	all        bool
+	reason     string
+	duration   time.Duration
+}
+
+// clientFactory builds the k8s client and reports the namespace of the
+// kubeconfig context, or "" if it sets none.
+type clientFactory func(kubeconfig, kubeContext string) (k8s.Client, string, error)
+
+func main() {
+	os.Exit(run(os.Args[1:], os.Stdout, os.Stderr, k8s.NewClientFromKubeconfig))
+}
+
+func defaultUsername() string {
+	if u := os.Getenv("KUBECTL_BOOK_USER"); u != "" {
+		return u
+	}
+	if u, err := user.Current(); err == nil {
+		return u.Username
+	}
+	return ""
+}
+
+func run(args []string, stdout, stderr io.Writer, newClient clientFactory) int {
+	if len(args) == 0 || args[0] == "-h" || args[0] == "--help" {
+		fmt.Fprint(stderr, usage)
+		return exitUsage
+	}
+	cmd, args := args[0], args[1:]
+
+	var opts options
+	fs := flag.NewFlagSet("kubectl-book "+cmd, flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	fs.StringVar(&opts.kubeconfig, "kubeconfig", "", "path to the kubeconfig file")
+	fs.StringVar(&opts.context, "context", "", "kubeconfig context to use")
+	fs.StringVar(&opts.namespace, "n", "", "namespace of the Application (default: from kubeconfig context, else argocd)")
+	fs.StringVar(&opts.username, "username", defaultUsername(), "booking holder name (env KUBECTL_BOOK_USER)")
+	fs.BoolVar(&opts.all, "A", false, "list bookings across all namespaces")
+	fs.StringVar(&opts.reason, "reason", "", "book: why you need the application")
+	fs.DurationVar(&opts.duration, "duration", 0, "book: release the booking automatically after this long")
+	fs.Usage = func() {
+		fmt.Fprint(stderr, usage)
+		fs.PrintDefaults()
+	}
+	positional, err := parseInterspersed(fs, args)
+	if err != nil {
+		return exitUsage
+	}
+
+	want := map[string]int{"get": 1, "book": 1, "release": 1, "force-release": 1, "list": 0}
+	n, ok := want[cmd]
+	if !ok {
+		fmt.Fprintf(stderr, "unknown command %q\n", cmd)
+		return exitUsage
+	}
+	if len(positional) != n {
+		fmt.Fprintf(stderr, "%s expects %d argument(s), got %d\n", cmd, n, len(positional))
+		return exitUsage
+	}
+
+	c, kubeNamespace, err := newClient(opts.kubeconfig, opts.context)
+	if err != nil {
+		return exitCode(err, stderr)
+	}
+	namespace := opts.namespace
+	if namespace == "" {
+		namespace = kubeNamespace
+	}
+	if namespace == "" {
+		namespace = "argocd"
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	if cmd == "list" {
+		if opts.all {
+			namespace = ""
+		}
+		bookings, err := c.ListBookings(ctx, namespace)
+		if err != nil {
+			return exitCode(err, stderr)
+		}
+		printList(stdout, bookings)
+		return exitOK
+	}
+
+	ns, app, err := k8s.ParseAppRef(positional[0], namespace)
+	if err != nil {
+		return exitCode(err, stderr)
+	}
+
+	switch cmd {
+	case "get":
+		bookedBy, bookedAt, err := c.GetBookingStatus(ctx, ns, app)
+		if err != nil {
+			return exitCode(err, stderr)
+		}
+		if bookedBy == "" {
+			fmt.Fprintf(stdout, "%s/%s is free\n", ns, app)
+		} else {
+			fmt.Fprintf(stdout, "%s/%s is booked by %s since %s\n", ns, app, bookedBy, bookedAt.UTC().Format(time.RFC3339))
+		}
+	case "book":
+		_, err = c.BookApp(ctx, ns, app, opts.username, k8s.BookOptions{Reason: opts.reason, Duration: opts.duration})
+		if err == nil {
+			fmt.Fprintf(stdout, "%s/%s booked by %s\n", ns, app, opts.username)
+		}
+	case "release":
+		_, err = c.UnbookApp(ctx, ns, app, opts.username, false, k8s.UnbookOptions{})
+		if err == nil {
+			fmt.Fprintf(stdout, "%s/%s released\n", ns, app)
+		}
+	case "force-release":
+		_, err = c.UnbookApp(ctx, ns, app, opts.username, true, k8s.UnbookOptions{})
+		if err == nil {
+			fmt.Fprintf(stdout, "%s/%s force-released\n", ns, app)
+		}
+	}
+	return exitCode(err, stderr)
+}
+
+// parseInterspersed parses flags that may appear before or after positional
+// arguments, as kubectl users expect, and returns the positional arguments.
+func parseInterspersed(fs *flag.FlagSet, args []string) ([]string, error) {
+	var positional []string
+	for {
+		if err := fs.Parse(args); err != nil {
+			return nil, err
+		}
+		args = fs.Args()
+		if len(args) == 0 {
+			return positional, nil
+		}
+		positional = append(positional, args[0])
+		args = args[1:]
+	}
+}
+
+func exitCode(err error, stderr io.Writer) int {
+	if err == nil {
+		return exitOK
+	}
+	fmt.Fprintf(stderr, "error: %v\n", err)
+	switch {
+	case errors.Is(err, k8s.ErrConflict):
+		return exitConflict
+	case errors.Is(err, k8s.ErrForbidden):
+		return exitForbidden
+	case errors.Is(err, k8s.ErrInvalid):
+		return exitUsage
+	}
+	return exitError
+}
+
+func printList(w io.Writer, bookings []k8s.Booking) {
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "NAMESPACE\tAPP\tPROJECT\tBOOKED BY\tBOOKED AT\tEXPIRES AT")
+	for _, b := range bookings {
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\t%s\n",
+			b.Namespace, b.AppName, dash(b.Project), b.BookedBy, dash(b.BookedAt), dash(b.ExpiresAt))
+	}
+	tw.Flush()
+}
+
+func dash(s string) string {
+	if s == "" {
+		return "-"
+	}
+	return s
+}
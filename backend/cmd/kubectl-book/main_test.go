@@ -0,0 +1,78 @@
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	dynamicfake "k8s.io/client-go/dynamic/fake"
+
+	"github.com/behavox/argocd-book-plugin/internal/k8s"
+)
+
+func newFakeApp(namespace, name string, annotations map[string]string) *unstructured.Unstructured {
+	app := &unstructured.Unstructured{}
+	app.SetGroupVersionKind(schema.GroupVersionKind{Group: "argoproj.io", Version: "v1alpha1", Kind: "Application"})
+	app.SetNamespace(namespace)
+	app.SetName(name)
+	app.SetAnnotations(annotations)
+	return app
+}
+
+func fakeFactory(objects ...runtime.Object) clientFactory {
+	fakeDyn := dynamicfake.NewSimpleDynamicClientWithCustomListKinds(runtime.NewScheme(),
+		map[schema.GroupVersionResource]string{
+			{Group: "argoproj.io", Version: "v1alpha1", Resource: "applications"}: "ApplicationList",
+		},
+		objects...,
+	)
+	c := k8s.NewClientFromDynamic(fakeDyn)
+	return func(string, string) (k8s.Client, string, error) { return c, "", nil }
+}
+
+func TestRun(t *testing.T) {
+	factory := fakeFactory(
+		newFakeApp("argocd", "free", nil),
+		newFakeApp("argocd", "taken", map[string]string{
+			k8s.AnnotationBookedBy: "alice",
+			k8s.AnnotationBookedAt: "2026-01-15T10:00:00Z",
+		}),
+		newFakeApp("default", "legacy", nil),
+		newFakeApp("team", "other", map[string]string{
+			k8s.AnnotationBookedBy: "carol",
+			k8s.AnnotationBookedAt: "2026-01-15T10:00:00Z",
+		}),
+	)
+
+	tests := []struct {
+		name string
+		args []string
+		want int
+		out  string
+	}{
+		{"get free", []string{"get", "free"}, exitOK, "argocd/free is free"},
+		{"explicit default namespace", []string{"get", "legacy", "-n", "default"}, exitOK, "default/legacy is free"},
+		{"book conflict", []string{"book", "taken", "--username", "bob"}, exitConflict, ""},
+		{"release forbidden", []string{"release", "argocd/taken", "--username", "bob"}, exitForbidden, ""},
+		{"book", []string{"book", "--username", "bob", "free"}, exitOK, "argocd/free booked by bob"},
+		{"get booked", []string{"get", "argocd:free"}, exitOK, "booked by bob"},
+		{"force-release", []string{"force-release", "taken", "--username", "bob"}, exitOK, "force-released"},
+		{"list namespace", []string{"list"}, exitOK, "free"},
+		{"list all", []string{"list", "-A"}, exitOK, "other"},
+		{"invalid app", []string{"get", "Bad_Name"}, exitUsage, ""},
+		{"unknown", []string{"frobnicate"}, exitUsage, ""},
+	}
+	for _, tt := range tests {
+		var stdout, stderr bytes.Buffer
+		if got := run(tt.args, &stdout, &stderr, factory); got != tt.want {
+			t.Errorf("%s: expected exit %d, got %d (stderr: %s)", tt.name, tt.want, got, stderr.String())
+			continue
+		}
+		if !strings.Contains(stdout.String(), tt.out) {
+			t.Errorf("%s: expected output containing %q, got:\n%s", tt.name, tt.out, stdout.String())
+		}
+	}
+}
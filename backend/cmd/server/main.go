@@ -1,31 +1,248 @@
 package main
 
 import (
-	"log"
+	"context"
+	"errors"
+	"log/slog"
 	"net/http"
 	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+	"time"
 
+	"github.com/behavox/argocd-book-plugin/internal/autobook"
+	"github.com/behavox/argocd-book-plugin/internal/autosync"
+	"github.com/behavox/argocd-book-plugin/internal/config"
 	"github.com/behavox/argocd-book-plugin/internal/handler"
+	"github.com/behavox/argocd-book-plugin/internal/idle"
 	"github.com/behavox/argocd-book-plugin/internal/k8s"
+	"github.com/behavox/argocd-book-plugin/internal/logging"
+	"github.com/behavox/argocd-book-plugin/internal/notify"
+	"github.com/behavox/argocd-book-plugin/internal/rbac"
+	"github.com/behavox/argocd-book-plugin/internal/tracing"
+)
+
+// Server limits. The write timeout comes from handler.WriteTimeout, which book
+// requests waiting for an application to become free extend for themselves.
+const (
+	readHeaderTimeout = 10 * time.Second
+	readTimeout       = 30 * time.Second
+	idleTimeout       = 2 * time.Minute
+	maxHeaderBytes    = 64 << 10
 )
 
 func main() {
+	logger, err := logging.New(os.Stderr, os.Getenv("LOG_LEVEL"), os.Getenv("LOG_FORMAT"))
+	if err != nil {
+		fatal("invalid logging configuration", logging.KeyError, err)
+	}
+	slog.SetDefault(logger)
+
 	port := os.Getenv("PORT")
 	if port == "" {
 		port = "8080"
 	}
+	shutdownDelay := durationEnv("SHUTDOWN_DELAY", 5*time.Second)
+	shutdownTimeout := durationEnv("SHUTDOWN_TIMEOUT", 30*time.Second)
 
-	client, err := k8s.NewClient()
+	// ctx is cancelled on SIGTERM or SIGINT, which starts the shutdown and stops
+	// the background jobs started with run.
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGTERM, os.Interrupt)
+	defer stop()
+	var jobs sync.WaitGroup
+	run := func(job func(context.Context)) {
+		jobs.Add(1)
+		go func() {
+			defer jobs.Done()
+			job(ctx)
+		}()
+	}
+
+	shutdownTracing, err := tracing.Setup(ctx)
 	if err != nil {
-		log.Fatalf("failed to create k8s client: %v", err)
+		fatal("failed to set up tracing", logging.KeyError, err)
+	}
+	if tracing.Enabled() {
+		slog.Info("exporting traces over OTLP")
+	}
+
+	dyn, err := k8s.NewInClusterDynamic()
+	if err != nil {
+		fatal("failed to create k8s client", logging.KeyError, err)
+	}
+	client := k8s.NewClientFromDynamic(dyn)
+
+	// /readyz fails while the service account cannot list or patch Applications,
+	// for example after a broken RBAC change.
+	opts := []handler.Option{handler.WithReadinessChecks(
+		handler.ReadinessCheck{Name: "list applications", Check: func(ctx context.Context, ns string) error {
+			return k8s.CheckApplicationAccess(ctx, dyn, ns, "list")
+		}},
+		handler.ReadinessCheck{Name: "patch applications", Check: func(ctx context.Context, ns string) error {
+			return k8s.CheckApplicationAccess(ctx, dyn, ns, "patch")
+		}},
+	)}
+	var notifier notify.Notifier
+	if urls := os.Getenv("WEBHOOK_URLS"); urls != "" {
+		events, err := notify.ParseEventTypes(os.Getenv("WEBHOOK_EVENTS"))
+		if err != nil {
+			fatal("invalid WEBHOOK_EVENTS", logging.KeyError, err)
+		}
+		targets, err := notify.ParseTargets(urls, events)
+		if err != nil {
+			fatal("invalid WEBHOOK_URLS", logging.KeyError, err)
+		}
+		webhook := notify.NewWebhook(notify.WebhookConfig{
+			Targets:   targets,
+			ArgoCDURL: os.Getenv("ARGOCD_URL"),
+			Secret:    os.Getenv("WEBHOOK_SECRET"),
+		})
+		defer webhook.Close()
+		notifier = webhook
+		opts = append(opts, handler.WithNotifier(webhook))
+		run(notify.NewExpiryWatcher(notify.ExpiryConfig{}, client, webhook).Run)
+		slog.Info("webhook notifications enabled", "targets", len(targets))
+	}
+
+	if host := os.Getenv("SMTP_HOST"); host != "" {
+		before := 15 * time.Minute
+		if v := os.Getenv("REMINDER_BEFORE"); v != "" {
+			if before, err = time.ParseDuration(v); err != nil {
+				fatal("invalid REMINDER_BEFORE", logging.KeyError, err)
+			}
+		}
+		addresses := notify.NewAddressBook(nil, os.Getenv("EMAIL_DOMAIN"))
+		if path := os.Getenv("EMAIL_MAPPING_FILE"); path != "" {
+			if addresses, err = notify.LoadAddressBook(path, os.Getenv("EMAIL_DOMAIN")); err != nil {
+				fatal("failed to load email mapping", logging.KeyError, err)
+			}
+		}
+		mailer := notify.NewMailer(notify.SMTPConfig{
+			Host:     host,
+			Port:     os.Getenv("SMTP_PORT"),
+			Username: os.Getenv("SMTP_USERNAME"),
+			Password: os.Getenv("SMTP_PASSWORD"),
+			From:     os.Getenv("SMTP_FROM"),
+			StartTLS: os.Getenv("SMTP_STARTTLS") != "false",
+		})
+		reminder := notify.NewReminder(notify.ReminderConfig{
+			Before:    before,
+			ArgoCDURL: os.Getenv("ARGOCD_URL"),
+		}, client, mailer, addresses)
+		run(reminder.Run)
+		opts = append(opts, handler.WithWaitNotifier(notify.NewWaitMailer(mailer, addresses, os.Getenv("ARGOCD_URL"))))
+		slog.Info("email reminders enabled", "smtp_host", host, "before", before.String())
+	}
+
+	path := os.Getenv("CONFIG_FILE")
+	if path == "" {
+		// POLICY_FILE predates CONFIG_FILE; a policy file is a valid config file.
+		path = os.Getenv("POLICY_FILE")
 	}
+	var store *config.Store
+	if path != "" {
+		store, err = config.Load(path)
+		if err != nil {
+			fatal("failed to load configuration", logging.KeyError, err)
+		}
+		interval := durationEnv("CONFIG_RELOAD_INTERVAL", 10*time.Second)
+		run(func(ctx context.Context) { store.Watch(ctx, interval) })
+		opts = append(opts, handler.WithConfig(store))
+		slog.Info("configuration loaded", "path", path, "reload_interval", interval.String())
 
-	h := handler.New(client)
+		// Idle bookings are only looked for while a policy sets idleTimeout.
+		detector := idle.New(idle.Config{Interval: durationEnv("IDLE_CHECK_INTERVAL", time.Minute)}, client, store, notifier)
+		run(detector.Run)
+
+		if os.Getenv("AUTO_BOOK") == "true" {
+			run(autobook.New(dyn, client, store, notifier, "").Run)
+			slog.Info("booking applications on manual sync where a policy sets autoBook")
+		}
+	} else if os.Getenv("AUTO_BOOK") == "true" {
+		fatal("AUTO_BOOK requires CONFIG_FILE with a policy setting autoBook")
+	}
+	// Unbooking restores automated sync paused by a booking; this restores it
+	// for bookings that expire instead, scanning only while pauses may exist.
+	run(autosync.NewResumer(autosync.Config{}, client, store).Run)
+
+	if action := os.Getenv("RBAC_BOOK_ACTION"); action != "" {
+		argocdNamespace := os.Getenv("ARGOCD_NAMESPACE")
+		if argocdNamespace == "" {
+			argocdNamespace = "argocd"
+		}
+		configMap := os.Getenv("RBAC_CONFIGMAP")
+		if configMap == "" {
+			configMap = "argocd-rbac-cm"
+		}
+		enforcer := rbac.NewEnforcer(argocdNamespace)
+		watcher := rbac.NewWatcher(dyn, argocdNamespace, configMap, enforcer)
+		if _, err := watcher.Load(ctx); err != nil {
+			fatal("failed to load RBAC policy", logging.KeyError, err)
+		}
+		run(watcher.Run)
+		opts = append(opts, handler.WithAuthorizer(enforcer, action))
+		slog.Info("booking requires an ArgoCD permission", logging.KeyAction, action, logging.KeyNamespace, argocdNamespace, "configmap", configMap)
+	}
+
+	h := handler.New(client, opts...)
 	mux := http.NewServeMux()
 	h.RegisterRoutes(mux)
 
-	log.Printf("starting booking backend on :%s", port)
-	if err := http.ListenAndServe(":"+port, mux); err != nil {
-		log.Fatalf("server failed: %v", err)
+	srv := &http.Server{
+		Addr:              ":" + port,
+		Handler:           mux,
+		ReadHeaderTimeout: readHeaderTimeout,
+		ReadTimeout:       readTimeout,
+		WriteTimeout:      handler.WriteTimeout,
+		IdleTimeout:       idleTimeout,
+		MaxHeaderBytes:    maxHeaderBytes,
+		ErrorLog:          slog.NewLogLogger(logger.Handler(), slog.LevelWarn),
+	}
+	serveErr := make(chan error, 1)
+	go func() { serveErr <- srv.ListenAndServe() }()
+	slog.Info("starting booking backend", "addr", srv.Addr)
+
+	select {
+	case err := <-serveErr:
+		fatal("server failed", logging.KeyError, err)
+	case <-ctx.Done():
+	}
+	stop() // a second signal terminates immediately
+
+	// Fail readiness first and give the Service time to stop routing to this pod,
+	// then finish in-flight requests. Waiting book requests are released with 503.
+	slog.Info("shutting down", "drain_delay", shutdownDelay.String(), "timeout", shutdownTimeout.String())
+	h.Drain()
+	time.Sleep(shutdownDelay)
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer cancel()
+	if err := srv.Shutdown(shutdownCtx); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		slog.Warn("shutdown did not complete cleanly", logging.KeyError, err)
+	}
+	jobs.Wait()
+	if err := shutdownTracing(shutdownCtx); err != nil {
+		slog.Warn("failed to flush traces", logging.KeyError, err)
+	}
+	slog.Info("shutdown complete")
+}
+
+// fatal logs msg at error level and exits.
+func fatal(msg string, args ...any) {
+	slog.Error(msg, args...)
+	os.Exit(1)
+}
+
+// durationEnv returns the duration in the environment variable name, or def
+// when it is unset. It exits on an invalid or negative value.
+func durationEnv(name string, def time.Duration) time.Duration {
+	v := os.Getenv(name)
+	if v == "" {
+		return def
+	}
+	d, err := time.ParseDuration(v)
+	if err != nil || d < 0 {
+		fatal("invalid duration", "variable", name, "value", v)
 	}
+	return d
 }
@@ -0,0 +1,125 @@
+// Package autobook watches Applications and books a free application for the
+// user who starts a manual sync of it, where the booking policy opts in, so
+// shared environments are not synced from under their users unannounced.
+package autobook
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"time"
+
+	"k8s.io/client-go/dynamic"
+
+	"github.com/behavox/argocd-book-plugin/internal/config"
+	"github.com/behavox/argocd-book-plugin/internal/k8s"
+	"github.com/behavox/argocd-book-plugin/internal/logging"
+	"github.com/behavox/argocd-book-plugin/internal/notify"
+)
+
+// Reason is recorded on automatic bookings.
+const Reason = "booked automatically on sync"
+
+const defaultRetryInterval = 10 * time.Second
+
+// Booker books applications. It is satisfied by k8s.Client.
+type Booker interface {
+	BookApp(ctx context.Context, namespace, appName, username string, opts k8s.BookOptions) (k8s.BookResult, error)
+}
+
+// Controller books applications on sync. Booking limits and allowed groups do
+// not apply: ArgoCD already let the user sync, and the booking only records
+// that they are using the application.
+type Controller struct {
+	dynamic   dynamic.Interface
+	booker    Booker
+	store     *config.Store
+	notifier  notify.Notifier
+	namespace string
+	retry     time.Duration
+
+	handled map[string]string // key: namespace/app, value: initiator of the pending sync
+}
+
+// New returns a Controller watching Applications in namespace (all namespaces
+// when empty) with dynClient and reading policies from store. notifier may be
+// nil. Call Run to start it.
+func New(dynClient dynamic.Interface, booker Booker, store *config.Store, notifier notify.Notifier, namespace string) *Controller {
+	return &Controller{
+		dynamic:   dynClient,
+		booker:    booker,
+		store:     store,
+		notifier:  notifier,
+		namespace: namespace,
+		retry:     defaultRetryInterval,
+		handled:   make(map[string]string),
+	}
+}
+
+// Run watches Applications until ctx is cancelled, re-listing after errors and
+// when the watch is closed by the API server.
+func (c *Controller) Run(ctx context.Context) {
+	for ctx.Err() == nil {
+		err := k8s.WatchApplications(ctx, c.dynamic, c.namespace, func(ev k8s.ApplicationEvent) {
+			c.handle(ctx, ev)
+		})
+		if err == nil {
+			continue
+		}
+		slog.Warn("auto-book: application watch failed, retrying", logging.KeyError, err)
+		select {
+		case <-ctx.Done():
+		case <-time.After(c.retry):
+		}
+	}
+}
+
+// handle books ev for the user syncing it, once per sync operation.
+func (c *Controller) handle(ctx context.Context, ev k8s.ApplicationEvent) {
+	key := ev.Namespace + "/" + ev.Name
+	if ev.SyncInitiator == "" {
+		delete(c.handled, key)
+		return
+	}
+	if c.handled[key] == ev.SyncInitiator {
+		return
+	}
+	c.handled[key] = ev.SyncInitiator
+	if ev.Booking != nil {
+		return
+	}
+	p := c.store.Config().PolicySet().For(ev.Namespace, ev.Project)
+	if !p.AutoBook {
+		return
+	}
+
+	logger := slog.With(logging.KeyUser, ev.SyncInitiator, logging.KeyNamespace, ev.Namespace, logging.KeyApp, ev.Name)
+	res, err := c.booker.BookApp(ctx, ev.Namespace, ev.Name, ev.SyncInitiator, k8s.BookOptions{
+		Reason:        Reason,
+		Duration:      p.AutoBookTTL(),
+		PauseAutoSync: p.PauseAutoSync,
+	})
+	if errors.Is(err, k8s.ErrConflict) {
+		logger.Info("auto-book: application was booked meanwhile", logging.KeyError, err)
+		return
+	}
+	if err != nil {
+		logger.Error("auto-book: failed to book application", logging.KeyError, err)
+		return
+	}
+	if !res.Changed {
+		return // the initiator already held it
+	}
+	logger.Info("application booked on sync", "duration", p.AutoBookTTL().String())
+	if c.notifier != nil {
+		c.notifier.Notify(notify.Event{
+			Type:      notify.EventBooked,
+			AppName:   ev.Name,
+			Namespace: ev.Namespace,
+			Project:   ev.Project,
+			User:      ev.SyncInitiator,
+			Reason:    Reason,
+			Timestamp: time.Now().UTC(),
+		})
+	}
+}
@@ -0,0 +1,153 @@
+package autobook
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	dynamicfake "k8s.io/client-go/dynamic/fake"
+
+	"github.com/behavox/argocd-book-plugin/internal/config"
+	"github.com/behavox/argocd-book-plugin/internal/k8s"
+	"github.com/behavox/argocd-book-plugin/internal/notify"
+)
+
+type booking struct {
+	app, user string
+	opts      k8s.BookOptions
+}
+
+type fakeBooker struct {
+	mu       sync.Mutex
+	bookings []booking
+	err      error
+}
+
+func (b *fakeBooker) BookApp(_ context.Context, namespace, appName, username string, opts k8s.BookOptions) (k8s.BookResult, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.err != nil {
+		return k8s.BookResult{}, b.err
+	}
+	b.bookings = append(b.bookings, booking{namespace + "/" + appName, username, opts})
+	return k8s.BookResult{Changed: true, Duration: opts.Duration}, nil
+}
+
+func (b *fakeBooker) booked() []booking {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return append([]booking(nil), b.bookings...)
+}
+
+type recordingNotifier struct {
+	events []notify.Event
+}
+
+func (n *recordingNotifier) Notify(e notify.Event) {
+	n.events = append(n.events, e)
+}
+
+func newStore(t *testing.T) *config.Store {
+	t.Helper()
+	c, err := config.Parse([]byte(`
+policies:
+  - project: staging
+    autoBook: true
+    autoBookDuration: 20m
+    pauseAutoSync: true
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return config.NewStore(c)
+}
+
+func event(project, name, initiator string, booking *k8s.Booking) k8s.ApplicationEvent {
+	return k8s.ApplicationEvent{
+		Application:   k8s.Application{Name: name, Namespace: "argocd", Project: project, Booking: booking},
+		SyncInitiator: initiator,
+	}
+}
+
+func TestController_BooksOnManualSync(t *testing.T) {
+	b := &fakeBooker{}
+	n := &recordingNotifier{}
+	c := New(nil, b, newStore(t), n, "")
+	ctx := context.Background()
+
+	c.handle(ctx, event("staging", "free", "alice", nil))
+	c.handle(ctx, event("staging", "free", "alice", nil)) // the same operation, seen again
+	c.handle(ctx, event("staging", "held", "alice", &k8s.Booking{BookedBy: "bob"}))
+	c.handle(ctx, event("staging", "idle", "", nil))
+	c.handle(ctx, event("prod", "opted-out", "alice", nil))
+
+	got := b.booked()
+	if len(got) != 1 || got[0].app != "argocd/free" || got[0].user != "alice" {
+		t.Fatalf("expected one booking of argocd/free for alice, got %+v", got)
+	}
+	if got[0].opts.Duration != 20*time.Minute || got[0].opts.Reason != Reason || !got[0].opts.PauseAutoSync {
+		t.Fatalf("unexpected booking options %+v", got[0].opts)
+	}
+	if len(n.events) != 1 || n.events[0].Type != notify.EventBooked || n.events[0].User != "alice" {
+		t.Fatalf("expected a booked notification, got %+v", n.events)
+	}
+
+	// Once the sync completes, the next one is handled again.
+	c.handle(ctx, event("staging", "free", "", nil))
+	c.handle(ctx, event("staging", "free", "alice", nil))
+	if got := b.booked(); len(got) != 2 {
+		t.Fatalf("expected the next sync to book again, got %+v", got)
+	}
+}
+
+func TestController_BookingErrors(t *testing.T) {
+	b := &fakeBooker{err: fmt.Errorf("%w: application already booked by bob", k8s.ErrConflict)}
+	n := &recordingNotifier{}
+	c := New(nil, b, newStore(t), n, "")
+
+	c.handle(context.Background(), event("staging", "free", "alice", nil))
+	if len(n.events) != 0 {
+		t.Fatalf("expected no notification for a failed booking, got %+v", n.events)
+	}
+}
+
+func TestController_Run(t *testing.T) {
+	app := &unstructured.Unstructured{}
+	app.SetAPIVersion("argoproj.io/v1alpha1")
+	app.SetKind("Application")
+	app.SetNamespace("argocd")
+	app.SetName("syncing")
+	unstructured.SetNestedField(app.Object, "staging", "spec", "project")
+	unstructured.SetNestedField(app.Object, "alice", "operation", "initiatedBy", "username")
+	dyn := dynamicfake.NewSimpleDynamicClientWithCustomListKinds(runtime.NewScheme(),
+		map[schema.GroupVersionResource]string{
+			{Group: "argoproj.io", Version: "v1alpha1", Resource: "applications"}: "ApplicationList",
+		}, app)
+
+	b := &fakeBooker{}
+	c := New(dyn, b, newStore(t), nil, "")
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		c.Run(ctx)
+		close(done)
+	}()
+
+	deadline := time.Now().Add(5 * time.Second)
+	for len(b.booked()) == 0 {
+		if time.Now().After(deadline) {
+			t.Fatal("timed out waiting for the syncing application to be booked")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	cancel()
+	<-done
+	if got := b.booked(); got[0].app != "argocd/syncing" || got[0].user != "alice" {
+		t.Fatalf("unexpected booking %+v", got[0])
+	}
+}
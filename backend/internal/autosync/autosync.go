@@ -0,0 +1,103 @@
+// Package autosync turns automated sync back on for applications whose booking
+// paused it and then expired instead of being unbooked.
+package autosync
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"github.com/behavox/argocd-book-plugin/internal/config"
+	"github.com/behavox/argocd-book-plugin/internal/k8s"
+	"github.com/behavox/argocd-book-plugin/internal/logging"
+	"github.com/behavox/argocd-book-plugin/internal/policy"
+)
+
+// Client is the part of k8s.Client the Resumer uses.
+type Client interface {
+	ListApplications(ctx context.Context, namespace string) ([]k8s.Application, error)
+	ResumeAutoSync(ctx context.Context, namespace, appName string) error
+}
+
+// Config configures the resume job.
+type Config struct {
+	// Namespace to scan; empty means all namespaces.
+	Namespace string
+	// Interval between scans.
+	Interval time.Duration
+}
+
+// Resumer periodically restores the automated sync policy of applications
+// whose booking has expired while it was paused. It lists the applications
+// only while a policy sets pauseAutoSync or its last scan found paused ones.
+type Resumer struct {
+	cfg    Config
+	client Client
+	store  *config.Store
+
+	paused bool // whether the last scan found applications with paused automated sync
+}
+
+// NewResumer creates a Resumer reading policies from store, which may be nil.
+// Call Run to start it.
+func NewResumer(cfg Config, client Client, store *config.Store) *Resumer {
+	if cfg.Interval <= 0 {
+		cfg.Interval = time.Minute
+	}
+	// The first scan always runs, to find applications paused under a policy
+	// that has since been removed.
+	return &Resumer{cfg: cfg, client: client, store: store, paused: true}
+}
+
+// Run scans for expired bookings with paused automated sync until ctx is cancelled.
+func (r *Resumer) Run(ctx context.Context) {
+	ticker := time.NewTicker(r.cfg.Interval)
+	defer ticker.Stop()
+	for {
+		r.check(ctx)
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (r *Resumer) check(ctx context.Context) {
+	if !r.paused && !(r.store != nil && pausesAutoSync(r.store.Config().Policies)) {
+		return
+	}
+	apps, err := r.client.ListApplications(ctx, r.cfg.Namespace)
+	if err != nil {
+		slog.Error("autosync: failed to list applications", logging.KeyError, err)
+		return
+	}
+	r.paused = false
+	for _, app := range apps {
+		if !app.AutoSyncPaused {
+			continue
+		}
+		if app.Booking != nil {
+			r.paused = true
+			continue
+		}
+		// A conflict means the application changed since it was listed; the
+		// next scan retries with the new state.
+		if err := r.client.ResumeAutoSync(ctx, app.Namespace, app.Name); err != nil {
+			slog.Error("autosync: failed to resume automated sync", logging.KeyNamespace, app.Namespace, logging.KeyApp, app.Name, logging.KeyError, err)
+			r.paused = true
+			continue
+		}
+		slog.Info("automated sync resumed after the booking expired", logging.KeyNamespace, app.Namespace, logging.KeyApp, app.Name)
+	}
+}
+
+// pausesAutoSync reports whether any of policies sets pauseAutoSync.
+func pausesAutoSync(policies []policy.Policy) bool {
+	for _, p := range policies {
+		if p.PauseAutoSync {
+			return true
+		}
+	}
+	return false
+}
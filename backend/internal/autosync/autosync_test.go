@@ -0,0 +1,68 @@
+package autosync
+
+import (
+	"context"
+	"testing"
+
+	"github.com/behavox/argocd-book-plugin/internal/config"
+	"github.com/behavox/argocd-book-plugin/internal/k8s"
+)
+
+type fakeClient struct {
+	apps    []k8s.Application
+	lists   int
+	resumed []string
+}
+
+func (c *fakeClient) ListApplications(_ context.Context, _ string) ([]k8s.Application, error) {
+	c.lists++
+	return c.apps, nil
+}
+
+func (c *fakeClient) ResumeAutoSync(_ context.Context, namespace, appName string) error {
+	c.resumed = append(c.resumed, namespace+"/"+appName)
+	return nil
+}
+
+func TestResumer_ResumesExpiredBookings(t *testing.T) {
+	client := &fakeClient{apps: []k8s.Application{
+		{Name: "expired", Namespace: "argocd", AutoSyncPaused: true},
+		{Name: "booked", Namespace: "argocd", AutoSyncPaused: true, Booking: &k8s.Booking{BookedBy: "alice"}},
+		{Name: "manual", Namespace: "argocd"},
+	}}
+	NewResumer(Config{}, client, nil).check(context.Background())
+
+	if len(client.resumed) != 1 || client.resumed[0] != "argocd/expired" {
+		t.Fatalf("expected only argocd/expired to be resumed, got %v", client.resumed)
+	}
+}
+
+func TestResumer_ScansOnlyWhilePausesMayExist(t *testing.T) {
+	client := &fakeClient{apps: []k8s.Application{
+		{Name: "booked", Namespace: "argocd", AutoSyncPaused: true, Booking: &k8s.Booking{BookedBy: "alice"}},
+	}}
+	r := NewResumer(Config{}, client, nil)
+	ctx := context.Background()
+
+	r.check(ctx)
+	r.check(ctx)
+	if client.lists != 2 {
+		t.Fatalf("expected scans while a paused application is booked, got %d", client.lists)
+	}
+	client.apps = nil
+	r.check(ctx)
+	r.check(ctx)
+	if client.lists != 3 {
+		t.Fatalf("expected scans to stop once nothing is paused, got %d", client.lists)
+	}
+
+	c, err := config.Parse([]byte("policies:\n  - project: staging\n    pauseAutoSync: true\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	r.store = config.NewStore(c)
+	r.check(ctx)
+	if client.lists != 4 {
+		t.Fatalf("expected scans while a policy pauses automated sync, got %d", client.lists)
+	}
+}
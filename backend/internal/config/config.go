@@ -0,0 +1,244 @@
+// Package config holds the backend's runtime configuration, read from a YAML
+// file (typically mounted from a ConfigMap) and reloaded when the file changes.
+package config
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"log/slog"
+	"os"
+	"sync/atomic"
+	"time"
+
+	"sigs.k8s.io/yaml"
+
+	"github.com/behavox/argocd-book-plugin/internal/logging"
+	"github.com/behavox/argocd-book-plugin/internal/policy"
+	"github.com/behavox/argocd-book-plugin/internal/ratelimit"
+)
+
+// Config is the effective configuration. Fields left out of the file keep the
+// values of Default.
+type Config struct {
+	// AdminGroups may unbook and transfer bookings held by others.
+	AdminGroups []string `json:"adminGroups"`
+	// DefaultNamespace is used when a request does not name a namespace.
+	DefaultNamespace string `json:"defaultNamespace"`
+	// Policies are the per-project booking policies; see package policy.
+	Policies []policy.Policy `json:"policies"`
+	// RateLimits throttle book, unbook and transfer requests.
+	RateLimits RateLimits `json:"rateLimits"`
+	// BookingLimits cap how many applications a user may hold at once.
+	BookingLimits BookingLimits `json:"bookingLimits"`
+
+	policies *policy.Set
+}
+
+// RateLimits are the token buckets applied to mutating requests: one per user,
+// keyed by the Argocd-Username header, and one shared by all users.
+type RateLimits struct {
+	PerUser ratelimit.Limit `json:"perUser"`
+	Global  ratelimit.Limit `json:"global"`
+}
+
+// BookingLimits cap the applications a user may hold at the same time. A limit
+// of 0 means unlimited; admins are never limited.
+type BookingLimits struct {
+	// PerUser applies to users in none of Groups.
+	PerUser int `json:"perUser"`
+	// Groups override PerUser for their members. A member of several groups
+	// gets the most generous of their limits.
+	Groups map[string]int `json:"groups"`
+}
+
+// For returns the limit of a user in groups, or 0 if they are unlimited.
+func (l BookingLimits) For(groups []string) int {
+	limit, matched := l.PerUser, false
+	for _, g := range groups {
+		n, ok := l.Groups[g]
+		if !ok {
+			continue
+		}
+		if n == 0 {
+			return 0
+		}
+		if !matched || n > limit {
+			limit, matched = n, true
+		}
+	}
+	return limit
+}
+
+// Default returns the configuration used without a config file.
+func Default() *Config {
+	c := &Config{
+		AdminGroups:      []string{"admin"},
+		DefaultNamespace: "argocd",
+		Policies:         []policy.Policy{},
+		RateLimits: RateLimits{
+			PerUser: ratelimit.Limit{PerMinute: 30, Burst: 10},
+			Global:  ratelimit.Limit{PerMinute: 600, Burst: 100},
+		},
+		BookingLimits: BookingLimits{Groups: map[string]int{}},
+	}
+	c.policies, _ = policy.New(nil)
+	return c
+}
+
+// Parse reads a YAML or JSON config document, rejecting unknown fields and
+// invalid values.
+func Parse(data []byte) (*Config, error) {
+	c := Default()
+	if err := yaml.UnmarshalStrict(data, c); err != nil {
+		return nil, err
+	}
+	if len(c.AdminGroups) == 0 {
+		return nil, fmt.Errorf("adminGroups must not be empty")
+	}
+	for _, g := range c.AdminGroups {
+		if g == "" {
+			return nil, fmt.Errorf("adminGroups must not contain an empty group")
+		}
+	}
+	if c.DefaultNamespace == "" {
+		return nil, fmt.Errorf("defaultNamespace must not be empty")
+	}
+	if err := c.RateLimits.PerUser.Validate(); err != nil {
+		return nil, fmt.Errorf("rateLimits.perUser: %w", err)
+	}
+	if err := c.RateLimits.Global.Validate(); err != nil {
+		return nil, fmt.Errorf("rateLimits.global: %w", err)
+	}
+	if c.BookingLimits.PerUser < 0 {
+		return nil, fmt.Errorf("bookingLimits.perUser must not be negative")
+	}
+	for g, n := range c.BookingLimits.Groups {
+		if g == "" {
+			return nil, fmt.Errorf("bookingLimits.groups must not contain an empty group")
+		}
+		if n < 0 {
+			return nil, fmt.Errorf("bookingLimits.groups.%s must not be negative", g)
+		}
+	}
+	if c.BookingLimits.Groups == nil {
+		c.BookingLimits.Groups = map[string]int{}
+	}
+	if c.Policies == nil {
+		c.Policies = []policy.Policy{}
+	}
+	set, err := policy.New(c.Policies)
+	if err != nil {
+		return nil, err
+	}
+	c.policies = set
+	return c, nil
+}
+
+// PolicySet returns the validated policies.
+func (c *Config) PolicySet() *policy.Set {
+	return c.policies
+}
+
+// IsAdmin reports whether any of groups is an admin group.
+func (c *Config) IsAdmin(groups []string) bool {
+	for _, g := range groups {
+		for _, a := range c.AdminGroups {
+			if g == a {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// Store holds the current Config and, for file-backed stores, where and when
+// it was loaded. It is safe for concurrent use.
+type Store struct {
+	current  atomic.Pointer[snapshot]
+	path     string
+	lastData []byte // only touched by Load and Watch
+}
+
+type snapshot struct {
+	config   *Config
+	loadedAt time.Time
+}
+
+// NewStore returns a Store holding c that is never reloaded.
+func NewStore(c *Config) *Store {
+	s := &Store{}
+	s.current.Store(&snapshot{config: c, loadedAt: time.Now().UTC()})
+	return s
+}
+
+// Load reads and validates the config file at path.
+func Load(path string) (*Store, error) {
+	s := &Store{path: path}
+	if _, err := s.reload(); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// Config returns the current configuration.
+func (s *Store) Config() *Config {
+	return s.current.Load().config
+}
+
+// Source returns the file the configuration was read from, or "" for defaults.
+func (s *Store) Source() string {
+	return s.path
+}
+
+// LoadedAt returns when the current configuration was applied.
+func (s *Store) LoadedAt() time.Time {
+	return s.current.Load().loadedAt
+}
+
+// reload re-reads the file and applies it if its content changed.
+func (s *Store) reload() (changed bool, err error) {
+	data, err := os.ReadFile(s.path)
+	if err != nil {
+		return false, fmt.Errorf("failed to read config file: %w", err)
+	}
+	if s.lastData != nil && bytes.Equal(data, s.lastData) {
+		return false, nil
+	}
+	// Remember invalid content too, so it is reported once rather than on every poll.
+	s.lastData = data
+	c, err := Parse(data)
+	if err != nil {
+		return false, fmt.Errorf("invalid config file %s: %w", s.path, err)
+	}
+	s.current.Store(&snapshot{config: c, loadedAt: time.Now().UTC()})
+	return true, nil
+}
+
+// Watch re-reads the config file every interval until ctx is cancelled and
+// applies it when it changes. Polling, rather than file events, also follows
+// the symlink swap the kubelet performs when a mounted ConfigMap is updated.
+// An invalid file is logged and the previous configuration kept. A
+// non-positive interval disables reloading.
+func (s *Store) Watch(ctx context.Context, interval time.Duration) {
+	if s.path == "" || interval <= 0 {
+		return
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		changed, err := s.reload()
+		if err != nil {
+			slog.Warn("invalid configuration, keeping the previous one", "path", s.path, logging.KeyError, err)
+			continue
+		}
+		if changed {
+			slog.Info("configuration reloaded", "path", s.path)
+		}
+	}
+}
@@ -0,0 +1,156 @@
+package config
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestParse_DefaultsAndOverrides(t *testing.T) {
+	c, err := Parse([]byte("defaultNamespace: apps\n"))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if c.DefaultNamespace != "apps" || len(c.AdminGroups) != 1 || c.AdminGroups[0] != "admin" {
+		t.Fatalf("expected defaults for unset fields, got %+v", c)
+	}
+	if c.RateLimits != Default().RateLimits {
+		t.Fatalf("expected the default rate limits, got %+v", c.RateLimits)
+	}
+
+	c, err = Parse([]byte(`
+adminGroups: [platform, sre]
+policies:
+  - project: prod
+    requireReason: true
+rateLimits:
+  perUser: {perMinute: 5}
+  global: {perMinute: 0}
+`))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !c.IsAdmin([]string{"dev", "sre"}) || c.IsAdmin([]string{"admin"}) {
+		t.Errorf("unexpected admin groups %v", c.AdminGroups)
+	}
+	if !c.PolicySet().For("argocd", "prod").RequireReason {
+		t.Error("expected the prod policy to be applied")
+	}
+	if l := c.RateLimits; l.PerUser.PerMinute != 5 || l.PerUser.Burst != 10 || l.Global.PerMinute != 0 {
+		t.Errorf("expected rate limits merged over the defaults, got %+v", l)
+	}
+}
+
+func TestParse_Invalid(t *testing.T) {
+	for _, doc := range []string{
+		"adminGroups: []\n",
+		"adminGroups: [\"\"]\n",
+		"defaultNamespace: \"\"\n",
+		"adminGroup: [sre]\n",
+		"policies:\n  - maxDuration: 1h\n    defaultDuration: 2h\n",
+		"policies:\n  - maxDuration: forever\n",
+		"policies:\n  - project: prod\n    requiredReason: true\n",
+		"rateLimits:\n  perUser: {perMinute: 10, burst: 0}\n",
+		"rateLimits:\n  global: {perMinute: -1}\n",
+		"bookingLimits:\n  perUser: -1\n",
+		"bookingLimits:\n  groups: {qa: -2}\n",
+		"bookingLimits:\n  groups: {\"\": 2}\n",
+		"port: [",
+	} {
+		if _, err := Parse([]byte(doc)); err == nil {
+			t.Errorf("expected an error for %q", doc)
+		}
+	}
+}
+
+func TestBookingLimits_For(t *testing.T) {
+	c, err := Parse([]byte(`
+bookingLimits:
+  perUser: 2
+  groups: {contractors: 1, qa: 5, release: 0}
+`))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	for _, tc := range []struct {
+		groups []string
+		want   int
+	}{
+		{nil, 2},
+		{[]string{"dev"}, 2},
+		{[]string{"contractors"}, 1},
+		{[]string{"contractors", "qa"}, 5},
+		{[]string{"qa", "release"}, 0},
+	} {
+		if got := c.BookingLimits.For(tc.groups); got != tc.want {
+			t.Errorf("For(%v) = %d, want %d", tc.groups, got, tc.want)
+		}
+	}
+	if got := Default().BookingLimits.For([]string{"dev"}); got != 0 {
+		t.Errorf("expected no limit by default, got %d", got)
+	}
+}
+
+func TestStore_WatchReloadsChanges(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	// Replace the file atomically, as the kubelet does, so the watcher never
+	// reads a truncated file.
+	write := func(doc string) {
+		tmp := path + ".tmp"
+		if err := os.WriteFile(tmp, []byte(doc), 0o600); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.Rename(tmp, path); err != nil {
+			t.Fatal(err)
+		}
+	}
+	write("defaultNamespace: one\n")
+
+	s, err := Load(path)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if s.Config().DefaultNamespace != "one" || s.Source() != path {
+		t.Fatalf("unexpected initial config %+v from %q", s.Config(), s.Source())
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go s.Watch(ctx, 5*time.Millisecond)
+
+	waitFor := func(ns string) {
+		t.Helper()
+		deadline := time.Now().Add(5 * time.Second)
+		for s.Config().DefaultNamespace != ns {
+			if time.Now().After(deadline) {
+				t.Fatalf("timed out waiting for namespace %q, have %q", ns, s.Config().DefaultNamespace)
+			}
+			time.Sleep(5 * time.Millisecond)
+		}
+	}
+
+	write("defaultNamespace: two\n")
+	waitFor("two")
+
+	write("defaultNamespace: [\n")
+	time.Sleep(50 * time.Millisecond)
+	if got := s.Config().DefaultNamespace; got != "two" {
+		t.Fatalf("expected an invalid file to be ignored, got %q", got)
+	}
+
+	write("defaultNamespace: three\n")
+	waitFor("three")
+}
+
+func TestLoad_InvalidFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	os.WriteFile(path, []byte("adminGroups: []\n"), 0o600)
+	if _, err := Load(path); err == nil {
+		t.Fatal("expected start-up validation to fail")
+	}
+	if _, err := Load(filepath.Join(t.TempDir(), "missing.yaml")); err == nil {
+		t.Fatal("expected an error for a missing file")
+	}
+}
@@ -0,0 +1,119 @@
+package handler
+
+import (
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"k8s.io/apimachinery/pkg/labels"
+
+	"github.com/behavox/argocd-book-plugin/internal/k8s"
+	"github.com/behavox/argocd-book-plugin/internal/logging"
+)
+
+// board is the response of GET /api/v1/board.
+type board struct {
+	GroupBy     string       `json:"groupBy"`
+	Groups      []boardGroup `json:"groups"`
+	GeneratedAt string       `json:"generatedAt"`
+}
+
+// boardGroup holds the applications sharing a project or label value. Applications
+// without the grouping label land in a group with an empty name.
+type boardGroup struct {
+	Name         string            `json:"name"`
+	Booked       int               `json:"booked"`
+	Free         int               `json:"free"`
+	Applications []k8s.Application `json:"applications"`
+}
+
+// Board returns every application in a namespace grouped for the environment
+// board UI. Query parameters: namespace (default from the config), groupBy ("project",
+// the default, or "label:<key>"), project and selector.
+func (h *Handler) Board(w http.ResponseWriter, r *http.Request) {
+	q := h.query(r)
+	ns := q.Get("namespace")
+	groupBy := q.Get("groupBy")
+	if groupBy == "" {
+		groupBy = "project"
+	}
+	labelKey, byLabel := strings.CutPrefix(groupBy, "label:")
+	if groupBy != "project" && (!byLabel || labelKey == "") {
+		writeError(w, http.StatusBadRequest, "invalid \"groupBy\" (expected project or label:<key>)")
+		return
+	}
+	selector := labels.Everything()
+	if v := q.Get("selector"); v != "" {
+		sel, err := labels.Parse(v)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, "invalid \"selector\": "+err.Error())
+			return
+		}
+		selector = sel
+	}
+	project := q.Get("project")
+
+	apps, err := h.client.ListApplications(r.Context(), ns)
+	if err != nil {
+		requestLogger(r).Error("failed to list applications", "list_namespace", ns, logging.KeyError, err)
+		writeError(w, http.StatusInternalServerError, "failed to list applications")
+		return
+	}
+
+	var kept []k8s.Application
+	for _, app := range apps {
+		if (project == "" || app.Project == project) && selector.Matches(labels.Set(app.Labels)) {
+			kept = append(kept, app)
+		}
+	}
+	key := func(app k8s.Application) string { return app.Project }
+	if byLabel {
+		key = func(app k8s.Application) string { return app.Labels[labelKey] }
+	}
+	writeJSON(w, http.StatusOK, board{
+		GroupBy:     groupBy,
+		Groups:      groupApplications(kept, key),
+		GeneratedAt: time.Now().UTC().Format(time.RFC3339),
+	})
+}
+
+// groupApplications buckets apps by key, sorting groups and the applications in
+// each by name. The unnamed group sorts last.
+func groupApplications(apps []k8s.Application, key func(k8s.Application) string) []boardGroup {
+	index := map[string]int{}
+	groups := []boardGroup{}
+	for _, app := range apps {
+		name := key(app)
+		i, ok := index[name]
+		if !ok {
+			i = len(groups)
+			index[name] = i
+			groups = append(groups, boardGroup{Name: name})
+		}
+		g := &groups[i]
+		g.Applications = append(g.Applications, app)
+		if app.Booking != nil {
+			g.Booked++
+		} else {
+			g.Free++
+		}
+	}
+
+	sort.Slice(groups, func(i, j int) bool {
+		if (groups[i].Name == "") != (groups[j].Name == "") {
+			return groups[j].Name == ""
+		}
+		return groups[i].Name < groups[j].Name
+	})
+	for _, g := range groups {
+		sort.Slice(g.Applications, func(i, j int) bool {
+			a, b := g.Applications[i], g.Applications[j]
+			if a.Name != b.Name {
+				return a.Name < b.Name
+			}
+			return a.Namespace < b.Namespace
+		})
+	}
+	return groups
+}
@@ -0,0 +1,83 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/behavox/argocd-book-plugin/internal/k8s"
+)
+
+func setupBoard() (*mockClient, *http.ServeMux) {
+	_, mc, mux := setupHandler()
+	mc.apps = []k8s.Application{
+		{Name: "web-qa", Namespace: "argocd", Project: "frontend", Labels: map[string]string{"env": "qa"}},
+		{Name: "api-qa", Namespace: "argocd", Project: "payments", Labels: map[string]string{"env": "qa"}},
+		{Name: "api-stg", Namespace: "argocd", Project: "payments", Labels: map[string]string{"env": "staging"}},
+		{Name: "tools", Namespace: "argocd", Project: "payments"},
+	}
+	mc.BookApp(context.Background(), "argocd", "api-stg", "alice", k8s.BookOptions{Reason: "release test"})
+	mc.bookings["argocd/api-stg"].Project = "payments"
+	mc.bookings["argocd/api-stg"].Labels = map[string]string{"env": "staging"}
+	return mc, mux
+}
+
+func getBoard(t *testing.T, mux *http.ServeMux, query string) (int, board) {
+	t.Helper()
+	req := httptest.NewRequest("GET", "/api/v1/board?"+query, nil)
+	w := httptest.NewRecorder()
+	mux.ServeHTTP(w, req)
+	var b board
+	json.NewDecoder(w.Body).Decode(&b)
+	return w.Code, b
+}
+
+func TestBoard_GroupsByProject(t *testing.T) {
+	_, mux := setupBoard()
+
+	code, b := getBoard(t, mux, "")
+	if code != http.StatusOK {
+		t.Fatalf("expected 200, got %d", code)
+	}
+	if b.GroupBy != "project" || len(b.Groups) != 2 {
+		t.Fatalf("unexpected board: %+v", b)
+	}
+	payments := b.Groups[1]
+	if payments.Name != "payments" || payments.Booked != 1 || payments.Free != 2 {
+		t.Fatalf("unexpected payments group: %+v", payments)
+	}
+	if names := []string{payments.Applications[0].Name, payments.Applications[1].Name}; names[0] != "api-qa" || names[1] != "api-stg" {
+		t.Fatalf("expected applications sorted by name, got %v", names)
+	}
+	if bk := payments.Applications[1].Booking; bk == nil || bk.BookedBy != "alice" || bk.Reason != "release test" {
+		t.Fatalf("expected the booking with its reason, got %+v", payments.Applications[1])
+	}
+}
+
+func TestBoard_GroupsByLabel(t *testing.T) {
+	_, mux := setupBoard()
+
+	code, b := getBoard(t, mux, "groupBy=label:env&project=payments")
+	if code != http.StatusOK {
+		t.Fatalf("expected 200, got %d", code)
+	}
+	var names []string
+	for _, g := range b.Groups {
+		names = append(names, g.Name)
+	}
+	if !equalStrings(names, []string{"qa", "staging", ""}) {
+		t.Fatalf("expected qa, staging and the unlabelled group last, got %q", names)
+	}
+}
+
+func TestBoard_InvalidParameters(t *testing.T) {
+	_, mux := setupBoard()
+
+	for _, query := range []string{"groupBy=owner", "groupBy=label:", "selector=env%3D%3D%3D"} {
+		if code, _ := getBoard(t, mux, query); code != http.StatusBadRequest {
+			t.Errorf("%q: expected 400, got %d", query, code)
+		}
+	}
+}
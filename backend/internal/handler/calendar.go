@@ -0,0 +1,132 @@
+package handler
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/behavox/argocd-book-plugin/internal/k8s"
+	"github.com/behavox/argocd-book-plugin/internal/logging"
+)
+
+const (
+	icalTimeFormat = "20060102T150405Z"
+	icalLineLimit  = 75
+)
+
+// Calendar returns current bookings as an RFC 5545 iCalendar feed.
+// The feed can be filtered with the namespace, project and user query parameters.
+func (h *Handler) Calendar(w http.ResponseWriter, r *http.Request) {
+	q := h.query(r)
+	ns := q.Get("namespace")
+	project := q.Get("project")
+	user := q.Get("user")
+
+	bookings, err := h.client.ListBookings(r.Context(), ns)
+	if err != nil {
+		requestLogger(r).Error("failed to list bookings", "list_namespace", ns, logging.KeyError, err)
+		writeError(w, http.StatusInternalServerError, "failed to list bookings")
+		return
+	}
+
+	var filtered []k8s.Booking
+	for _, b := range bookings {
+		if project != "" && b.Project != project {
+			continue
+		}
+		if user != "" && b.BookedBy != user {
+			continue
+		}
+		filtered = append(filtered, b)
+	}
+
+	w.Header().Set("Content-Type", "text/calendar; charset=utf-8")
+	w.Header().Set("Content-Disposition", `inline; filename="bookings.ics"`)
+	w.WriteHeader(http.StatusOK)
+	if _, err := w.Write([]byte(renderCalendar(filtered, time.Now()))); err != nil {
+		requestLogger(r).Warn("failed to write response", logging.KeyError, err)
+	}
+}
+
+// renderCalendar builds a VCALENDAR containing one VEVENT per booking.
+// Bookings without a parseable booked-at timestamp are skipped, since DTSTART is mandatory;
+// DTEND is only set for bookings with an expiry.
+func renderCalendar(bookings []k8s.Booking, now time.Time) string {
+	var sb strings.Builder
+	line := func(s string) {
+		sb.WriteString(foldLine(s))
+		sb.WriteString("\r\n")
+	}
+
+	line("BEGIN:VCALENDAR")
+	line("VERSION:2.0")
+	line("PRODID:-//argocd-book-plugin//bookings//EN")
+	line("CALSCALE:GREGORIAN")
+	line("METHOD:PUBLISH")
+	line("X-WR-CALNAME:ArgoCD bookings")
+
+	stamp := now.UTC().Format(icalTimeFormat)
+	for _, b := range bookings {
+		start, err := time.Parse(time.RFC3339, b.BookedAt)
+		if err != nil {
+			continue
+		}
+		line("BEGIN:VEVENT")
+		line(fmt.Sprintf("UID:%s-%s-%d@booking.argocd.io", b.Namespace, b.AppName, start.Unix()))
+		line("DTSTAMP:" + stamp)
+		line("DTSTART:" + start.UTC().Format(icalTimeFormat))
+		if end, err := time.Parse(time.RFC3339, b.ExpiresAt); err == nil && end.After(start) {
+			line("DTEND:" + end.UTC().Format(icalTimeFormat))
+		}
+		line("SUMMARY:" + escapeText(fmt.Sprintf("%s booked by %s", b.AppName, b.BookedBy)))
+		desc := fmt.Sprintf("Application: %s/%s\nBooked by: %s", b.Namespace, b.AppName, b.BookedBy)
+		if b.Project != "" {
+			desc += "\nProject: " + b.Project
+		}
+		if b.Reason != "" {
+			desc += "\nReason: " + b.Reason
+		}
+		line("DESCRIPTION:" + escapeText(desc))
+		line("STATUS:CONFIRMED")
+		line("TRANSP:OPAQUE")
+		line("END:VEVENT")
+	}
+
+	line("END:VCALENDAR")
+	return sb.String()
+}
+
+// escapeText escapes a TEXT property value as described in RFC 5545 section 3.3.11.
+func escapeText(s string) string {
+	r := strings.NewReplacer(
+		`\`, `\\`,
+		";", `\;`,
+		",", `\,`,
+		"\r\n", `\n`,
+		"\n", `\n`,
+	)
+	return r.Replace(s)
+}
+
+// foldLine splits content lines longer than 75 octets, continuing with a single space
+// as described in RFC 5545 section 3.1. Multi-byte UTF-8 sequences are never split.
+func foldLine(s string) string {
+	if len(s) <= icalLineLimit {
+		return s
+	}
+	var sb strings.Builder
+	limit := icalLineLimit
+	width := 0
+	for _, r := range s {
+		n := len(string(r))
+		if width+n > limit {
+			sb.WriteString("\r\n ")
+			width = 0
+			limit = icalLineLimit - 1 // account for the leading space
+		}
+		sb.WriteRune(r)
+		width += n
+	}
+	return sb.String()
+}
@@ -0,0 +1,135 @@
+package handler
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/behavox/argocd-book-plugin/internal/k8s"
+)
+
+func TestCalendar_Feed(t *testing.T) {
+	_, mc, mux := setupHandler()
+
+	mc.bookings["argocd/app1"] = &k8s.Booking{
+		AppName: "app1", Namespace: "argocd", Project: "staging",
+		BookedBy: "alice", BookedAt: "2026-01-15T10:00:00Z",
+	}
+	mc.bookings["argocd/app2"] = &k8s.Booking{
+		AppName: "app2", Namespace: "argocd", Project: "prod",
+		BookedBy: "bob", BookedAt: "2026-01-15T11:00:00Z",
+	}
+
+	req := httptest.NewRequest("GET", "/api/calendar.ics?namespace=argocd", nil)
+	w := httptest.NewRecorder()
+	mux.ServeHTTP(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d", w.Code)
+	}
+	if ct := w.Header().Get("Content-Type"); !strings.HasPrefix(ct, "text/calendar") {
+		t.Fatalf("expected text/calendar content type, got %q", ct)
+	}
+	body := w.Body.String()
+	if !strings.HasPrefix(body, "BEGIN:VCALENDAR\r\n") || !strings.HasSuffix(body, "END:VCALENDAR\r\n") {
+		t.Fatalf("malformed calendar:\n%s", body)
+	}
+	if n := strings.Count(body, "BEGIN:VEVENT"); n != 2 {
+		t.Fatalf("expected 2 events, got %d", n)
+	}
+	if !strings.Contains(body, "DTSTART:20260115T100000Z") {
+		t.Fatalf("expected DTSTART for app1, got:\n%s", body)
+	}
+}
+
+func TestCalendar_Filters(t *testing.T) {
+	_, mc, mux := setupHandler()
+
+	mc.bookings["argocd/app1"] = &k8s.Booking{
+		AppName: "app1", Namespace: "argocd", Project: "staging",
+		BookedBy: "alice", BookedAt: "2026-01-15T10:00:00Z",
+	}
+	mc.bookings["argocd/app2"] = &k8s.Booking{
+		AppName: "app2", Namespace: "argocd", Project: "prod",
+		BookedBy: "bob", BookedAt: "2026-01-15T11:00:00Z",
+	}
+	mc.bookings["argocd/app3"] = &k8s.Booking{
+		AppName: "app3", Namespace: "argocd", Project: "prod",
+		BookedBy: "alice", BookedAt: "2026-01-15T12:00:00Z",
+	}
+
+	tests := []struct {
+		query string
+		want  int
+	}{
+		{"project=prod", 2},
+		{"user=alice", 2},
+		{"project=prod&user=alice", 1},
+		{"user=carol", 0},
+	}
+	for _, tt := range tests {
+		req := httptest.NewRequest("GET", "/api/calendar.ics?"+tt.query, nil)
+		w := httptest.NewRecorder()
+		mux.ServeHTTP(w, req)
+
+		if n := strings.Count(w.Body.String(), "BEGIN:VEVENT"); n != tt.want {
+			t.Errorf("%s: expected %d events, got %d", tt.query, tt.want, n)
+		}
+	}
+}
+
+func TestRenderCalendar_EscapingAndFolding(t *testing.T) {
+	out := renderCalendar([]k8s.Booking{{
+		AppName:   "a-very-long-application-name-that-pushes-the-summary-line-over-the-limit",
+		Namespace: "argocd",
+		BookedBy:  "doe, john; qa",
+		BookedAt:  "2026-01-15T10:00:00Z",
+	}}, time.Date(2026, 1, 16, 0, 0, 0, 0, time.UTC))
+
+	if !strings.Contains(out, `doe\, john\; qa`) {
+		t.Fatalf("expected escaped username, got:\n%s", out)
+	}
+	for _, l := range strings.Split(out, "\r\n") {
+		if len(l) > icalLineLimit {
+			t.Fatalf("line exceeds %d octets: %q", icalLineLimit, l)
+		}
+	}
+	if !strings.Contains(out, "\r\n ") {
+		t.Fatal("expected folded continuation line")
+	}
+}
+
+func TestRenderCalendar_SkipsInvalidTimestamp(t *testing.T) {
+	out := renderCalendar([]k8s.Booking{{
+		AppName: "app1", Namespace: "argocd", BookedBy: "alice", BookedAt: "garbage",
+	}}, time.Now())
+
+	if strings.Contains(out, "BEGIN:VEVENT") {
+		t.Fatalf("expected no events, got:\n%s", out)
+	}
+}
+
+func TestRenderCalendar_ExpiryAndReason(t *testing.T) {
+	out := renderCalendar([]k8s.Booking{{
+		AppName:   "app1",
+		Namespace: "argocd",
+		BookedBy:  "alice",
+		BookedAt:  "2026-01-15T10:00:00Z",
+		ExpiresAt: "2026-01-15T12:00:00Z",
+		Reason:    "load test",
+	}, {
+		AppName:   "app2",
+		Namespace: "argocd",
+		BookedBy:  "bob",
+		BookedAt:  "2026-01-15T10:00:00Z",
+	}}, time.Date(2026, 1, 15, 11, 0, 0, 0, time.UTC))
+
+	if strings.Count(out, "DTEND:") != 1 || !strings.Contains(out, "DTEND:20260115T120000Z") {
+		t.Fatalf("expected a single DTEND for the expiring booking, got:\n%s", out)
+	}
+	if !strings.Contains(out, `Reason: load test`) {
+		t.Fatalf("expected the reason in the description, got:\n%s", out)
+	}
+}
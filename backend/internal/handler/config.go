@@ -0,0 +1,29 @@
+package handler
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/behavox/argocd-book-plugin/internal/config"
+)
+
+// configResponse is the response of GET /api/v1/config.
+type configResponse struct {
+	// Source is the config file in use; empty when running on defaults.
+	Source   string         `json:"source"`
+	LoadedAt string         `json:"loadedAt"`
+	Config   *config.Config `json:"config"`
+}
+
+// Config returns the effective configuration. Only admins may read it.
+func (h *Handler) Config(w http.ResponseWriter, r *http.Request) {
+	if !h.isAdmin(r) {
+		writeError(w, http.StatusForbidden, "forbidden: only admins may read the configuration")
+		return
+	}
+	writeJSON(w, http.StatusOK, configResponse{
+		Source:   h.cfg.Source(),
+		LoadedAt: h.cfg.LoadedAt().Format(time.RFC3339),
+		Config:   h.cfg.Config(),
+	})
+}
@@ -0,0 +1,88 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/behavox/argocd-book-plugin/internal/config"
+	"github.com/behavox/argocd-book-plugin/internal/k8s"
+)
+
+func setupConfigHandler(t *testing.T, doc string) (*mockClient, *http.ServeMux) {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	if err := os.WriteFile(path, []byte(doc), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	store, err := config.Load(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	mc := newMockClient()
+	mux := http.NewServeMux()
+	New(mc, WithConfig(store)).RegisterRoutes(mux)
+	return mc, mux
+}
+
+func TestConfig_AdminOnly(t *testing.T) {
+	_, mux := setupConfigHandler(t, "adminGroups: [platform]\ndefaultNamespace: apps\n")
+
+	get := func(groups string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("GET", "/api/v1/config", nil)
+		req.Header.Set(headerUserGroups, groups)
+		w := httptest.NewRecorder()
+		mux.ServeHTTP(w, req)
+		return w
+	}
+
+	if w := get("admin"); w.Code != http.StatusForbidden {
+		t.Fatalf("expected the default admin group to be replaced, got %d", w.Code)
+	}
+	w := get("dev, platform")
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", w.Code, w.Body.String())
+	}
+	var resp configResponse
+	json.NewDecoder(w.Body).Decode(&resp)
+	if resp.Config.DefaultNamespace != "apps" || resp.Source == "" || resp.LoadedAt == "" {
+		t.Fatalf("unexpected response %+v", resp)
+	}
+}
+
+func TestConfig_AppliesAdminGroupsAndNamespace(t *testing.T) {
+	mc, mux := setupConfigHandler(t, "adminGroups: [platform]\ndefaultNamespace: apps\n")
+	mc.BookApp(context.Background(), "apps", "api", "alice", k8s.BookOptions{})
+
+	req := httptest.NewRequest("GET", "/api/v1/list", nil)
+	w := httptest.NewRecorder()
+	mux.ServeHTTP(w, req)
+	var list bookingList
+	json.NewDecoder(w.Body).Decode(&list)
+	if list.Count.Total != 1 {
+		t.Fatalf("expected the list to default to the apps namespace, got %+v", list)
+	}
+
+	req = httptest.NewRequest("GET", "/api/v1/applications", nil)
+	w = httptest.NewRecorder()
+	mux.ServeHTTP(w, req)
+	var apps applicationList
+	json.NewDecoder(w.Body).Decode(&apps)
+	if len(apps.Items) != 1 || apps.Items[0].Namespace != "apps" {
+		t.Fatalf("expected the applications to default to the apps namespace, got %+v", apps)
+	}
+
+	req = httptest.NewRequest("POST", "/api/v1/unbook", nil)
+	req.Header.Set(headerAppName, "apps:api")
+	req.Header.Set(headerUsername, "bob")
+	req.Header.Set(headerUserGroups, "platform")
+	w = httptest.NewRecorder()
+	mux.ServeHTTP(w, req)
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected a configured admin group to unbook, got %d: %s", w.Code, w.Body.String())
+	}
+}
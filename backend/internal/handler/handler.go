@@ -1,20 +1,31 @@
 package handler
 
 import (
+	"context"
 	"encoding/json"
-	"log"
+	"errors"
+	"fmt"
+	"log/slog"
 	"net/http"
+	"net/url"
 	"strings"
+	"sync"
+	"time"
 
+	"github.com/behavox/argocd-book-plugin/internal/config"
 	"github.com/behavox/argocd-book-plugin/internal/k8s"
+	"github.com/behavox/argocd-book-plugin/internal/logging"
+	"github.com/behavox/argocd-book-plugin/internal/metrics"
+	"github.com/behavox/argocd-book-plugin/internal/notify"
+	"github.com/behavox/argocd-book-plugin/internal/policy"
+	"github.com/behavox/argocd-book-plugin/internal/ratelimit"
 )
 
 const (
 	headerAppName    = "Argocd-Application-Name"
 	headerUsername   = "Argocd-Username"
 	headerUserGroups = "Argocd-User-Groups"
-
-	adminGroup = "admin"
+	headerProject    = "Argocd-Project-Name"
 )
 
 type statusResponse struct {
@@ -23,27 +34,164 @@ type statusResponse struct {
 	BookedAt string `json:"bookedAt,omitempty"`
 }
 
+// actionResult is the response of book, unbook and transfer.
+type actionResult struct {
+	Status   string `json:"status"`
+	BookedBy string `json:"bookedBy,omitempty"`
+	// Duration is how long a booking lasts after policy defaults, if this call
+	// set its expiry.
+	Duration string `json:"duration,omitempty"`
+	// DryRun is set when the request was a dry run and nothing changed.
+	DryRun bool `json:"dryRun,omitempty"`
+}
+
 type errorResponse struct {
-	Error string `json:"error"`
+	Error     string `json:"error"`
+	RequestID string `json:"requestId,omitempty"`
 }
 
 // Handler provides HTTP handlers for the booking API.
 type Handler struct {
-	client k8s.Client
+	client   k8s.Client
+	notifier notify.Notifier
+	cfg      *config.Store
+	log      *slog.Logger
+
+	// waitNotifier, when set, is told when a waiting book request gets its application.
+	waitNotifier notify.Notifier
+
+	// authz, when set, must allow bookAction on an application before it is booked.
+	authz      Authorizer
+	bookAction string
+
+	limiter          *ratelimit.Limiter
+	waits            *waitQueue
+	waitPollInterval time.Duration
+
+	draining  chan struct{} // closed by Drain
+	drainOnce sync.Once
+	readiness readiness
+}
+
+// Authorizer decides whether a user may perform an ArgoCD RBAC action, such as
+// "sync", on an Application. It is implemented by *rbac.Enforcer.
+type Authorizer interface {
+	AllowApp(user string, groups []string, action, project, namespace, name string) bool
+}
+
+// Option configures optional Handler behaviour.
+type Option func(*Handler)
+
+// WithNotifier sends booking state changes to n.
+func WithNotifier(n notify.Notifier) Option {
+	return func(h *Handler) {
+		h.notifier = n
+	}
+}
+
+// WithWaitNotifier tells n, with an EventBooked, when a book request that had
+// to wait for the application gets it.
+func WithWaitNotifier(n notify.Notifier) Option {
+	return func(h *Handler) {
+		h.waitNotifier = n
+	}
+}
+
+// WithLogger sets the logger requests are logged to. Without it the Handler
+// uses slog.Default.
+func WithLogger(l *slog.Logger) Option {
+	return func(h *Handler) {
+		h.log = l
+	}
+}
+
+// WithConfig reads admin groups, the default namespace and booking policies
+// from s on every request, so reloads apply without a restart. Without it the
+// Handler uses config.Default.
+func WithConfig(s *config.Store) Option {
+	return func(h *Handler) {
+		h.cfg = s
+	}
+}
+
+// WithAuthorizer requires callers to hold the ArgoCD permission action on an
+// application, as decided by a, before booking it.
+func WithAuthorizer(a Authorizer, action string) Option {
+	return func(h *Handler) {
+		h.authz = a
+		h.bookAction = action
+	}
 }
 
 // New creates a new Handler with the given K8s client.
-func New(client k8s.Client) *Handler {
-	return &Handler{client: client}
+func New(client k8s.Client, opts ...Option) *Handler {
+	h := &Handler{
+		client:           client,
+		cfg:              config.NewStore(config.Default()),
+		log:              slog.Default(),
+		limiter:          ratelimit.New(),
+		waits:            newWaitQueue(),
+		draining:         make(chan struct{}),
+		waitPollInterval: defaultWaitPollInterval,
+	}
+	for _, opt := range opts {
+		opt(h)
+	}
+	return h
 }
 
-// RegisterRoutes registers all booking API routes on the given mux.
+// route is a single "METHOD /path" pattern and its handler.
+type route struct {
+	pattern string
+	handler http.HandlerFunc
+}
+
+// routes lists every endpoint served by the Handler. The OpenAPI document must describe each of them.
+func (h *Handler) routes() []route {
+	return []route{
+		{"GET /api/v1/status", h.Status},
+		{"POST /api/v1/book", h.BookV1},
+		{"POST /api/v1/unbook", h.UnbookV1},
+		{"POST /api/v1/transfer", h.TransferV1},
+		{"GET /api/v1/list", h.ListV1},
+		{"GET /api/v1/applications", h.Applications},
+		{"GET /api/v1/board", h.Board},
+		{"GET /api/v1/calendar.ics", h.Calendar},
+		{"GET /api/v1/config", h.Config},
+		{"GET /api/v1/openapi.json", h.OpenAPI},
+		{"GET /metrics", metrics.Handler().ServeHTTP},
+
+		// Unversioned routes predating /api/v1, kept as deprecated aliases.
+		{"GET /api/status", deprecated("status", h.Status)},
+		{"POST /api/book", deprecated("book", h.Book)},
+		{"POST /api/unbook", deprecated("unbook", h.Unbook)},
+		{"POST /api/transfer", deprecated("transfer", h.Transfer)},
+		{"GET /api/list", deprecated("list", h.List)},
+		{"GET /api/calendar.ics", deprecated("calendar.ics", h.Calendar)},
+		{"GET /api/openapi.json", deprecated("openapi.json", h.OpenAPI)},
+
+		{"GET /healthz", h.Healthz},
+		{"GET /livez", h.Livez},
+		{"GET /readyz", h.Readyz},
+	}
+}
+
+// RegisterRoutes registers all booking API routes on the given mux. Every
+// request is logged, every request but probes is traced, and the mutating
+// (POST) requests are rate limited.
 func (h *Handler) RegisterRoutes(mux *http.ServeMux) {
-	mux.HandleFunc("GET /api/status", h.Status)
-	mux.HandleFunc("POST /api/book", h.Book)
-	mux.HandleFunc("POST /api/unbook", h.Unbook)
-	mux.HandleFunc("GET /api/list", h.List)
-	mux.HandleFunc("GET /healthz", h.Healthz)
+	for _, rt := range h.routes() {
+		action := rt.action()
+		handler := rt.handler
+		if strings.HasPrefix(rt.pattern, "POST ") {
+			handler = h.rateLimited(action, handler)
+		}
+		var next http.Handler = h.withRequestLog(action, handler)
+		if !isProbe(action) {
+			next = withTracing(rt.pattern, next)
+		}
+		mux.Handle(rt.pattern, next)
+	}
 }
 
 // parseAppHeader parses the "Argocd-Application-Name" header in the format "namespace:appname".
@@ -59,26 +207,95 @@ func parseAppHeader(r *http.Request) (namespace, appName string, ok bool) {
 	return parts[0], parts[1], true
 }
 
-func isAdmin(r *http.Request) bool {
-	groups := r.Header.Get(headerUserGroups)
-	for _, g := range strings.Split(groups, ",") {
-		if strings.TrimSpace(g) == adminGroup {
-			return true
+// userGroups returns the groups in the "Argocd-User-Groups" header.
+func userGroups(r *http.Request) []string {
+	var groups []string
+	for _, g := range strings.Split(r.Header.Get(headerUserGroups), ",") {
+		if g = strings.TrimSpace(g); g != "" {
+			groups = append(groups, g)
 		}
 	}
-	return false
+	return groups
+}
+
+// isAdmin reports whether the caller belongs to a configured admin group.
+func (h *Handler) isAdmin(r *http.Request) bool {
+	return h.cfg.Config().IsAdmin(userGroups(r))
+}
+
+// bookingLimit returns how many applications the caller may hold at once, or 0
+// if they are unlimited. Admins are exempt.
+func (h *Handler) bookingLimit(r *http.Request) int {
+	cfg := h.cfg.Config()
+	if cfg.IsAdmin(userGroups(r)) {
+		return 0
+	}
+	return cfg.BookingLimits.For(userGroups(r))
+}
+
+// transferLimit returns how many applications the target of a transfer may
+// hold, or 0 when the caller is an admin. The target's groups are not known,
+// so the perUser limit applies to them.
+func (h *Handler) transferLimit(r *http.Request) int {
+	cfg := h.cfg.Config()
+	if cfg.IsAdmin(userGroups(r)) {
+		return 0
+	}
+	return cfg.BookingLimits.PerUser
+}
+
+// query returns the query parameters of r with the configured default
+// namespace filled in when the request names none.
+func (h *Handler) query(r *http.Request) url.Values {
+	q := r.URL.Query()
+	if q.Get("namespace") == "" {
+		q.Set("namespace", h.cfg.Config().DefaultNamespace)
+	}
+	return q
+}
+
+// policyFor returns the booking policy of the application addressed by r.
+func (h *Handler) policyFor(r *http.Request, ns string) policy.Policy {
+	return h.cfg.Config().PolicySet().For(ns, r.Header.Get(headerProject))
+}
+
+// canOverride reports whether the caller is an admin and the application's
+// policy lets admins act on bookings held by others.
+func (h *Handler) canOverride(r *http.Request, ns string) bool {
+	return h.isAdmin(r) && h.policyFor(r, ns).AllowsAdminOverride()
+}
+
+// writePolicyError writes a policy.Policy error as a 403 or 422 response.
+func writePolicyError(w http.ResponseWriter, err error) {
+	if errors.Is(err, policy.ErrForbidden) {
+		writeError(w, http.StatusForbidden, err.Error())
+		return
+	}
+	writeError(w, http.StatusUnprocessableEntity, err.Error())
+}
+
+// notify sends e, filling in the project from the request and the timestamp.
+func (h *Handler) notify(r *http.Request, e notify.Event) {
+	if h.notifier == nil {
+		return
+	}
+	e.Project = r.Header.Get(headerProject)
+	e.Timestamp = time.Now().UTC()
+	h.notifier.Notify(e)
 }
 
 func writeJSON(w http.ResponseWriter, status int, v interface{}) {
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(status)
 	if err := json.NewEncoder(w).Encode(v); err != nil {
-		log.Printf("failed to write response: %v", err)
+		slog.Warn("failed to write response", logging.KeyRequestID, w.Header().Get(headerRequestID), logging.KeyError, err)
 	}
 }
 
+// writeError writes an errorResponse. It echoes the request ID set by
+// withRequestLog, so a user can quote it when reporting the error.
 func writeError(w http.ResponseWriter, status int, msg string) {
-	writeJSON(w, status, errorResponse{Error: msg})
+	writeJSON(w, status, errorResponse{Error: msg, RequestID: w.Header().Get(headerRequestID)})
 }
 
 // Status returns the booking status of an application.
@@ -91,7 +308,11 @@ func (h *Handler) Status(w http.ResponseWriter, r *http.Request) {
 
 	bookedBy, bookedAt, err := h.client.GetBookingStatus(r.Context(), ns, app)
 	if err != nil {
-		log.Printf("error getting booking status for %s/%s: %v", ns, app, err)
+		if errors.Is(err, k8s.ErrInvalid) {
+			writeError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		requestLogger(r).Error("failed to get booking status", logging.KeyError, err)
 		writeError(w, http.StatusInternalServerError, "failed to get booking status")
 		return
 	}
@@ -104,8 +325,35 @@ func (h *Handler) Status(w http.ResponseWriter, r *http.Request) {
 	writeJSON(w, http.StatusOK, resp)
 }
 
-// Book books an application for the requesting user.
+// Book books an application for the requesting user. With a "wait" query parameter
+// (a Go duration such as "5m") the request blocks until the application is free,
+// queueing fairly behind other waiters, instead of failing with 409 straight away.
+//
+// Deprecated: use BookV1, which also accepts a reason and a duration.
 func (h *Handler) Book(w http.ResponseWriter, r *http.Request) {
+	var req bookRequest
+	if v := r.URL.Query().Get("wait"); v != "" {
+		d, err := time.ParseDuration(v)
+		if err != nil || d < 0 {
+			writeError(w, http.StatusBadRequest, "invalid \"wait\" query parameter (expected a duration such as 5m)")
+			return
+		}
+		req.Wait = duration(d)
+	}
+	h.book(w, r, req)
+}
+
+// BookV1 books an application for the requesting user, taking an optional
+// bookRequest body with a reason, a duration, a wait or dryRun.
+func (h *Handler) BookV1(w http.ResponseWriter, r *http.Request) {
+	var req bookRequest
+	if !decodeBody(w, r, &req) {
+		return
+	}
+	h.book(w, r, req)
+}
+
+func (h *Handler) book(w http.ResponseWriter, r *http.Request, req bookRequest) {
 	ns, app, ok := parseAppHeader(r)
 	if !ok {
 		writeError(w, http.StatusBadRequest, "missing or invalid Argocd-Application-Name header (expected namespace:appname)")
@@ -118,22 +366,104 @@ func (h *Handler) Book(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	err := h.client.BookApp(r.Context(), ns, app, username)
+	if h.authz != nil && !h.authz.AllowApp(username, userGroups(r), h.bookAction, r.Header.Get(headerProject), ns, app) {
+		writeError(w, http.StatusForbidden, fmt.Sprintf("forbidden: %s lacks the ArgoCD %q permission on %s/%s", username, h.bookAction, ns, app))
+		return
+	}
+
+	p := h.policyFor(r, ns)
+	d, err := p.Book(req.Reason, time.Duration(req.Duration), userGroups(r))
 	if err != nil {
-		if strings.Contains(err.Error(), "conflict:") {
+		writePolicyError(w, err)
+		return
+	}
+	// The policy default applies to a new booking only; a renewal without a
+	// duration keeps its expiry.
+	opts := k8s.BookOptions{
+		Reason:          req.Reason,
+		Duration:        time.Duration(req.Duration),
+		DefaultDuration: d,
+		MaxDuration:     time.Duration(p.MaxDuration),
+		MaxHeld:         h.bookingLimit(r),
+		PauseAutoSync:   p.PauseAutoSync,
+		DryRun:          req.DryRun,
+	}
+	wait := min(time.Duration(req.Wait), maxBookWait)
+	if req.DryRun && wait > 0 {
+		writeError(w, http.StatusBadRequest, "wait cannot be combined with dryRun")
+		return
+	}
+
+	var (
+		booked k8s.BookResult
+		waited bool
+	)
+	if wait > 0 {
+		// Leave the response the usual write timeout after the wait ends.
+		rc := http.NewResponseController(w)
+		if err := rc.SetWriteDeadline(time.Now().Add(wait + WriteTimeout)); err != nil && !errors.Is(err, http.ErrNotSupported) {
+			requestLogger(r).Warn("failed to extend the write deadline of a waiting book request", logging.KeyError, err)
+		}
+		ctx, cancel := context.WithTimeout(r.Context(), wait)
+		defer cancel()
+		booked, waited, err = h.waitAndBook(ctx, ns, app, username, opts, wait)
+	} else {
+		booked, err = h.client.BookApp(r.Context(), ns, app, username, opts)
+	}
+	if err != nil {
+		if errors.Is(err, k8s.ErrInvalid) {
+			writeError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		if errors.Is(err, k8s.ErrConflict) {
 			writeError(w, http.StatusConflict, err.Error())
 			return
 		}
-		log.Printf("error booking %s/%s for %s: %v", ns, app, username, err)
+		if errors.Is(err, k8s.ErrLimit) {
+			writeError(w, http.StatusUnprocessableEntity, err.Error())
+			return
+		}
+		if errors.Is(err, errDraining) {
+			w.Header().Set("Retry-After", "5")
+			writeError(w, http.StatusServiceUnavailable, err.Error())
+			return
+		}
+		requestLogger(r).Error("failed to book application", logging.KeyError, err)
 		writeError(w, http.StatusInternalServerError, "failed to book application")
 		return
 	}
 
-	writeJSON(w, http.StatusOK, map[string]string{"status": "booked"})
+	result := actionResult{Status: "booked", BookedBy: username, DryRun: req.DryRun}
+	if booked.Duration > 0 {
+		result.Duration = booked.Duration.String()
+	}
+	if booked.Changed && !req.DryRun {
+		e := notify.Event{Type: notify.EventBooked, Namespace: ns, AppName: app, User: username, Reason: req.Reason}
+		h.notify(r, e)
+		if waited && h.waitNotifier != nil {
+			e.Project = r.Header.Get(headerProject)
+			e.Timestamp = time.Now().UTC()
+			h.waitNotifier.Notify(e)
+		}
+	}
+	writeJSON(w, http.StatusOK, result)
+}
+
+// UnbookV1 unbooks an application as described by the unbookRequest body.
+func (h *Handler) UnbookV1(w http.ResponseWriter, r *http.Request) {
+	var req unbookRequest
+	if !decodeBody(w, r, &req) {
+		return
+	}
+	h.unbook(w, r, req)
 }
 
 // Unbook unbooks an application.
 func (h *Handler) Unbook(w http.ResponseWriter, r *http.Request) {
+	h.unbook(w, r, unbookRequest{})
+}
+
+func (h *Handler) unbook(w http.ResponseWriter, r *http.Request, req unbookRequest) {
 	ns, app, ok := parseAppHeader(r)
 	if !ok {
 		writeError(w, http.StatusBadRequest, "missing or invalid Argocd-Application-Name header (expected namespace:appname)")
@@ -146,30 +476,121 @@ func (h *Handler) Unbook(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	err := h.client.UnbookApp(r.Context(), ns, app, username, isAdmin(r))
+	released, err := h.client.UnbookApp(r.Context(), ns, app, username, h.canOverride(r, ns), k8s.UnbookOptions{DryRun: req.DryRun})
 	if err != nil {
-		if strings.Contains(err.Error(), "forbidden:") {
+		if errors.Is(err, k8s.ErrInvalid) {
+			writeError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		if errors.Is(err, k8s.ErrForbidden) {
 			writeError(w, http.StatusForbidden, err.Error())
 			return
 		}
-		log.Printf("error unbooking %s/%s by %s: %v", ns, app, username, err)
+		if errors.Is(err, k8s.ErrConflict) {
+			writeError(w, http.StatusConflict, err.Error())
+			return
+		}
+		requestLogger(r).Error("failed to unbook application", logging.KeyError, err)
 		writeError(w, http.StatusInternalServerError, "failed to unbook application")
 		return
 	}
 
-	writeJSON(w, http.StatusOK, map[string]string{"status": "unbooked"})
+	if released && !req.DryRun {
+		h.waits.released(ns + "/" + app)
+		h.notify(r, notify.Event{Type: notify.EventUnbooked, Namespace: ns, AppName: app, User: username})
+	}
+	writeJSON(w, http.StatusOK, actionResult{Status: "unbooked", DryRun: req.DryRun})
 }
 
-// List returns all currently booked applications.
-func (h *Handler) List(w http.ResponseWriter, r *http.Request) {
-	ns := r.URL.Query().Get("namespace")
-	if ns == "" {
-		ns = "argocd"
+// Transfer hands an existing booking over to the user given in the "to" query parameter.
+//
+// Deprecated: use TransferV1.
+func (h *Handler) Transfer(w http.ResponseWriter, r *http.Request) {
+	target := r.URL.Query().Get("to")
+	if target == "" {
+		writeError(w, http.StatusBadRequest, "missing \"to\" query parameter")
+		return
+	}
+	h.transfer(w, r, transferRequest{To: target})
+}
+
+// TransferV1 hands an existing booking over to the user given in the transferRequest body.
+func (h *Handler) TransferV1(w http.ResponseWriter, r *http.Request) {
+	var req transferRequest
+	if !decodeBody(w, r, &req) {
+		return
+	}
+	if req.To == "" {
+		writeError(w, http.StatusBadRequest, "missing \"to\" in request body")
+		return
 	}
+	h.transfer(w, r, req)
+}
+
+func (h *Handler) transfer(w http.ResponseWriter, r *http.Request, req transferRequest) {
+	target := req.To
+	ns, app, ok := parseAppHeader(r)
+	if !ok {
+		writeError(w, http.StatusBadRequest, "missing or invalid Argocd-Application-Name header (expected namespace:appname)")
+		return
+	}
+
+	username := r.Header.Get(headerUsername)
+	if username == "" {
+		writeError(w, http.StatusBadRequest, "missing Argocd-Username header")
+		return
+	}
+
+	// The target must be able to book the application themselves. Their groups
+	// are not known, so only grants to the user count.
+	if h.authz != nil && !h.authz.AllowApp(target, nil, h.bookAction, r.Header.Get(headerProject), ns, app) {
+		writeError(w, http.StatusForbidden, fmt.Sprintf("forbidden: %s lacks the ArgoCD %q permission on %s/%s", target, h.bookAction, ns, app))
+		return
+	}
+	if err := h.policyFor(r, ns).Transfer(target); err != nil {
+		writePolicyError(w, err)
+		return
+	}
+
+	opts := k8s.TransferOptions{MaxHeld: h.transferLimit(r), DryRun: req.DryRun}
+	previous, err := h.client.TransferApp(r.Context(), ns, app, username, target, h.canOverride(r, ns), opts)
+	if err != nil {
+		if errors.Is(err, k8s.ErrInvalid) {
+			writeError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		if errors.Is(err, k8s.ErrConflict) {
+			writeError(w, http.StatusConflict, err.Error())
+			return
+		}
+		if errors.Is(err, k8s.ErrForbidden) {
+			writeError(w, http.StatusForbidden, err.Error())
+			return
+		}
+		if errors.Is(err, k8s.ErrLimit) {
+			writeError(w, http.StatusUnprocessableEntity, err.Error())
+			return
+		}
+		requestLogger(r).Error("failed to transfer application", "to", target, logging.KeyError, err)
+		writeError(w, http.StatusInternalServerError, "failed to transfer application")
+		return
+	}
+
+	if previous != target && !req.DryRun {
+		h.notify(r, notify.Event{Type: notify.EventTransferred, Namespace: ns, AppName: app, User: target, PreviousUser: previous})
+	}
+	writeJSON(w, http.StatusOK, actionResult{Status: "transferred", BookedBy: target, DryRun: req.DryRun})
+}
+
+// List returns all currently booked applications as a plain array.
+//
+// Deprecated: use ListV1, which filters, sorts and paginates.
+func (h *Handler) List(w http.ResponseWriter, r *http.Request) {
+	ns := h.query(r).Get("namespace")
 
 	bookings, err := h.client.ListBookings(r.Context(), ns)
 	if err != nil {
-		log.Printf("error listing bookings in %s: %v", ns, err)
+		requestLogger(r).Error("failed to list bookings", "list_namespace", ns, logging.KeyError, err)
 		writeError(w, http.StatusInternalServerError, "failed to list bookings")
 		return
 	}
@@ -180,8 +601,8 @@ func (h *Handler) List(w http.ResponseWriter, r *http.Request) {
 	writeJSON(w, http.StatusOK, bookings)
 }
 
-// Healthz is a simple health check endpoint.
+// Healthz is a simple health check endpoint, kept for existing probes; it
+// behaves like Livez.
 func (h *Handler) Healthz(w http.ResponseWriter, r *http.Request) {
-	w.WriteHeader(http.StatusOK)
-	w.Write([]byte("ok"))
+	h.Livez(w, r)
 }
@@ -6,15 +6,23 @@ import (
 	"fmt"
 	"net/http"
 	"net/http/httptest"
+	"strings"
+	"sync"
 	"testing"
 	"time"
 
+	"github.com/behavox/argocd-book-plugin/internal/config"
 	"github.com/behavox/argocd-book-plugin/internal/k8s"
+	"github.com/behavox/argocd-book-plugin/internal/notify"
+	"github.com/behavox/argocd-book-plugin/internal/rbac"
 )
 
 // mockClient implements k8s.Client for testing.
 type mockClient struct {
+	mu       sync.Mutex
 	bookings map[string]*k8s.Booking // key: "namespace/appName"
+	apps     []k8s.Application       // free applications returned by ListApplications
+	lastOpts k8s.BookOptions         // options of the last BookApp call
 }
 
 func newMockClient() *mockClient {
@@ -24,6 +32,8 @@ func newMockClient() *mockClient {
 func (m *mockClient) key(ns, app string) string { return ns + "/" + app }
 
 func (m *mockClient) GetBookingStatus(_ context.Context, namespace, appName string) (string, time.Time, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
 	b, ok := m.bookings[m.key(namespace, appName)]
 	if !ok || b.BookedBy == "" {
 		return "", time.Time{}, nil
@@ -32,34 +42,110 @@ func (m *mockClient) GetBookingStatus(_ context.Context, namespace, appName stri
 	return b.BookedBy, t, nil
 }
 
-func (m *mockClient) BookApp(_ context.Context, namespace, appName, username string) error {
+func (m *mockClient) BookApp(_ context.Context, namespace, appName, username string, opts k8s.BookOptions) (k8s.BookResult, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.lastOpts = opts
 	k := m.key(namespace, appName)
 	if b, ok := m.bookings[k]; ok && b.BookedBy != "" && b.BookedBy != username {
-		return fmt.Errorf("conflict: application already booked by %s", b.BookedBy)
+		return k8s.BookResult{}, fmt.Errorf("%w: application already booked by %s", k8s.ErrConflict, b.BookedBy)
 	}
-	m.bookings[k] = &k8s.Booking{
-		AppName:   appName,
-		Namespace: namespace,
-		BookedBy:  username,
-		BookedAt:  time.Now().UTC().Format(time.RFC3339),
+	if b, ok := m.bookings[k]; opts.MaxHeld > 0 && (!ok || b.BookedBy != username) {
+		held := 0
+		for _, b := range m.bookings {
+			if b.BookedBy == username {
+				held++
+			}
+		}
+		if held >= opts.MaxHeld {
+			return k8s.BookResult{}, fmt.Errorf("%w: %s already holds %d applications", k8s.ErrLimit, username, held)
+		}
 	}
-	return nil
+	now := time.Now().UTC()
+	d := opts.Duration
+	b, renewal := m.bookings[k]
+	renewal = renewal && b.BookedBy == username
+	if renewal {
+		if opts.Reason == "" && d == 0 {
+			return k8s.BookResult{}, nil
+		}
+		start, _ := time.Parse(time.RFC3339, b.BookedAt)
+		if opts.MaxDuration > 0 && d > 0 && now.Add(d).After(start.Add(opts.MaxDuration)) {
+			return k8s.BookResult{}, fmt.Errorf("%w: the booking may last at most %s", k8s.ErrLimit, opts.MaxDuration)
+		}
+	} else if d == 0 {
+		d = opts.DefaultDuration
+	}
+	if opts.DryRun {
+		return k8s.BookResult{Changed: true, Duration: d}, nil
+	}
+	if !renewal {
+		b = &k8s.Booking{AppName: appName, Namespace: namespace, BookedBy: username, BookedAt: now.Format(time.RFC3339)}
+	}
+	if opts.Reason != "" {
+		b.Reason = opts.Reason
+	}
+	if d > 0 {
+		b.ExpiresAt = now.Add(d).Format(time.RFC3339)
+	}
+	m.bookings[k] = b
+	return k8s.BookResult{Changed: true, Duration: d}, nil
 }
 
-func (m *mockClient) UnbookApp(_ context.Context, namespace, appName, username string, isAdmin bool) error {
+func (m *mockClient) UnbookApp(_ context.Context, namespace, appName, username string, isAdmin bool, opts k8s.UnbookOptions) (bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
 	k := m.key(namespace, appName)
 	b, ok := m.bookings[k]
 	if !ok || b.BookedBy == "" {
-		return nil
+		return false, nil
 	}
 	if b.BookedBy != username && !isAdmin {
-		return fmt.Errorf("forbidden: application is booked by %s, only they or an admin can unbook", b.BookedBy)
+		return false, fmt.Errorf("%w: application is booked by %s, only they or an admin can unbook", k8s.ErrForbidden, b.BookedBy)
+	}
+	if opts.DryRun {
+		return true, nil
 	}
 	delete(m.bookings, k)
+	return true, nil
+}
+
+func (m *mockClient) ResumeAutoSync(_ context.Context, _, _ string) error {
 	return nil
 }
 
+func (m *mockClient) TransferApp(_ context.Context, namespace, appName, username, target string, isAdmin bool, opts k8s.TransferOptions) (string, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	b, ok := m.bookings[m.key(namespace, appName)]
+	if !ok || b.BookedBy == "" {
+		return "", fmt.Errorf("%w: application is not booked", k8s.ErrConflict)
+	}
+	if b.BookedBy != username && !isAdmin {
+		return "", fmt.Errorf("%w: application is booked by %s, only they or an admin can transfer it", k8s.ErrForbidden, b.BookedBy)
+	}
+	if opts.MaxHeld > 0 && b.BookedBy != target {
+		held := 0
+		for _, b := range m.bookings {
+			if b.BookedBy == target {
+				held++
+			}
+		}
+		if held >= opts.MaxHeld {
+			return "", fmt.Errorf("%w: %s already holds %d applications", k8s.ErrLimit, target, held)
+		}
+	}
+	previous := b.BookedBy
+	if opts.DryRun {
+		return previous, nil
+	}
+	b.BookedBy = target
+	return previous, nil
+}
+
 func (m *mockClient) ListBookings(_ context.Context, namespace string) ([]k8s.Booking, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
 	var result []k8s.Booking
 	for _, b := range m.bookings {
 		if b.Namespace == namespace {
@@ -69,6 +155,28 @@ func (m *mockClient) ListBookings(_ context.Context, namespace string) ([]k8s.Bo
 	return result, nil
 }
 
+func (m *mockClient) ListExpiredBookings(_ context.Context, _ string) ([]k8s.Booking, error) {
+	return nil, nil
+}
+
+func (m *mockClient) ListApplications(_ context.Context, namespace string) ([]k8s.Application, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var result []k8s.Application
+	for _, a := range m.apps {
+		if a.Namespace == namespace && m.bookings[m.key(a.Namespace, a.Name)] == nil {
+			result = append(result, a)
+		}
+	}
+	for _, b := range m.bookings {
+		if b.Namespace == namespace {
+			b := *b
+			result = append(result, k8s.Application{Name: b.AppName, Namespace: b.Namespace, Project: b.Project, Labels: b.Labels, Booking: &b})
+		}
+	}
+	return result, nil
+}
+
 func setupHandler() (*Handler, *mockClient, *http.ServeMux) {
 	mc := newMockClient()
 	h := New(mc)
@@ -138,7 +246,7 @@ func TestBook_Conflict(t *testing.T) {
 	_, mc, mux := setupHandler()
 
 	// Pre-book as alice
-	mc.BookApp(context.Background(), "argocd", "my-app", "alice")
+	mc.BookApp(context.Background(), "argocd", "my-app", "alice", k8s.BookOptions{})
 
 	// Bob tries to book
 	req := httptest.NewRequest("POST", "/api/book", nil)
@@ -155,7 +263,7 @@ func TestBook_Conflict(t *testing.T) {
 func TestUnbook_ByBooker(t *testing.T) {
 	_, mc, mux := setupHandler()
 
-	mc.BookApp(context.Background(), "argocd", "my-app", "alice")
+	mc.BookApp(context.Background(), "argocd", "my-app", "alice", k8s.BookOptions{})
 
 	req := httptest.NewRequest("POST", "/api/unbook", nil)
 	req.Header.Set(headerAppName, "argocd:my-app")
@@ -171,7 +279,7 @@ func TestUnbook_ByBooker(t *testing.T) {
 func TestUnbook_ByOtherUser_Forbidden(t *testing.T) {
 	_, mc, mux := setupHandler()
 
-	mc.BookApp(context.Background(), "argocd", "my-app", "alice")
+	mc.BookApp(context.Background(), "argocd", "my-app", "alice", k8s.BookOptions{})
 
 	req := httptest.NewRequest("POST", "/api/unbook", nil)
 	req.Header.Set(headerAppName, "argocd:my-app")
@@ -187,7 +295,7 @@ func TestUnbook_ByOtherUser_Forbidden(t *testing.T) {
 func TestUnbook_ByAdmin(t *testing.T) {
 	_, mc, mux := setupHandler()
 
-	mc.BookApp(context.Background(), "argocd", "my-app", "alice")
+	mc.BookApp(context.Background(), "argocd", "my-app", "alice", k8s.BookOptions{})
 
 	req := httptest.NewRequest("POST", "/api/unbook", nil)
 	req.Header.Set(headerAppName, "argocd:my-app")
@@ -221,8 +329,8 @@ func TestList_Empty(t *testing.T) {
 func TestList_WithBookings(t *testing.T) {
 	_, mc, mux := setupHandler()
 
-	mc.BookApp(context.Background(), "argocd", "app1", "alice")
-	mc.BookApp(context.Background(), "argocd", "app2", "bob")
+	mc.BookApp(context.Background(), "argocd", "app1", "alice", k8s.BookOptions{})
+	mc.BookApp(context.Background(), "argocd", "app2", "bob", k8s.BookOptions{})
 
 	req := httptest.NewRequest("GET", "/api/list?namespace=argocd", nil)
 	w := httptest.NewRecorder()
@@ -262,3 +370,513 @@ func TestHealthz(t *testing.T) {
 		t.Fatalf("expected 200, got %d", w.Code)
 	}
 }
+
+type recordingNotifier struct {
+	events []notify.Event
+}
+
+func (n *recordingNotifier) Notify(e notify.Event) { n.events = append(n.events, e) }
+
+func TestBookUnbook_Notifies(t *testing.T) {
+	rn := &recordingNotifier{}
+	h := New(newMockClient(), WithNotifier(rn))
+	mux := http.NewServeMux()
+	h.RegisterRoutes(mux)
+
+	for _, path := range []string{"/api/book", "/api/unbook"} {
+		req := httptest.NewRequest("POST", path, nil)
+		req.Header.Set(headerAppName, "argocd:my-app")
+		req.Header.Set(headerUsername, "alice")
+		req.Header.Set(headerProject, "staging")
+		w := httptest.NewRecorder()
+		mux.ServeHTTP(w, req)
+		if w.Code != http.StatusOK {
+			t.Fatalf("%s: expected 200, got %d: %s", path, w.Code, w.Body.String())
+		}
+	}
+
+	if len(rn.events) != 2 {
+		t.Fatalf("expected 2 events, got %d", len(rn.events))
+	}
+	if rn.events[0].Type != notify.EventBooked || rn.events[1].Type != notify.EventUnbooked {
+		t.Fatalf("unexpected event types: %+v", rn.events)
+	}
+	if rn.events[0].User != "alice" || rn.events[0].Project != "staging" || rn.events[0].AppName != "my-app" {
+		t.Fatalf("unexpected event: %+v", rn.events[0])
+	}
+}
+
+func TestBook_ConflictDoesNotNotify(t *testing.T) {
+	rn := &recordingNotifier{}
+	mc := newMockClient()
+	h := New(mc, WithNotifier(rn))
+	mux := http.NewServeMux()
+	h.RegisterRoutes(mux)
+
+	mc.BookApp(context.Background(), "argocd", "my-app", "alice", k8s.BookOptions{})
+
+	req := httptest.NewRequest("POST", "/api/book", nil)
+	req.Header.Set(headerAppName, "argocd:my-app")
+	req.Header.Set(headerUsername, "bob")
+	w := httptest.NewRecorder()
+	mux.ServeHTTP(w, req)
+
+	if len(rn.events) != 0 {
+		t.Fatalf("expected no events, got %+v", rn.events)
+	}
+}
+
+func TestNoOps_DoNotNotify(t *testing.T) {
+	rn := &recordingNotifier{}
+	mc := newMockClient()
+	h := New(mc, WithNotifier(rn))
+	mux := http.NewServeMux()
+	h.RegisterRoutes(mux)
+
+	mc.BookApp(context.Background(), "argocd", "my-app", "alice", k8s.BookOptions{})
+
+	for _, tc := range []struct{ path, app, body string }{
+		{"/api/v1/unbook", "argocd:free", `{}`},
+		{"/api/v1/book", "argocd:my-app", `{}`},
+		{"/api/v1/transfer", "argocd:my-app", `{"to":"alice"}`},
+	} {
+		req := httptest.NewRequest("POST", tc.path, strings.NewReader(tc.body))
+		req.Header.Set(headerAppName, tc.app)
+		req.Header.Set(headerUsername, "alice")
+		w := httptest.NewRecorder()
+		mux.ServeHTTP(w, req)
+		if w.Code != http.StatusOK {
+			t.Fatalf("%s: expected 200, got %d: %s", tc.path, w.Code, w.Body.String())
+		}
+	}
+
+	if len(rn.events) != 0 {
+		t.Fatalf("expected no events for calls that changed nothing, got %+v", rn.events)
+	}
+}
+
+func TestTransfer_ByHolder(t *testing.T) {
+	_, mc, mux := setupHandler()
+
+	mc.BookApp(context.Background(), "argocd", "my-app", "alice", k8s.BookOptions{})
+
+	req := httptest.NewRequest("POST", "/api/transfer?to=bob", nil)
+	req.Header.Set(headerAppName, "argocd:my-app")
+	req.Header.Set(headerUsername, "alice")
+	w := httptest.NewRecorder()
+	mux.ServeHTTP(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", w.Code, w.Body.String())
+	}
+	if got := mc.bookings["argocd/my-app"].BookedBy; got != "bob" {
+		t.Fatalf("expected booking held by bob, got %q", got)
+	}
+}
+
+func TestTransfer_Errors(t *testing.T) {
+	_, mc, mux := setupHandler()
+
+	mc.BookApp(context.Background(), "argocd", "booked", "alice", k8s.BookOptions{})
+
+	tests := []struct {
+		name  string
+		app   string
+		user  string
+		query string
+		want  int
+	}{
+		{"missing target", "argocd:booked", "alice", "", http.StatusBadRequest},
+		{"not holder", "argocd:booked", "carol", "?to=bob", http.StatusForbidden},
+		{"not booked", "argocd:free", "alice", "?to=bob", http.StatusConflict},
+	}
+	for _, tt := range tests {
+		req := httptest.NewRequest("POST", "/api/transfer"+tt.query, nil)
+		req.Header.Set(headerAppName, tt.app)
+		req.Header.Set(headerUsername, tt.user)
+		w := httptest.NewRecorder()
+		mux.ServeHTTP(w, req)
+
+		if w.Code != tt.want {
+			t.Errorf("%s: expected %d, got %d: %s", tt.name, tt.want, w.Code, w.Body.String())
+		}
+	}
+}
+
+func TestBookV1_ReasonAndDuration(t *testing.T) {
+	rn := &recordingNotifier{}
+	mc := newMockClient()
+	h := New(mc, WithNotifier(rn))
+	mux := http.NewServeMux()
+	h.RegisterRoutes(mux)
+
+	req := httptest.NewRequest("POST", "/api/v1/book", strings.NewReader(`{"reason":"load test","duration":"2h"}`))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(headerAppName, "argocd:my-app")
+	req.Header.Set(headerUsername, "alice")
+	w := httptest.NewRecorder()
+	mux.ServeHTTP(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", w.Code, w.Body.String())
+	}
+	b := mc.bookings["argocd/my-app"]
+	if b.Reason != "load test" || b.ExpiresAt == "" {
+		t.Fatalf("expected reason and expiry to be stored, got %+v", b)
+	}
+	if len(rn.events) != 1 || rn.events[0].Reason != "load test" {
+		t.Fatalf("expected the reason in the booked event, got %+v", rn.events)
+	}
+}
+
+func TestTransferV1_Body(t *testing.T) {
+	_, mc, mux := setupHandler()
+
+	mc.BookApp(context.Background(), "argocd", "my-app", "alice", k8s.BookOptions{})
+
+	tests := []struct {
+		body string
+		want int
+	}{
+		{"", http.StatusBadRequest},
+		{`{}`, http.StatusBadRequest},
+		{`{"to":"bob","extra":1}`, http.StatusBadRequest},
+		{`{"to":"bob"}`, http.StatusOK},
+	}
+	for _, tt := range tests {
+		req := httptest.NewRequest("POST", "/api/v1/transfer", strings.NewReader(tt.body))
+		req.Header.Set(headerAppName, "argocd:my-app")
+		req.Header.Set(headerUsername, "alice")
+		w := httptest.NewRecorder()
+		mux.ServeHTTP(w, req)
+
+		if w.Code != tt.want {
+			t.Errorf("body %q: expected %d, got %d: %s", tt.body, tt.want, w.Code, w.Body.String())
+		}
+	}
+	if got := mc.bookings["argocd/my-app"].BookedBy; got != "bob" {
+		t.Fatalf("expected booking held by bob, got %q", got)
+	}
+}
+
+func TestDryRun(t *testing.T) {
+	rn := &recordingNotifier{}
+	mc := newMockClient()
+	mux := http.NewServeMux()
+	New(mc, WithNotifier(rn)).RegisterRoutes(mux)
+	post := func(path, app, user, body string) (int, actionResult) {
+		req := httptest.NewRequest("POST", path, strings.NewReader(body))
+		req.Header.Set(headerAppName, "argocd:"+app)
+		req.Header.Set(headerUsername, user)
+		w := httptest.NewRecorder()
+		mux.ServeHTTP(w, req)
+		var res actionResult
+		json.NewDecoder(w.Body).Decode(&res)
+		return w.Code, res
+	}
+
+	code, res := post("/api/v1/book", "free", "alice", `{"dryRun":true,"duration":"1h"}`)
+	if code != http.StatusOK || !res.DryRun || res.Status != "booked" || res.Duration != "1h0m0s" {
+		t.Fatalf("expected a dry-run booking result, got %d %+v", code, res)
+	}
+	if _, booked := mc.bookings["argocd/free"]; booked {
+		t.Fatal("expected a dry run not to book")
+	}
+
+	mc.BookApp(context.Background(), "argocd", "held", "alice", k8s.BookOptions{})
+	if code, _ := post("/api/v1/book", "held", "bob", `{"dryRun":true}`); code != http.StatusConflict {
+		t.Fatalf("expected the conflict check to run, got %d", code)
+	}
+	if code, _ := post("/api/v1/book", "free", "bob", `{"dryRun":true,"wait":"1m"}`); code != http.StatusBadRequest {
+		t.Fatalf("expected dryRun with wait to be rejected, got %d", code)
+	}
+	if code, _ := post("/api/v1/unbook", "held", "bob", `{"dryRun":true}`); code != http.StatusForbidden {
+		t.Fatalf("expected the holder check to run, got %d", code)
+	}
+	if code, res := post("/api/v1/transfer", "held", "alice", `{"to":"bob","dryRun":true}`); code != http.StatusOK || !res.DryRun {
+		t.Fatalf("expected a dry-run transfer result, got %d %+v", code, res)
+	}
+	if code, res := post("/api/v1/unbook", "held", "alice", `{"dryRun":true}`); code != http.StatusOK || !res.DryRun {
+		t.Fatalf("expected a dry-run unbook result, got %d %+v", code, res)
+	}
+
+	if got := mc.bookings["argocd/held"].BookedBy; got != "alice" {
+		t.Fatalf("expected the booking to stay with alice, got %q", got)
+	}
+	if len(rn.events) != 0 {
+		t.Fatalf("expected no notifications for dry runs, got %+v", rn.events)
+	}
+}
+
+func TestBookV1_Policy(t *testing.T) {
+	cfg, err := config.Parse([]byte(`
+policies:
+  - project: prod
+    requireReason: true
+    maxDuration: 2h
+    allowedGroups: [sre]
+    adminOverride: false
+    pauseAutoSync: true
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	mc := newMockClient()
+	mux := http.NewServeMux()
+	New(mc, WithConfig(config.NewStore(cfg))).RegisterRoutes(mux)
+
+	do := func(path, body, user, groups string) int {
+		req := httptest.NewRequest("POST", path, strings.NewReader(body))
+		req.Header.Set(headerAppName, "argocd:api")
+		req.Header.Set(headerProject, "prod")
+		req.Header.Set(headerUsername, user)
+		req.Header.Set(headerUserGroups, groups)
+		w := httptest.NewRecorder()
+		mux.ServeHTTP(w, req)
+		return w.Code
+	}
+
+	tests := []struct {
+		body, groups string
+		want         int
+	}{
+		{`{"reason":"hotfix"}`, "dev", http.StatusForbidden},
+		{`{}`, "sre", http.StatusUnprocessableEntity},
+		{`{"reason":"hotfix","duration":"3h"}`, "sre", http.StatusUnprocessableEntity},
+		{`{"reason":"hotfix"}`, "sre", http.StatusOK},
+	}
+	for _, tt := range tests {
+		if code := do("/api/v1/book", tt.body, "alice", tt.groups); code != tt.want {
+			t.Errorf("%s as %s: expected %d, got %d", tt.body, tt.groups, tt.want, code)
+		}
+	}
+	if b := mc.bookings["argocd/api"]; b == nil || b.ExpiresAt == "" {
+		t.Fatalf("expected the policy maximum to apply as the duration, got %+v", b)
+	}
+	if !mc.lastOpts.PauseAutoSync {
+		t.Fatal("expected the policy to pause automated sync")
+	}
+
+	if code := do("/api/v1/unbook", "", "bob", "admin"); code != http.StatusForbidden {
+		t.Fatalf("expected admin override to be refused, got %d", code)
+	}
+}
+
+func TestBookV1_PolicyCapsRenewals(t *testing.T) {
+	cfg, err := config.Parse([]byte(`
+policies:
+  - project: prod
+    maxDuration: 2h
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	mc := newMockClient()
+	bookedAt := time.Now().UTC().Add(-90 * time.Minute)
+	mc.bookings["argocd/api"] = &k8s.Booking{AppName: "api", Namespace: "argocd", BookedBy: "alice", BookedAt: bookedAt.Format(time.RFC3339)}
+	mux := http.NewServeMux()
+	New(mc, WithConfig(config.NewStore(cfg))).RegisterRoutes(mux)
+
+	renew := func(body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("POST", "/api/v1/book", strings.NewReader(body))
+		req.Header.Set(headerAppName, "argocd:api")
+		req.Header.Set(headerProject, "prod")
+		req.Header.Set(headerUsername, "alice")
+		w := httptest.NewRecorder()
+		mux.ServeHTTP(w, req)
+		return w
+	}
+
+	// Re-booking without a duration leaves the booking as it is rather than
+	// renewing it for the policy default.
+	w := renew(`{}`)
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected a re-book without a duration to succeed, got %d: %s", w.Code, w.Body.String())
+	}
+	var resp actionResult
+	json.NewDecoder(w.Body).Decode(&resp)
+	if resp.Duration != "" {
+		t.Fatalf("expected no duration reported, got %q", resp.Duration)
+	}
+	if b := mc.bookings["argocd/api"]; b.ExpiresAt != "" {
+		t.Fatalf("expected the booking left unchanged, got %+v", b)
+	}
+
+	if code := renew(`{"duration":"1h"}`).Code; code != http.StatusUnprocessableEntity {
+		t.Fatalf("expected a renewal past the policy maximum to be refused, got %d", code)
+	}
+	if mc.lastOpts.MaxDuration != 2*time.Hour {
+		t.Fatalf("expected the policy maximum to be passed on, got %s", mc.lastOpts.MaxDuration)
+	}
+	if code := renew(`{"duration":"20m"}`).Code; code != http.StatusOK {
+		t.Fatalf("expected a renewal within the policy maximum to succeed, got %d", code)
+	}
+	if b := mc.bookings["argocd/api"]; b.BookedAt != bookedAt.Format(time.RFC3339) {
+		t.Fatalf("expected the renewal to keep booked-at, got %+v", b)
+	}
+}
+
+func TestBookV1_BookingLimits(t *testing.T) {
+	cfg, err := config.Parse([]byte(`
+bookingLimits:
+  perUser: 1
+  groups: {qa: 2}
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	mc := newMockClient()
+	mux := http.NewServeMux()
+	New(mc, WithConfig(config.NewStore(cfg))).RegisterRoutes(mux)
+
+	book := func(app, user, groups string) int {
+		req := httptest.NewRequest("POST", "/api/v1/book", nil)
+		req.Header.Set(headerAppName, "argocd:"+app)
+		req.Header.Set(headerUsername, user)
+		req.Header.Set(headerUserGroups, groups)
+		w := httptest.NewRecorder()
+		mux.ServeHTTP(w, req)
+		return w.Code
+	}
+
+	tests := []struct {
+		app, user, groups string
+		want              int
+	}{
+		{"a", "alice", "dev", http.StatusOK},
+		{"a", "alice", "dev", http.StatusOK},
+		{"b", "alice", "dev", http.StatusUnprocessableEntity},
+		{"b", "carol", "qa", http.StatusOK},
+		{"c", "carol", "qa", http.StatusOK},
+		{"d", "carol", "qa", http.StatusUnprocessableEntity},
+		{"d", "dave", "admin", http.StatusOK},
+		{"e", "dave", "admin", http.StatusOK},
+	}
+	for _, tt := range tests {
+		if code := book(tt.app, tt.user, tt.groups); code != tt.want {
+			t.Errorf("%s booking %s: expected %d, got %d", tt.user, tt.app, tt.want, code)
+		}
+	}
+}
+
+func TestTransferV1_BookingLimits(t *testing.T) {
+	cfg, err := config.Parse([]byte(`
+bookingLimits:
+  perUser: 1
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	mc := newMockClient()
+	for _, app := range []string{"a", "b", "c"} {
+		mc.bookings["argocd/"+app] = &k8s.Booking{AppName: app, Namespace: "argocd", BookedBy: "alice"}
+	}
+	mc.bookings["argocd/held"] = &k8s.Booking{AppName: "held", Namespace: "argocd", BookedBy: "bob"}
+	mux := http.NewServeMux()
+	New(mc, WithConfig(config.NewStore(cfg))).RegisterRoutes(mux)
+
+	transfer := func(app, to, groups string) int {
+		req := httptest.NewRequest("POST", "/api/v1/transfer", strings.NewReader(`{"to":"`+to+`"}`))
+		req.Header.Set(headerAppName, "argocd:"+app)
+		req.Header.Set(headerUsername, "alice")
+		req.Header.Set(headerUserGroups, groups)
+		w := httptest.NewRecorder()
+		mux.ServeHTTP(w, req)
+		return w.Code
+	}
+
+	if code := transfer("a", "bob", "dev"); code != http.StatusUnprocessableEntity {
+		t.Fatalf("expected a transfer to a user at the limit to be refused, got %d", code)
+	}
+	if code := transfer("b", "carol", "dev"); code != http.StatusOK {
+		t.Fatalf("expected a transfer within the limit to succeed, got %d", code)
+	}
+	if code := transfer("c", "bob", "admin"); code != http.StatusOK {
+		t.Fatalf("expected an admin transfer to ignore the limit, got %d", code)
+	}
+}
+
+func TestBookV1_RequiresRBACPermission(t *testing.T) {
+	enforcer := rbac.NewEnforcer("argocd")
+	p, err := rbac.Parse(map[string]string{
+		"policy.default": "role:readonly",
+		"policy.csv":     "p, role:dev, applications, sync, staging/*, allow\ng, devs, role:dev",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	enforcer.SetPolicy(p)
+
+	mc := newMockClient()
+	mux := http.NewServeMux()
+	New(mc, WithAuthorizer(enforcer, "sync")).RegisterRoutes(mux)
+
+	tests := []struct {
+		project, groups string
+		want            int
+	}{
+		{"staging", "", http.StatusForbidden},
+		{"prod", "devs", http.StatusForbidden},
+		{"staging", "devs", http.StatusOK},
+	}
+	for _, tt := range tests {
+		req := httptest.NewRequest("POST", "/api/v1/book", nil)
+		req.Header.Set(headerAppName, "argocd:api")
+		req.Header.Set(headerProject, tt.project)
+		req.Header.Set(headerUsername, "alice")
+		req.Header.Set(headerUserGroups, tt.groups)
+		w := httptest.NewRecorder()
+		mux.ServeHTTP(w, req)
+		if w.Code != tt.want {
+			t.Errorf("%s as %q: expected %d, got %d: %s", tt.project, tt.groups, tt.want, w.Code, w.Body.String())
+		}
+	}
+}
+
+func TestTransferV1_ChecksTarget(t *testing.T) {
+	enforcer := rbac.NewEnforcer("argocd")
+	p, err := rbac.Parse(map[string]string{
+		"policy.csv": "p, carol, applications, sync, */*, allow\np, role:dev, applications, sync, */*, allow\ng, devs, role:dev",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	enforcer.SetPolicy(p)
+	cfg, err := config.Parse([]byte(`
+policies:
+  - project: prod
+    allowedGroups: [sre]
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	mc := newMockClient()
+	mux := http.NewServeMux()
+	New(mc, WithAuthorizer(enforcer, "sync"), WithConfig(config.NewStore(cfg))).RegisterRoutes(mux)
+
+	tests := []struct {
+		project, to string
+		want        int
+	}{
+		{"staging", "bob", http.StatusForbidden}, // only the caller's group may sync
+		{"prod", "carol", http.StatusForbidden},  // the target's groups cannot be checked
+		{"staging", "carol", http.StatusOK},
+	}
+	for _, tt := range tests {
+		mc.bookings["argocd/api"] = &k8s.Booking{AppName: "api", Namespace: "argocd", BookedBy: "alice"}
+		req := httptest.NewRequest("POST", "/api/v1/transfer", strings.NewReader(`{"to":"`+tt.to+`"}`))
+		req.Header.Set(headerAppName, "argocd:api")
+		req.Header.Set(headerProject, tt.project)
+		req.Header.Set(headerUsername, "alice")
+		req.Header.Set(headerUserGroups, "devs")
+		w := httptest.NewRecorder()
+		mux.ServeHTTP(w, req)
+		if w.Code != tt.want {
+			t.Errorf("%s to %s: expected %d, got %d: %s", tt.project, tt.to, tt.want, w.Code, w.Body.String())
+		}
+		if b := mc.bookings["argocd/api"]; tt.want != http.StatusOK && b.BookedBy != "alice" {
+			t.Errorf("%s to %s: expected the booking kept, got %+v", tt.project, tt.to, b)
+		}
+	}
+}
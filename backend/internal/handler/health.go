@@ -0,0 +1,142 @@
+package handler
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// WriteTimeout is the http.Server write timeout. Book requests that wait for
+// an application push their own deadline back by the length of the wait.
+const WriteTimeout = 30 * time.Second
+
+const (
+	// readinessCacheTTL is how long /readyz reuses check results, so frequent
+	// probes do not each hit the API server.
+	readinessCacheTTL = 5 * time.Second
+	// readinessCheckTimeout bounds each readiness check.
+	readinessCheckTimeout = 3 * time.Second
+)
+
+// errDraining is returned to waiting book requests released by Drain.
+var errDraining = errors.New("server is shutting down, retry the request")
+
+// Drain marks the Handler as shutting down: /readyz starts failing so the pod
+// is taken out of the Service, and waiting book requests are answered with 503
+// instead of holding up the shutdown. It is safe to call more than once.
+func (h *Handler) Drain() {
+	h.drainOnce.Do(func() { close(h.draining) })
+}
+
+func (h *Handler) isDraining() bool {
+	select {
+	case <-h.draining:
+		return true
+	default:
+		return false
+	}
+}
+
+// Livez reports that the process is running. It stays healthy while draining,
+// so the kubelet does not restart a pod that is shutting down cleanly.
+func (h *Handler) Livez(w http.ResponseWriter, r *http.Request) {
+	writeText(w, http.StatusOK, "ok")
+}
+
+// ReadinessCheck is a named dependency check run by /readyz. Check receives the
+// configured default namespace and returns nil when the dependency is usable.
+type ReadinessCheck struct {
+	Name  string
+	Check func(ctx context.Context, namespace string) error
+}
+
+// readiness caches the results of the readiness checks.
+type readiness struct {
+	checks []ReadinessCheck
+
+	mu        sync.Mutex
+	checkedAt time.Time
+	results   []checkResult
+}
+
+// readinessResponse is the response of GET /readyz.
+type readinessResponse struct {
+	Ready  bool          `json:"ready"`
+	Checks []checkResult `json:"checks"`
+}
+
+type checkResult struct {
+	Name      string `json:"name"`
+	OK        bool   `json:"ok"`
+	Error     string `json:"error,omitempty"`
+	CheckedAt string `json:"checkedAt"`
+}
+
+// run returns the check results, re-running the checks when the cached ones are
+// older than readinessCacheTTL. Concurrent probes share a single run.
+func (rd *readiness) run(ctx context.Context, namespace string, now time.Time) []checkResult {
+	rd.mu.Lock()
+	defer rd.mu.Unlock()
+	if rd.results != nil && now.Sub(rd.checkedAt) < readinessCacheTTL {
+		return rd.results
+	}
+
+	results := make([]checkResult, len(rd.checks))
+	var wg sync.WaitGroup
+	for i, c := range rd.checks {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			// Results are shared, so one probe hanging up must not fail them for the rest.
+			cctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), readinessCheckTimeout)
+			defer cancel()
+			res := checkResult{Name: c.Name, OK: true, CheckedAt: now.UTC().Format(time.RFC3339)}
+			if err := c.Check(cctx, namespace); err != nil {
+				res.OK, res.Error = false, err.Error()
+			}
+			results[i] = res
+		}()
+	}
+	wg.Wait()
+	rd.results, rd.checkedAt = results, now
+	return results
+}
+
+// WithReadinessChecks makes /readyz fail while any of checks fails, such as
+// the service account losing access to Applications.
+func WithReadinessChecks(checks ...ReadinessCheck) Option {
+	return func(h *Handler) {
+		h.readiness.checks = append(h.readiness.checks, checks...)
+	}
+}
+
+// Readyz reports whether the Handler should receive traffic, with the result of
+// every readiness check. It fails with 503 once Drain has been called or while
+// any check fails.
+func (h *Handler) Readyz(w http.ResponseWriter, r *http.Request) {
+	now := time.Now()
+	resp := readinessResponse{Ready: true, Checks: []checkResult{}}
+	if h.isDraining() {
+		resp.Checks = append(resp.Checks, checkResult{
+			Name: "shutdown", Error: errDraining.Error(), CheckedAt: now.UTC().Format(time.RFC3339),
+		})
+	}
+	resp.Checks = append(resp.Checks, h.readiness.run(r.Context(), h.cfg.Config().DefaultNamespace, now)...)
+	for _, c := range resp.Checks {
+		resp.Ready = resp.Ready && c.OK
+	}
+
+	status := http.StatusOK
+	if !resp.Ready {
+		status = http.StatusServiceUnavailable
+	}
+	writeJSON(w, status, resp)
+}
+
+func writeText(w http.ResponseWriter, status int, text string) {
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	w.WriteHeader(status)
+	w.Write([]byte(text))
+}
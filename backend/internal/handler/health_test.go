@@ -0,0 +1,100 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/behavox/argocd-book-plugin/internal/k8s"
+)
+
+func TestReadyz_FailsWhileDraining(t *testing.T) {
+	h, _, mux := setupHandler()
+
+	get := func(path string) int {
+		w := httptest.NewRecorder()
+		mux.ServeHTTP(w, httptest.NewRequest("GET", path, nil))
+		return w.Code
+	}
+	if get("/readyz") != http.StatusOK || get("/livez") != http.StatusOK {
+		t.Fatal("expected ready and live before draining")
+	}
+
+	h.Drain()
+	h.Drain()
+	if code := get("/readyz"); code != http.StatusServiceUnavailable {
+		t.Fatalf("expected 503 from /readyz while draining, got %d", code)
+	}
+	if code := get("/livez"); code != http.StatusOK {
+		t.Fatalf("expected /livez to stay healthy while draining, got %d", code)
+	}
+}
+
+func TestDrain_ReleasesWaitingBooks(t *testing.T) {
+	h, mc, mux := setupWaitHandler()
+	mc.BookApp(context.Background(), "argocd", "my-app", "alice", k8s.BookOptions{})
+
+	done := make(chan *httptest.ResponseRecorder)
+	go func() { done <- postAs(mux, "/api/book?wait=1m", "bob") }()
+	waitForQueue(t, h, 1)
+
+	h.Drain()
+	w := <-done
+	if w.Code != http.StatusServiceUnavailable || w.Header().Get("Retry-After") == "" {
+		t.Fatalf("expected 503 with Retry-After, got %d: %s", w.Code, w.Body.String())
+	}
+}
+
+func TestReadyz_ReportsAndCachesChecks(t *testing.T) {
+	var calls atomic.Int32
+	var failing atomic.Bool
+	mc := newMockClient()
+	h := New(mc, WithReadinessChecks(
+		ReadinessCheck{Name: "list applications", Check: func(_ context.Context, ns string) error {
+			calls.Add(1)
+			if ns != "argocd" {
+				t.Errorf("expected the default namespace, got %q", ns)
+			}
+			return nil
+		}},
+		ReadinessCheck{Name: "patch applications", Check: func(context.Context, string) error {
+			if failing.Load() {
+				return errors.New("forbidden: cannot patch applications")
+			}
+			return nil
+		}},
+	))
+	mux := http.NewServeMux()
+	h.RegisterRoutes(mux)
+
+	get := func() (int, readinessResponse) {
+		w := httptest.NewRecorder()
+		mux.ServeHTTP(w, httptest.NewRequest("GET", "/readyz", nil))
+		var resp readinessResponse
+		json.NewDecoder(w.Body).Decode(&resp)
+		return w.Code, resp
+	}
+
+	code, resp := get()
+	if code != http.StatusOK || !resp.Ready || len(resp.Checks) != 2 {
+		t.Fatalf("expected ready with two checks, got %d %+v", code, resp)
+	}
+	failing.Store(true)
+	if code, _ := get(); code != http.StatusOK || calls.Load() != 1 {
+		t.Fatalf("expected the cached result to be reused, got %d after %d calls", code, calls.Load())
+	}
+
+	h.readiness.checkedAt = time.Now().Add(-readinessCacheTTL)
+	code, resp = get()
+	if code != http.StatusServiceUnavailable || resp.Ready {
+		t.Fatalf("expected 503 once the cache expired, got %d %+v", code, resp)
+	}
+	if c := resp.Checks[1]; c.OK || c.Error == "" || !resp.Checks[0].OK {
+		t.Fatalf("expected only the patch check to fail, got %+v", resp.Checks)
+	}
+}
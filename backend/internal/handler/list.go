@@ -0,0 +1,351 @@
+package handler
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"k8s.io/apimachinery/pkg/labels"
+
+	"github.com/behavox/argocd-book-plugin/internal/k8s"
+	"github.com/behavox/argocd-book-plugin/internal/logging"
+)
+
+const (
+	defaultListLimit = 100
+	maxListLimit     = 500
+)
+
+// bookingList is the response of GET /api/v1/list.
+type bookingList struct {
+	Items []k8s.Booking `json:"items"`
+	// Continue fetches the next page when passed back as the "continue" parameter.
+	Continue string `json:"continue,omitempty"`
+	// Count summarises every booking matching the filters, not just this page.
+	Count listCount `json:"count"`
+}
+
+type listCount struct {
+	Total     int            `json:"total"`
+	ByProject map[string]int `json:"byProject"`
+	ByUser    map[string]int `json:"byUser"`
+}
+
+// applicationList is the response of GET /api/v1/applications.
+type applicationList struct {
+	Items    []k8s.Application `json:"items"`
+	Continue string            `json:"continue,omitempty"`
+	Count    applicationCount  `json:"count"`
+}
+
+type applicationCount struct {
+	Total     int            `json:"total"`
+	Booked    int            `json:"booked"`
+	Free      int            `json:"free"`
+	ByProject map[string]int `json:"byProject"`
+}
+
+// listQuery holds the parsed query parameters of GET /api/v1/list and /api/v1/applications.
+type listQuery struct {
+	namespace     string
+	user          string
+	project       string
+	selector      labels.Selector
+	minAge        time.Duration
+	maxAge        time.Duration
+	expiresWithin time.Duration
+	sortBy        string
+	descending    bool
+	limit         int
+	offset        int
+
+	// Only parsed for /api/v1/applications.
+	state  string // "booked" or "free"
+	sync   string
+	health string
+}
+
+// continueToken is the decoded form of the opaque "continue" parameter. Sorting
+// needs every booking, so pages are offsets into the filtered, sorted result
+// rather than Kubernetes list continuations; the fingerprint rejects tokens
+// reused with different filters.
+type continueToken struct {
+	Offset      int    `json:"o"`
+	Fingerprint string `json:"f"`
+}
+
+var listSortKeys = map[string]bool{"bookedAt": true, "appName": true}
+
+// parseListQuery reads namespace (defaulted by Handler.query), user, project,
+// selector (a label selector), minAge and maxAge (time since booking),
+// expiresWithin, sort (bookedAt or appName, "-" prefix for descending), limit
+// and continue.
+func parseListQuery(q url.Values) (listQuery, error) {
+	return parseQuery(q, "bookedAt", nil)
+}
+
+// parseApplicationQuery reads the parameters of parseListQuery plus state
+// (booked or free), sync and health. Results sort by appName by default.
+func parseApplicationQuery(q url.Values) (listQuery, error) {
+	return parseQuery(q, "appName", func(lq *listQuery) error {
+		lq.state, lq.sync, lq.health = q.Get("state"), q.Get("sync"), q.Get("health")
+		if lq.state != "" && lq.state != "booked" && lq.state != "free" {
+			return fmt.Errorf("invalid \"state\" %q (expected booked or free)", lq.state)
+		}
+		return nil
+	})
+}
+
+// parseQuery parses the shared list parameters; extra reads endpoint-specific
+// ones before the continue token is checked against the final fingerprint.
+func parseQuery(q url.Values, defaultSort string, extra func(*listQuery) error) (listQuery, error) {
+	lq := listQuery{
+		namespace: q.Get("namespace"),
+		user:      q.Get("user"),
+		project:   q.Get("project"),
+		selector:  labels.Everything(),
+		sortBy:    defaultSort,
+		limit:     defaultListLimit,
+	}
+	if v := q.Get("selector"); v != "" {
+		sel, err := labels.Parse(v)
+		if err != nil {
+			return lq, fmt.Errorf("invalid \"selector\": %v", err)
+		}
+		lq.selector = sel
+	}
+	for name, dst := range map[string]*time.Duration{
+		"minAge": &lq.minAge, "maxAge": &lq.maxAge, "expiresWithin": &lq.expiresWithin,
+	} {
+		if v := q.Get(name); v != "" {
+			d, err := time.ParseDuration(v)
+			if err != nil || d <= 0 {
+				return lq, fmt.Errorf("invalid %q (expected a positive duration such as 2h)", name)
+			}
+			*dst = d
+		}
+	}
+	if v := q.Get("sort"); v != "" {
+		lq.sortBy, lq.descending = strings.TrimPrefix(v, "-"), strings.HasPrefix(v, "-")
+		if !listSortKeys[lq.sortBy] {
+			return lq, fmt.Errorf("invalid \"sort\" %q (expected bookedAt or appName, optionally prefixed with -)", v)
+		}
+	}
+	if v := q.Get("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 || n > maxListLimit {
+			return lq, fmt.Errorf("invalid \"limit\" (expected 1 to %d)", maxListLimit)
+		}
+		lq.limit = n
+	}
+	if extra != nil {
+		if err := extra(&lq); err != nil {
+			return lq, err
+		}
+	}
+	if v := q.Get("continue"); v != "" {
+		tok, err := decodeContinue(v)
+		if err != nil || tok.Offset < 0 || tok.Fingerprint != lq.fingerprint() {
+			return lq, errors.New("invalid or expired \"continue\" token; restart the listing")
+		}
+		lq.offset = tok.Offset
+	}
+	return lq, nil
+}
+
+// fingerprint identifies the filters and sort order a continue token belongs to.
+func (lq listQuery) fingerprint() string {
+	sum := sha256.Sum256([]byte(strings.Join([]string{
+		lq.namespace, lq.user, lq.project, lq.selector.String(),
+		lq.minAge.String(), lq.maxAge.String(), lq.expiresWithin.String(),
+		lq.sortBy, strconv.FormatBool(lq.descending), lq.state, lq.sync, lq.health,
+	}, "\x00")))
+	return hex.EncodeToString(sum[:8])
+}
+
+func encodeContinue(tok continueToken) string {
+	b, _ := json.Marshal(tok)
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+func decodeContinue(s string) (continueToken, error) {
+	var tok continueToken
+	b, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return tok, err
+	}
+	err = json.Unmarshal(b, &tok)
+	return tok, err
+}
+
+// matches reports whether b passes every filter of lq at time now. Bookings with an
+// unparseable booked-at never match an age filter, nor expiresWithin without an expiry.
+func (lq listQuery) matches(b k8s.Booking, now time.Time) bool {
+	if lq.user != "" && b.BookedBy != lq.user {
+		return false
+	}
+	if lq.project != "" && b.Project != lq.project {
+		return false
+	}
+	if !lq.selector.Matches(labels.Set(b.Labels)) {
+		return false
+	}
+	if lq.minAge > 0 || lq.maxAge > 0 {
+		bookedAt, err := time.Parse(time.RFC3339, b.BookedAt)
+		if err != nil {
+			return false
+		}
+		age := now.Sub(bookedAt)
+		if (lq.minAge > 0 && age < lq.minAge) || (lq.maxAge > 0 && age > lq.maxAge) {
+			return false
+		}
+	}
+	if lq.expiresWithin > 0 {
+		expiresAt, err := time.Parse(time.RFC3339, b.ExpiresAt)
+		if err != nil || expiresAt.Sub(now) > lq.expiresWithin {
+			return false
+		}
+	}
+	return true
+}
+
+// less orders bookings by lq's sort key, breaking ties by namespace and name so
+// pages are stable between requests.
+func (lq listQuery) less(a, b k8s.Booking) bool {
+	if lq.descending {
+		a, b = b, a
+	}
+	var ka, kb string
+	switch lq.sortBy {
+	case "bookedAt":
+		ka, kb = a.BookedAt, b.BookedAt // RFC 3339 UTC timestamps sort lexically
+	case "appName":
+		ka, kb = a.AppName, b.AppName
+	}
+	if ka != kb {
+		return ka < kb
+	}
+	return a.Namespace+"/"+a.AppName < b.Namespace+"/"+b.AppName
+}
+
+// selectPage filters items with lq, sorts them and cuts out the page lq points
+// at, returning every match, the page and the next continue token. view exposes
+// the booking fields of an item for filtering and sorting.
+func selectPage[T any](lq listQuery, items []T, view func(T) k8s.Booking, now time.Time) (matched, page []T, next string) {
+	for _, item := range items {
+		if lq.matches(view(item), now) {
+			matched = append(matched, item)
+		}
+	}
+	sort.SliceStable(matched, func(i, j int) bool { return lq.less(view(matched[i]), view(matched[j])) })
+
+	page = []T{}
+	if lq.offset < len(matched) {
+		end := min(lq.offset+lq.limit, len(matched))
+		page = append(page, matched[lq.offset:end]...)
+		if end < len(matched) {
+			next = encodeContinue(continueToken{Offset: end, Fingerprint: lq.fingerprint()})
+		}
+	}
+	return matched, page, next
+}
+
+// paginate filters, sorts and pages bookings according to lq.
+func (lq listQuery) paginate(bookings []k8s.Booking, now time.Time) bookingList {
+	matched, page, next := selectPage(lq, bookings, func(b k8s.Booking) k8s.Booking { return b }, now)
+	resp := bookingList{
+		Items:    page,
+		Continue: next,
+		Count:    listCount{Total: len(matched), ByProject: map[string]int{}, ByUser: map[string]int{}},
+	}
+	for _, b := range matched {
+		resp.Count.ByProject[b.Project]++
+		resp.Count.ByUser[b.BookedBy]++
+	}
+	return resp
+}
+
+// applicationView is the booking of app, or a holder-less booking for a free app,
+// so that the booking filters apply to applications too.
+func applicationView(app k8s.Application) k8s.Booking {
+	if app.Booking != nil {
+		return *app.Booking
+	}
+	return k8s.Booking{AppName: app.Name, Namespace: app.Namespace, Project: app.Project, Labels: app.Labels}
+}
+
+// paginateApplications filters, sorts and pages apps according to lq.
+func (lq listQuery) paginateApplications(apps []k8s.Application, now time.Time) applicationList {
+	var kept []k8s.Application
+	for _, app := range apps {
+		if (lq.state == "booked" && app.Booking == nil) || (lq.state == "free" && app.Booking != nil) {
+			continue
+		}
+		if (lq.sync != "" && app.SyncStatus != lq.sync) || (lq.health != "" && app.HealthStatus != lq.health) {
+			continue
+		}
+		kept = append(kept, app)
+	}
+
+	matched, page, next := selectPage(lq, kept, applicationView, now)
+	resp := applicationList{
+		Items:    page,
+		Continue: next,
+		Count:    applicationCount{Total: len(matched), ByProject: map[string]int{}},
+	}
+	for _, app := range matched {
+		if app.Booking != nil {
+			resp.Count.Booked++
+		} else {
+			resp.Count.Free++
+		}
+		resp.Count.ByProject[app.Project]++
+	}
+	return resp
+}
+
+// ListV1 returns the bookings in a namespace as a page of a filtered, sorted list.
+// See parseListQuery for the accepted query parameters.
+func (h *Handler) ListV1(w http.ResponseWriter, r *http.Request) {
+	lq, err := parseListQuery(h.query(r))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	bookings, err := h.client.ListBookings(r.Context(), lq.namespace)
+	if err != nil {
+		requestLogger(r).Error("failed to list bookings", "list_namespace", lq.namespace, logging.KeyError, err)
+		writeError(w, http.StatusInternalServerError, "failed to list bookings")
+		return
+	}
+	writeJSON(w, http.StatusOK, lq.paginate(bookings, time.Now()))
+}
+
+// Applications returns every Application in a namespace with its booking state,
+// destination, sync and health status, so free environments can be found.
+// See parseApplicationQuery for the accepted query parameters.
+func (h *Handler) Applications(w http.ResponseWriter, r *http.Request) {
+	lq, err := parseApplicationQuery(h.query(r))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	apps, err := h.client.ListApplications(r.Context(), lq.namespace)
+	if err != nil {
+		requestLogger(r).Error("failed to list applications", "list_namespace", lq.namespace, logging.KeyError, err)
+		writeError(w, http.StatusInternalServerError, "failed to list applications")
+		return
+	}
+	writeJSON(w, http.StatusOK, lq.paginateApplications(apps, time.Now()))
+}
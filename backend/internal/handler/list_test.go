@@ -0,0 +1,183 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/behavox/argocd-book-plugin/internal/k8s"
+)
+
+var listNow = time.Date(2026, 1, 15, 12, 0, 0, 0, time.UTC)
+
+func listFixture() []k8s.Booking {
+	return []k8s.Booking{
+		{AppName: "api", Namespace: "argocd", Project: "payments", BookedBy: "alice", BookedAt: "2026-01-15T11:00:00Z",
+			ExpiresAt: "2026-01-15T12:30:00Z", Labels: map[string]string{"team": "payments", "env": "staging"}},
+		{AppName: "web", Namespace: "argocd", Project: "frontend", BookedBy: "bob", BookedAt: "2026-01-13T09:00:00Z",
+			Labels: map[string]string{"team": "frontend", "env": "staging"}},
+		{AppName: "db", Namespace: "argocd", Project: "payments", BookedBy: "alice", BookedAt: "2026-01-14T08:00:00Z",
+			ExpiresAt: "2026-01-16T08:00:00Z", Labels: map[string]string{"team": "payments", "env": "qa"}},
+	}
+}
+
+func listNames(items []k8s.Booking) []string {
+	var names []string
+	for _, b := range items {
+		names = append(names, b.AppName)
+	}
+	return names
+}
+
+func TestListQuery_FiltersAndSort(t *testing.T) {
+	tests := []struct {
+		query string
+		want  []string
+	}{
+		{"", []string{"web", "db", "api"}},
+		{"sort=-bookedAt", []string{"api", "db", "web"}},
+		{"sort=appName", []string{"api", "db", "web"}},
+		{"user=alice", []string{"db", "api"}},
+		{"project=frontend", []string{"web"}},
+		{"selector=team%3Dpayments,env!%3Dqa", []string{"api"}},
+		{"minAge=24h", []string{"web", "db"}},
+		{"maxAge=2h", []string{"api"}},
+		{"expiresWithin=1h", []string{"api"}},
+	}
+	for _, tt := range tests {
+		q, _ := url.ParseQuery(tt.query)
+		lq, err := parseListQuery(q)
+		if err != nil {
+			t.Fatalf("%q: unexpected error: %v", tt.query, err)
+		}
+		got := lq.paginate(listFixture(), listNow)
+		if names := listNames(got.Items); !equalStrings(names, tt.want) {
+			t.Errorf("%q: expected %v, got %v", tt.query, tt.want, names)
+		}
+		if got.Count.Total != len(tt.want) {
+			t.Errorf("%q: expected total %d, got %d", tt.query, len(tt.want), got.Count.Total)
+		}
+	}
+}
+
+func equalStrings(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func TestListQuery_InvalidParameters(t *testing.T) {
+	for _, query := range []string{
+		"selector=team%3D%3D%3D",
+		"minAge=yesterday",
+		"expiresWithin=-1h",
+		"sort=owner",
+		"limit=0",
+		"limit=501",
+		"continue=garbage",
+	} {
+		q, _ := url.ParseQuery(query)
+		if _, err := parseListQuery(q); err == nil {
+			t.Errorf("%q: expected an error", query)
+		}
+	}
+}
+
+func TestListV1_Pagination(t *testing.T) {
+	_, mc, mux := setupHandler()
+	for _, b := range listFixture() {
+		b := b
+		mc.bookings[mc.key(b.Namespace, b.AppName)] = &b
+	}
+
+	get := func(query string) (int, bookingList) {
+		req := httptest.NewRequest("GET", "/api/v1/list?"+query, nil)
+		w := httptest.NewRecorder()
+		mux.ServeHTTP(w, req)
+		var resp bookingList
+		json.NewDecoder(w.Body).Decode(&resp)
+		return w.Code, resp
+	}
+
+	var names []string
+	query := "sort=appName&limit=2"
+	for pages := 0; ; pages++ {
+		code, page := get(query)
+		if code != http.StatusOK {
+			t.Fatalf("expected 200, got %d", code)
+		}
+		if page.Count.Total != 3 || page.Count.ByUser["alice"] != 2 || page.Count.ByProject["payments"] != 2 {
+			t.Fatalf("unexpected count: %+v", page.Count)
+		}
+		names = append(names, listNames(page.Items)...)
+		if page.Continue == "" {
+			break
+		}
+		if pages > 2 {
+			t.Fatal("pagination did not terminate")
+		}
+		query = "sort=appName&limit=2&continue=" + page.Continue
+	}
+	if !equalStrings(names, []string{"api", "db", "web"}) {
+		t.Fatalf("unexpected pages: %v", names)
+	}
+
+	_, first := get("sort=appName&limit=1")
+	if code, _ := get("sort=-appName&limit=1&continue=" + first.Continue); code != http.StatusBadRequest {
+		t.Fatalf("expected 400 for a token reused with another sort order, got %d", code)
+	}
+}
+
+func TestApplications_IncludesFreeApps(t *testing.T) {
+	_, mc, mux := setupHandler()
+	mc.apps = []k8s.Application{
+		{Name: "qa-1", Namespace: "argocd", Project: "payments", SyncStatus: "Synced", HealthStatus: "Healthy",
+			Destination: k8s.Destination{Server: "https://kubernetes.default.svc", Namespace: "qa-1"}},
+		{Name: "qa-2", Namespace: "argocd", Project: "payments", SyncStatus: "OutOfSync", HealthStatus: "Degraded"},
+	}
+	mc.BookApp(context.Background(), "argocd", "qa-2", "alice", k8s.BookOptions{})
+
+	tests := []struct {
+		query        string
+		want         []string
+		booked, free int
+	}{
+		{"", []string{"qa-1", "qa-2"}, 1, 1},
+		{"state=free", []string{"qa-1"}, 0, 1},
+		{"state=booked", []string{"qa-2"}, 1, 0},
+		{"user=alice", []string{"qa-2"}, 1, 0},
+		{"health=Healthy", []string{"qa-1"}, 0, 1},
+		{"sort=-appName&limit=1", []string{"qa-2"}, 1, 1},
+	}
+	for _, tt := range tests {
+		req := httptest.NewRequest("GET", "/api/v1/applications?"+tt.query, nil)
+		w := httptest.NewRecorder()
+		mux.ServeHTTP(w, req)
+		if w.Code != http.StatusOK {
+			t.Fatalf("%q: expected 200, got %d: %s", tt.query, w.Code, w.Body.String())
+		}
+
+		var resp applicationList
+		json.NewDecoder(w.Body).Decode(&resp)
+		var names []string
+		for _, a := range resp.Items {
+			names = append(names, a.Name)
+		}
+		if !equalStrings(names, tt.want) {
+			t.Errorf("%q: expected %v, got %v", tt.query, tt.want, names)
+		}
+		if resp.Count.Booked != tt.booked || resp.Count.Free != tt.free {
+			t.Errorf("%q: unexpected count %+v", tt.query, resp.Count)
+		}
+	}
+}
@@ -0,0 +1,22 @@
+package handler
+
+import (
+	_ "embed"
+	"net/http"
+
+	"github.com/behavox/argocd-book-plugin/internal/logging"
+)
+
+// openAPISpec is the OpenAPI 3 description of every route in routes().
+//
+//go:embed openapi.json
+var openAPISpec []byte
+
+// OpenAPI serves the OpenAPI 3 document describing the booking API.
+func (h *Handler) OpenAPI(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	if _, err := w.Write(openAPISpec); err != nil {
+		requestLogger(r).Warn("failed to write response", logging.KeyError, err)
+	}
+}
@@ -0,0 +1,237 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"mime"
+	"net/http/httptest"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/behavox/argocd-book-plugin/internal/k8s"
+)
+
+type specSchema struct {
+	Ref      string                `json:"$ref"`
+	Type     string                `json:"type"`
+	Required []string              `json:"required"`
+	Items    *specSchema           `json:"items"`
+	Props    map[string]specSchema `json:"properties"`
+}
+
+type specResponse struct {
+	Ref     string `json:"$ref"`
+	Content map[string]struct {
+		Schema specSchema `json:"schema"`
+	} `json:"content"`
+}
+
+type specOperation struct {
+	Responses map[string]specResponse `json:"responses"`
+}
+
+type spec struct {
+	OpenAPI    string                              `json:"openapi"`
+	Paths      map[string]map[string]specOperation `json:"paths"`
+	Components struct {
+		Schemas   map[string]specSchema   `json:"schemas"`
+		Responses map[string]specResponse `json:"responses"`
+	} `json:"components"`
+}
+
+func loadSpec(t *testing.T) spec {
+	t.Helper()
+	var s spec
+	if err := json.Unmarshal(openAPISpec, &s); err != nil {
+		t.Fatalf("openapi.json is not valid JSON: %v", err)
+	}
+	if !strings.HasPrefix(s.OpenAPI, "3.") {
+		t.Fatalf("expected an OpenAPI 3 document, got %q", s.OpenAPI)
+	}
+	return s
+}
+
+func (s spec) response(r specResponse) specResponse {
+	if r.Ref != "" {
+		return s.Components.Responses[strings.TrimPrefix(r.Ref, "#/components/responses/")]
+	}
+	return r
+}
+
+func (s spec) schema(sc specSchema) specSchema {
+	if sc.Ref != "" {
+		return s.Components.Schemas[strings.TrimPrefix(sc.Ref, "#/components/schemas/")]
+	}
+	return sc
+}
+
+// checkBody verifies that a JSON body carries the required properties of its schema.
+func (s spec) checkBody(t *testing.T, name string, sc specSchema, body []byte) {
+	t.Helper()
+	sc = s.schema(sc)
+	switch sc.Type {
+	case "array":
+		var items []json.RawMessage
+		if err := json.Unmarshal(body, &items); err != nil {
+			t.Errorf("%s: expected JSON array: %v", name, err)
+			return
+		}
+		for _, item := range items {
+			s.checkBody(t, name, *sc.Items, item)
+		}
+	case "object":
+		var obj map[string]json.RawMessage
+		if err := json.Unmarshal(body, &obj); err != nil {
+			t.Errorf("%s: expected JSON object: %v", name, err)
+			return
+		}
+		for _, req := range sc.Required {
+			if _, ok := obj[req]; !ok {
+				t.Errorf("%s: response lacks required property %q: %s", name, req, body)
+			}
+		}
+		for key := range obj {
+			if len(sc.Props) > 0 {
+				if _, ok := sc.Props[key]; !ok {
+					t.Errorf("%s: response property %q is not in the spec", name, key)
+				}
+			}
+		}
+	}
+}
+
+func TestOpenAPI_DescribesEveryRoute(t *testing.T) {
+	s := loadSpec(t)
+	h := New(newMockClient())
+
+	var registered, documented []string
+	for _, rt := range h.routes() {
+		registered = append(registered, rt.pattern)
+	}
+	for path, ops := range s.Paths {
+		for method := range ops {
+			documented = append(documented, strings.ToUpper(method)+" "+path)
+		}
+	}
+	sort.Strings(registered)
+	sort.Strings(documented)
+
+	if strings.Join(registered, "\n") != strings.Join(documented, "\n") {
+		t.Fatalf("routes and spec differ\nregistered:\n  %s\ndocumented:\n  %s",
+			strings.Join(registered, "\n  "), strings.Join(documented, "\n  "))
+	}
+}
+
+func TestOpenAPI_HandlersMatchSpec(t *testing.T) {
+	s := loadSpec(t)
+	_, mc, mux := setupHandler()
+	mc.BookApp(context.Background(), "argocd", "booked", "alice", k8s.BookOptions{})
+
+	tests := []struct {
+		method, path, target string
+		headers              map[string]string
+		body                 string
+	}{
+		{"GET", "/api/v1/status", "/api/v1/status", map[string]string{headerAppName: "argocd:booked"}, ""},
+		{"GET", "/api/v1/status", "/api/v1/status", nil, ""},
+		{"POST", "/api/v1/book", "/api/v1/book", map[string]string{headerAppName: "argocd:free", headerUsername: "bob"}, `{"reason":"testing","duration":"2h"}`},
+		{"POST", "/api/v1/book", "/api/v1/book", map[string]string{headerAppName: "argocd:other", headerUsername: "bob"}, `{"dryRun":true}`},
+		{"POST", "/api/v1/book", "/api/v1/book", map[string]string{headerAppName: "argocd:booked", headerUsername: "bob"}, ""},
+		{"POST", "/api/v1/book", "/api/v1/book", map[string]string{headerAppName: "argocd:other", headerUsername: "bob"}, `{"reasn":"typo"}`},
+		{"POST", "/api/v1/book", "/api/v1/book", map[string]string{headerAppName: "argocd:other", headerUsername: "bob", "Content-Type": "text/plain"}, "x"},
+		{"POST", "/api/v1/book", "/api/v1/book", map[string]string{headerAppName: "argocd:other", headerUsername: "bob"}, `{"reason":"` + strings.Repeat("x", maxBodyBytes) + `"}`},
+		{"POST", "/api/v1/unbook", "/api/v1/unbook", map[string]string{headerAppName: "argocd:booked", headerUsername: "bob"}, "{}"},
+		{"POST", "/api/v1/transfer", "/api/v1/transfer", map[string]string{headerAppName: "argocd:booked", headerUsername: "alice"}, `{"to":"carol"}`},
+		{"POST", "/api/v1/transfer", "/api/v1/transfer", map[string]string{headerAppName: "argocd:nobody", headerUsername: "alice"}, `{"to":"carol"}`},
+		{"POST", "/api/v1/transfer", "/api/v1/transfer", map[string]string{headerAppName: "argocd:booked", headerUsername: "alice"}, ""},
+		{"POST", "/api/v1/unbook", "/api/v1/unbook", map[string]string{headerAppName: "argocd:booked", headerUsername: "alice"}, ""},
+		{"GET", "/api/v1/list", "/api/v1/list", nil, ""},
+		{"GET", "/api/v1/list", "/api/v1/list?user=alice&sort=-appName&limit=1", nil, ""},
+		{"GET", "/api/v1/list", "/api/v1/list?sort=owner", nil, ""},
+		{"GET", "/api/v1/applications", "/api/v1/applications?state=booked", nil, ""},
+		{"GET", "/api/v1/applications", "/api/v1/applications?state=taken", nil, ""},
+		{"GET", "/api/v1/board", "/api/v1/board?groupBy=label:env", nil, ""},
+		{"GET", "/api/v1/board", "/api/v1/board?groupBy=owner", nil, ""},
+		{"GET", "/api/v1/calendar.ics", "/api/v1/calendar.ics?user=bob", nil, ""},
+		{"GET", "/api/v1/config", "/api/v1/config", map[string]string{headerUserGroups: "admin"}, ""},
+		{"GET", "/api/v1/config", "/api/v1/config", nil, ""},
+		{"GET", "/api/v1/openapi.json", "/api/v1/openapi.json", nil, ""},
+		{"GET", "/api/status", "/api/status", map[string]string{headerAppName: "argocd:free"}, ""},
+		{"POST", "/api/book", "/api/book?wait=bogus", map[string]string{headerAppName: "argocd:booked", headerUsername: "bob"}, ""},
+		{"POST", "/api/book", "/api/book", map[string]string{headerAppName: "argocd:free"}, ""},
+		{"POST", "/api/transfer", "/api/transfer", map[string]string{headerAppName: "argocd:booked", headerUsername: "alice"}, ""},
+		{"POST", "/api/unbook", "/api/unbook", map[string]string{headerAppName: "argocd:free", headerUsername: "carol"}, ""},
+		{"GET", "/api/list", "/api/list", nil, ""},
+		{"GET", "/healthz", "/healthz", nil, ""},
+		{"GET", "/livez", "/livez", nil, ""},
+		{"GET", "/readyz", "/readyz", nil, ""},
+		{"GET", "/metrics", "/metrics", nil, ""},
+	}
+	for _, tt := range tests {
+		name := tt.method + " " + tt.target
+		req := httptest.NewRequest(tt.method, tt.target, strings.NewReader(tt.body))
+		for k, v := range tt.headers {
+			req.Header.Set(k, v)
+		}
+		w := httptest.NewRecorder()
+		mux.ServeHTTP(w, req)
+
+		op, ok := s.Paths[tt.path][strings.ToLower(tt.method)]
+		if !ok {
+			t.Fatalf("%s: operation not in spec", name)
+		}
+		resp, ok := op.Responses[strconv.Itoa(w.Code)]
+		if !ok {
+			t.Errorf("%s: status %d is not documented", name, w.Code)
+			continue
+		}
+		resp = s.response(resp)
+
+		mediaType, _, _ := mime.ParseMediaType(w.Header().Get("Content-Type"))
+		if len(resp.Content) == 0 {
+			continue
+		}
+		content, ok := resp.Content[mediaType]
+		if !ok {
+			t.Errorf("%s: content type %q is not documented for %d", name, mediaType, w.Code)
+			continue
+		}
+		if mediaType == "application/json" {
+			s.checkBody(t, name, content.Schema, w.Body.Bytes())
+		}
+	}
+}
+
+func TestOpenAPI_RequestBodiesMatchSpec(t *testing.T) {
+	s := loadSpec(t)
+	bodies := map[string]interface{}{
+		"BookRequest":     bookRequest{},
+		"TransferRequest": transferRequest{},
+		"UnbookRequest":   unbookRequest{},
+	}
+	for name, body := range bodies {
+		sc, ok := s.Components.Schemas[name]
+		if !ok {
+			t.Errorf("schema %s is not in the spec", name)
+			continue
+		}
+		var fields []string
+		typ := reflect.TypeOf(body)
+		for i := 0; i < typ.NumField(); i++ {
+			tag, _, _ := strings.Cut(typ.Field(i).Tag.Get("json"), ",")
+			fields = append(fields, tag)
+		}
+		var props []string
+		for p := range sc.Props {
+			props = append(props, p)
+		}
+		sort.Strings(fields)
+		sort.Strings(props)
+		if strings.Join(fields, ",") != strings.Join(props, ",") {
+			t.Errorf("%s: struct fields %v differ from spec properties %v", name, fields, props)
+		}
+	}
+}
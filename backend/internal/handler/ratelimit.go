@@ -0,0 +1,30 @@
+package handler
+
+import (
+	"fmt"
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/behavox/argocd-book-plugin/internal/metrics"
+)
+
+// rateLimited rejects requests over the configured per-user or global rate
+// limit with 429 and a Retry-After header, before they reach the API server.
+func (h *Handler) rateLimited(action string, next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		limits := h.cfg.Config().RateLimits
+		ok, scope, retryAfter := h.limiter.Allow(r.Header.Get(headerUsername), limits.PerUser, limits.Global, time.Now())
+		if ok {
+			next(w, r)
+			return
+		}
+
+		metrics.ThrottledRequests.WithLabelValues(string(scope), action).Inc()
+		seconds := max(1, int(math.Ceil(retryAfter.Seconds())))
+		requestLogger(r).Warn("request throttled", "scope", string(scope), "retry_after_s", seconds)
+		w.Header().Set("Retry-After", strconv.Itoa(seconds))
+		writeError(w, http.StatusTooManyRequests, fmt.Sprintf("%s rate limit exceeded, retry in %d seconds", scope, seconds))
+	}
+}
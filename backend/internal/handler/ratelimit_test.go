@@ -0,0 +1,60 @@
+package handler
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+
+	"github.com/behavox/argocd-book-plugin/internal/config"
+	"github.com/behavox/argocd-book-plugin/internal/metrics"
+)
+
+func TestRateLimit_ThrottlesMutatingRequests(t *testing.T) {
+	cfg, err := config.Parse([]byte(`
+rateLimits:
+  perUser: {perMinute: 1, burst: 2}
+  global: {perMinute: 60, burst: 3}
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	mux := http.NewServeMux()
+	New(newMockClient(), WithConfig(config.NewStore(cfg))).RegisterRoutes(mux)
+	throttled := func(scope string) float64 {
+		return testutil.ToFloat64(metrics.ThrottledRequests.WithLabelValues(scope, "book"))
+	}
+	userBefore, globalBefore := throttled("user"), throttled("global")
+
+	postAs(mux, "/api/v1/book", "alice")
+	postAs(mux, "/api/v1/unbook", "alice")
+	w := postAs(mux, "/api/v1/book", "alice")
+	if w.Code != http.StatusTooManyRequests || w.Header().Get("Retry-After") != "60" {
+		t.Fatalf("expected 429 with Retry-After 60 for alice, got %d %q", w.Code, w.Header().Get("Retry-After"))
+	}
+
+	// Reads are not limited.
+	for i := 0; i < 5; i++ {
+		w := httptest.NewRecorder()
+		mux.ServeHTTP(w, httptest.NewRequest("GET", "/api/v1/list", nil))
+		if w.Code != http.StatusOK {
+			t.Fatalf("expected reads to be allowed, got %d", w.Code)
+		}
+	}
+
+	if w := postAs(mux, "/api/v1/book", "bob"); w.Code == http.StatusTooManyRequests {
+		t.Fatal("expected bob to be under the limits")
+	}
+	w = postAs(mux, "/api/book", "carol")
+	if w.Code != http.StatusTooManyRequests || w.Header().Get("Retry-After") != "1" {
+		t.Fatalf("expected the global limit to apply, got %d %q", w.Code, w.Header().Get("Retry-After"))
+	}
+
+	if d := throttled("user") - userBefore; d != 1 {
+		t.Errorf("expected one request throttled by the per-user limit, got %v", d)
+	}
+	if d := throttled("global") - globalBefore; d != 1 {
+		t.Errorf("expected one request throttled by the global limit, got %v", d)
+	}
+}
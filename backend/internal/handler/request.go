@@ -0,0 +1,96 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"time"
+)
+
+// maxBodyBytes bounds JSON request bodies; every body in the API is a handful of fields.
+const maxBodyBytes = 64 << 10
+
+// bookRequest is the body of POST /api/v1/book.
+type bookRequest struct {
+	// Reason is a free-text note shown to other users.
+	Reason string `json:"reason,omitempty"`
+	// Duration makes the booking lapse after that long.
+	Duration duration `json:"duration,omitempty"`
+	// Wait holds the request until the application is free, as ?wait= does on /api/book.
+	Wait duration `json:"wait,omitempty"`
+	// DryRun runs every check without booking; see k8s.BookOptions.DryRun.
+	DryRun bool `json:"dryRun,omitempty"`
+}
+
+// unbookRequest is the body of POST /api/v1/unbook.
+type unbookRequest struct {
+	DryRun bool `json:"dryRun,omitempty"`
+}
+
+// transferRequest is the body of POST /api/v1/transfer.
+type transferRequest struct {
+	To     string `json:"to"`
+	DryRun bool   `json:"dryRun,omitempty"`
+}
+
+// duration is a time.Duration encoded in JSON as a Go duration string such as "2h30m".
+type duration time.Duration
+
+func (d *duration) UnmarshalJSON(b []byte) error {
+	var s string
+	if err := json.Unmarshal(b, &s); err != nil {
+		return errors.New("durations must be strings such as \"2h\"")
+	}
+	v, err := time.ParseDuration(s)
+	if err != nil || v < 0 {
+		return fmt.Errorf("invalid duration %q (expected a positive duration such as \"2h\")", s)
+	}
+	*d = duration(v)
+	return nil
+}
+
+// decodeBody strictly decodes a JSON request body into v: unknown fields, trailing
+// data, non-JSON content types and oversized bodies are rejected. An empty body
+// leaves v at its zero value. On failure the error response has been written and
+// decodeBody returns false.
+func decodeBody(w http.ResponseWriter, r *http.Request, v interface{}) bool {
+	if ct := r.Header.Get("Content-Type"); ct != "" {
+		if mediaType, _, err := mime.ParseMediaType(ct); err != nil || mediaType != "application/json" {
+			writeError(w, http.StatusUnsupportedMediaType, "request body must be application/json")
+			return false
+		}
+	}
+
+	dec := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxBodyBytes))
+	dec.DisallowUnknownFields()
+	err := dec.Decode(v)
+	if errors.Is(err, io.EOF) {
+		return true
+	}
+	if err == nil && dec.Decode(&struct{}{}) != io.EOF {
+		err = errors.New("unexpected data after the JSON object")
+	}
+	if err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			writeError(w, http.StatusRequestEntityTooLarge, fmt.Sprintf("request body larger than %d bytes", maxBodyBytes))
+			return false
+		}
+		writeError(w, http.StatusBadRequest, "invalid request body: "+err.Error())
+		return false
+	}
+	return true
+}
+
+// deprecated marks an unversioned alias of an /api/v1 route. The Link target is
+// relative, so it resolves correctly behind the ArgoCD proxy prefix as well.
+func deprecated(successor string, next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Deprecation", "true")
+		w.Header().Set("Link", fmt.Sprintf(`<v1/%s>; rel="successor-version"`, successor))
+		next(w, r)
+	}
+}
@@ -0,0 +1,69 @@
+package handler
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestDecodeBody(t *testing.T) {
+	tests := []struct {
+		name        string
+		contentType string
+		body        string
+		want        int
+	}{
+		{"empty body", "", "", http.StatusOK},
+		{"empty object", "application/json", "{}", http.StatusOK},
+		{"all fields", "application/json; charset=utf-8", `{"reason":"demo","duration":"1h","wait":"30s"}`, http.StatusOK},
+		{"unknown field", "application/json", `{"reson":"typo"}`, http.StatusBadRequest},
+		{"wrong type", "application/json", `{"reason":42}`, http.StatusBadRequest},
+		{"numeric duration", "application/json", `{"duration":3600}`, http.StatusBadRequest},
+		{"negative duration", "application/json", `{"duration":"-1h"}`, http.StatusBadRequest},
+		{"trailing data", "application/json", `{} {}`, http.StatusBadRequest},
+		{"malformed", "application/json", `{"reason":`, http.StatusBadRequest},
+		{"not json", "text/plain", "reason=demo", http.StatusUnsupportedMediaType},
+		{"too large", "application/json", `{"reason":"` + strings.Repeat("x", maxBodyBytes) + `"}`, http.StatusRequestEntityTooLarge},
+	}
+	for _, tt := range tests {
+		req := httptest.NewRequest("POST", "/", strings.NewReader(tt.body))
+		if tt.contentType != "" {
+			req.Header.Set("Content-Type", tt.contentType)
+		}
+		w := httptest.NewRecorder()
+		var br bookRequest
+		if decodeBody(w, req, &br) {
+			w.WriteHeader(http.StatusOK)
+		}
+		if w.Code != tt.want {
+			t.Errorf("%s: expected %d, got %d: %s", tt.name, tt.want, w.Code, w.Body.String())
+		}
+	}
+}
+
+func TestDeprecatedAliases(t *testing.T) {
+	_, _, mux := setupHandler()
+
+	for _, path := range []string{"/api/status", "/api/v1/status"} {
+		req := httptest.NewRequest("GET", path, nil)
+		req.Header.Set(headerAppName, "argocd:my-app")
+		w := httptest.NewRecorder()
+		mux.ServeHTTP(w, req)
+
+		if w.Code != http.StatusOK {
+			t.Fatalf("%s: expected 200, got %d", path, w.Code)
+		}
+		deprecated := w.Header().Get("Deprecation") != ""
+		if deprecated != (path == "/api/status") {
+			t.Errorf("%s: unexpected Deprecation header %q", path, w.Header().Get("Deprecation"))
+		}
+	}
+
+	req := httptest.NewRequest("GET", "/api/list", nil)
+	w := httptest.NewRecorder()
+	mux.ServeHTTP(w, req)
+	if got := w.Header().Get("Link"); got != `<v1/list>; rel="successor-version"` {
+		t.Errorf("unexpected Link header %q", got)
+	}
+}
@@ -0,0 +1,101 @@
+package handler
+
+import (
+	"log/slog"
+	"net/http"
+	"path"
+	"strings"
+	"time"
+
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/behavox/argocd-book-plugin/internal/logging"
+)
+
+// headerRequestID carries the request ID, taken from the caller when valid and
+// generated otherwise. It is echoed in every response.
+const headerRequestID = "X-Request-Id"
+
+// action names a route in logs after the last element of its path, such as
+// "book" for both /api/v1/book and /api/book.
+func (rt route) action() string {
+	_, p, _ := strings.Cut(rt.pattern, " ")
+	return path.Base(p)
+}
+
+// withRequestLog assigns the request an ID and a logger carrying it along with
+// the action, user, namespace, app and trace ID, and logs the request once it
+// completes. The same fields are set on the request's span.
+// Probe requests are logged at debug level to keep them out of the way.
+func (h *Handler) withRequestLog(action string, next http.HandlerFunc) http.HandlerFunc {
+	level := slog.LevelInfo
+	if isProbe(action) {
+		level = slog.LevelDebug
+	}
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		id := r.Header.Get(headerRequestID)
+		if !logging.ValidRequestID(id) {
+			id = logging.NewRequestID()
+		}
+		w.Header().Set(headerRequestID, id)
+
+		attrs := []any{logging.KeyRequestID, id, logging.KeyAction, action}
+		if user := r.Header.Get(headerUsername); user != "" {
+			attrs = append(attrs, logging.KeyUser, user)
+		}
+		if ns, app, ok := parseAppHeader(r); ok {
+			attrs = append(attrs, logging.KeyNamespace, ns, logging.KeyApp, app)
+		}
+		span := trace.SpanFromContext(r.Context())
+		span.SetAttributes(requestAttributes(attrs)...)
+		if sc := span.SpanContext(); sc.HasTraceID() {
+			attrs = append(attrs, logging.KeyTraceID, sc.TraceID().String())
+		}
+		log := h.log.With(attrs...)
+
+		rec := &statusRecorder{ResponseWriter: w, status: http.StatusOK}
+		start := time.Now()
+		next(rec, r.WithContext(logging.NewContext(r.Context(), log)))
+		log.Log(r.Context(), level, "request completed",
+			"method", r.Method,
+			"path", r.URL.Path,
+			"status", rec.status,
+			"duration_ms", time.Since(start).Milliseconds(),
+		)
+	}
+}
+
+// isProbe reports whether action is a Kubernetes probe or the metrics endpoint,
+// which are requested every few seconds.
+func isProbe(action string) bool {
+	switch action {
+	case "livez", "readyz", "healthz", "metrics":
+		return true
+	}
+	return false
+}
+
+// requestLogger returns the logger set up for r by withRequestLog.
+func requestLogger(r *http.Request) *slog.Logger {
+	return logging.FromContext(r.Context())
+}
+
+// statusRecorder records the status code written through it.
+type statusRecorder struct {
+	http.ResponseWriter
+	status      int
+	wroteHeader bool
+}
+
+func (rec *statusRecorder) WriteHeader(status int) {
+	if !rec.wroteHeader {
+		rec.status, rec.wroteHeader = status, true
+	}
+	rec.ResponseWriter.WriteHeader(status)
+}
+
+// Unwrap lets http.ResponseController reach the underlying writer.
+func (rec *statusRecorder) Unwrap() http.ResponseWriter {
+	return rec.ResponseWriter
+}
@@ -0,0 +1,107 @@
+package handler
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/behavox/argocd-book-plugin/internal/k8s"
+)
+
+// failingBookClient fails every booking with an unexpected error.
+type failingBookClient struct {
+	*mockClient
+}
+
+func (failingBookClient) BookApp(context.Context, string, string, string, k8s.BookOptions) (k8s.BookResult, error) {
+	return k8s.BookResult{}, errors.New("etcd unavailable")
+}
+
+func setupLoggedHandler(client k8s.Client) (*bytes.Buffer, *http.ServeMux) {
+	var buf bytes.Buffer
+	h := New(client, WithLogger(slog.New(slog.NewJSONHandler(&buf, &slog.HandlerOptions{Level: slog.LevelDebug}))))
+	mux := http.NewServeMux()
+	h.RegisterRoutes(mux)
+	return &buf, mux
+}
+
+func logLines(t *testing.T, buf *bytes.Buffer) []map[string]interface{} {
+	t.Helper()
+	var lines []map[string]interface{}
+	sc := bufio.NewScanner(buf)
+	for sc.Scan() {
+		var line map[string]interface{}
+		if err := json.Unmarshal(sc.Bytes(), &line); err != nil {
+			t.Fatalf("invalid log line %q: %v", sc.Text(), err)
+		}
+		lines = append(lines, line)
+	}
+	return lines
+}
+
+func TestRequestLog_PropagatesRequestID(t *testing.T) {
+	buf, mux := setupLoggedHandler(failingBookClient{newMockClient()})
+
+	req := httptest.NewRequest("POST", "/api/v1/book", nil)
+	req.Header.Set(headerAppName, "argocd:my-app")
+	req.Header.Set(headerUsername, "alice")
+	req.Header.Set(headerRequestID, "req-42")
+	w := httptest.NewRecorder()
+	mux.ServeHTTP(w, req)
+
+	if w.Code != http.StatusInternalServerError || w.Header().Get(headerRequestID) != "req-42" {
+		t.Fatalf("expected 500 echoing the request ID, got %d %q", w.Code, w.Header().Get(headerRequestID))
+	}
+	var resp errorResponse
+	json.NewDecoder(w.Body).Decode(&resp)
+	if resp.RequestID != "req-42" {
+		t.Fatalf("expected the request ID in the error body, got %+v", resp)
+	}
+
+	lines := logLines(t, buf)
+	if len(lines) != 2 {
+		t.Fatalf("expected an error line and a request line, got %v", lines)
+	}
+	for _, line := range lines {
+		for key, want := range map[string]string{
+			"request_id": "req-42", "action": "book", "user": "alice", "namespace": "argocd", "app": "my-app",
+		} {
+			if line[key] != want {
+				t.Errorf("expected %s=%q in %v", key, want, line)
+			}
+		}
+	}
+	if lines[0]["level"] != "ERROR" || lines[0]["error"] != "etcd unavailable" {
+		t.Errorf("unexpected error line %v", lines[0])
+	}
+	if lines[1]["msg"] != "request completed" || lines[1]["status"] != float64(http.StatusInternalServerError) {
+		t.Errorf("unexpected request line %v", lines[1])
+	}
+}
+
+func TestRequestLog_GeneratesRequestID(t *testing.T) {
+	buf, mux := setupLoggedHandler(newMockClient())
+
+	req := httptest.NewRequest("GET", "/livez", nil)
+	req.Header.Set(headerRequestID, "not a valid id")
+	w := httptest.NewRecorder()
+	mux.ServeHTTP(w, req)
+
+	id := w.Header().Get(headerRequestID)
+	if len(id) != 32 {
+		t.Fatalf("expected a generated request ID, got %q", id)
+	}
+	lines := logLines(t, buf)
+	if len(lines) != 1 || lines[0]["request_id"] != id || lines[0]["level"] != "DEBUG" {
+		t.Fatalf("expected one debug line for the probe, got %v", lines)
+	}
+	if _, ok := lines[0]["user"]; ok {
+		t.Errorf("expected no user field without the header, got %v", lines[0])
+	}
+}
@@ -0,0 +1,27 @@
+package handler
+
+import (
+	"net/http"
+
+	"go.opentelemetry.io/contrib/instrumentation/net/http/otelhttp"
+	"go.opentelemetry.io/otel/attribute"
+)
+
+// withTracing starts a server span named after the route pattern, continuing
+// the caller's trace when the request carries W3C trace context headers, as
+// ArgoCD's proxy forwards them.
+func withTracing(pattern string, next http.Handler) http.Handler {
+	return otelhttp.NewHandler(next, pattern)
+}
+
+// requestAttributes converts the key-value log attributes of withRequestLog
+// into span attributes, so spans can be searched by user or application too.
+func requestAttributes(kvs []any) []attribute.KeyValue {
+	attrs := make([]attribute.KeyValue, 0, len(kvs)/2)
+	for i := 0; i+1 < len(kvs); i += 2 {
+		key, _ := kvs[i].(string)
+		value, _ := kvs[i+1].(string)
+		attrs = append(attrs, attribute.String("booking."+key, value))
+	}
+	return attrs
+}
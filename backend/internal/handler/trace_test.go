@@ -0,0 +1,61 @@
+package handler
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/propagation"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+func TestTracing_ContinuesCallerTrace(t *testing.T) {
+	exporter := tracetest.NewInMemoryExporter()
+	tp := sdktrace.NewTracerProvider(sdktrace.WithSyncer(exporter))
+	otel.SetTracerProvider(tp)
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+	t.Cleanup(func() { tp.Shutdown(context.Background()) })
+
+	buf, mux := setupLoggedHandler(newMockClient())
+	const traceID = "4bf92f3577b34da6a3ce929d0e0e4736"
+	req := httptest.NewRequest("POST", "/api/v1/book", nil)
+	req.Header.Set(headerAppName, "argocd:my-app")
+	req.Header.Set(headerUsername, "alice")
+	req.Header.Set("Traceparent", "00-"+traceID+"-00f067aa0ba902b7-01")
+	w := httptest.NewRecorder()
+	mux.ServeHTTP(w, req)
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", w.Code, w.Body.String())
+	}
+
+	spans := exporter.GetSpans()
+	if len(spans) != 1 {
+		t.Fatalf("expected one server span, got %d", len(spans))
+	}
+	span := spans[0]
+	if span.Name != "POST /api/v1/book" || span.SpanContext.TraceID().String() != traceID {
+		t.Fatalf("expected a span continuing the caller's trace, got %s in trace %s", span.Name, span.SpanContext.TraceID())
+	}
+	attrs := map[string]string{}
+	for _, a := range span.Attributes {
+		attrs[string(a.Key)] = a.Value.Emit()
+	}
+	if attrs["booking.user"] != "alice" || attrs["booking.app"] != "my-app" || attrs["booking.action"] != "book" {
+		t.Errorf("expected booking attributes on the span, got %v", attrs)
+	}
+
+	lines := logLines(t, buf)
+	if len(lines) != 1 || lines[0]["trace_id"] != traceID {
+		t.Fatalf("expected the trace ID in the request log line, got %v", lines)
+	}
+
+	exporter.Reset()
+	w = httptest.NewRecorder()
+	mux.ServeHTTP(w, httptest.NewRequest("GET", "/livez", nil))
+	if n := len(exporter.GetSpans()); n != 0 {
+		t.Fatalf("expected probes not to be traced, got %d spans", n)
+	}
+}
@@ -0,0 +1,129 @@
+package handler
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/behavox/argocd-book-plugin/internal/k8s"
+)
+
+const (
+	// maxBookWait caps the ?wait= duration so long-polls cannot pin connections indefinitely.
+	maxBookWait = 15 * time.Minute
+
+	defaultWaitPollInterval = 2 * time.Second
+)
+
+// waiter is a single wait-for-free book request.
+type waiter struct {
+	turn chan struct{} // closed when the waiter reaches the head of its queue
+	wake chan struct{} // signalled when the application may have been released
+}
+
+// waitQueue orders concurrent wait-for-free book requests per application, so
+// waiters acquire a released booking in arrival order. Only the head of each
+// queue attempts to book; the rest wait for their turn.
+type waitQueue struct {
+	mu     sync.Mutex
+	queues map[string][]*waiter
+}
+
+func newWaitQueue() *waitQueue {
+	return &waitQueue{queues: make(map[string][]*waiter)}
+}
+
+func (q *waitQueue) join(key string) *waiter {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	w := &waiter{turn: make(chan struct{}), wake: make(chan struct{}, 1)}
+	q.queues[key] = append(q.queues[key], w)
+	if len(q.queues[key]) == 1 {
+		close(w.turn)
+	}
+	return w
+}
+
+func (q *waitQueue) leave(key string, w *waiter) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	queue := q.queues[key]
+	for i, other := range queue {
+		if other != w {
+			continue
+		}
+		queue = append(queue[:i], queue[i+1:]...)
+		if i == 0 && len(queue) > 0 {
+			close(queue[0].turn)
+		}
+		break
+	}
+	if len(queue) == 0 {
+		delete(q.queues, key)
+		return
+	}
+	q.queues[key] = queue
+}
+
+// released wakes the head waiter of key so it retries immediately instead of at its next poll.
+func (q *waitQueue) released(key string) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if queue := q.queues[key]; len(queue) > 0 {
+		select {
+		case queue[0].wake <- struct{}{}:
+		default:
+		}
+	}
+}
+
+// waitAndBook queues behind earlier waiters for the application and, once at the
+// head, retries booking until it succeeds, fails for a reason other than a
+// conflict, ctx is done or the Handler is drained. booked is BookApp's result;
+// waited reports whether the booking had to wait for the application to become
+// free.
+func (h *Handler) waitAndBook(ctx context.Context, ns, app, username string, opts k8s.BookOptions, wait time.Duration) (booked k8s.BookResult, waited bool, err error) {
+	key := ns + "/" + app
+	w := h.waits.join(key)
+	defer h.waits.leave(key, w)
+
+	timeout := fmt.Errorf("%w: timed out after %s waiting for application to become free", k8s.ErrConflict, wait)
+	select {
+	case <-w.turn:
+	default:
+		waited = true
+		select {
+		case <-w.turn:
+		case <-ctx.Done():
+			return booked, waited, timeout
+		case <-h.draining:
+			return booked, waited, errDraining
+		}
+	}
+
+	ticker := time.NewTicker(h.waitPollInterval)
+	defer ticker.Stop()
+	for {
+		booked, err = h.client.BookApp(ctx, ns, app, username, opts)
+		if err == nil {
+			return booked, waited, nil
+		}
+		if ctx.Err() != nil {
+			return booked, waited, timeout
+		}
+		if !errors.Is(err, k8s.ErrConflict) {
+			return booked, waited, err
+		}
+		waited = true
+		select {
+		case <-ctx.Done():
+			return booked, waited, timeout
+		case <-h.draining:
+			return booked, waited, errDraining
+		case <-ticker.C:
+		case <-w.wake:
+		}
+	}
+}
@@ -0,0 +1,171 @@
+package handler
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/behavox/argocd-book-plugin/internal/k8s"
+)
+
+func setupWaitHandler() (*Handler, *mockClient, *http.ServeMux) {
+	h, mc, mux := setupHandler()
+	h.waitPollInterval = 10 * time.Millisecond
+	return h, mc, mux
+}
+
+func postAs(mux *http.ServeMux, path, user string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest("POST", path, nil)
+	req.Header.Set(headerAppName, "argocd:my-app")
+	req.Header.Set(headerUsername, user)
+	w := httptest.NewRecorder()
+	mux.ServeHTTP(w, req)
+	return w
+}
+
+func waitForQueue(t *testing.T, h *Handler, n int) {
+	t.Helper()
+	deadline := time.Now().Add(2 * time.Second)
+	for time.Now().Before(deadline) {
+		h.waits.mu.Lock()
+		got := len(h.waits.queues["argocd/my-app"])
+		h.waits.mu.Unlock()
+		if got == n {
+			return
+		}
+		time.Sleep(time.Millisecond)
+	}
+	t.Fatalf("timed out waiting for %d waiters", n)
+}
+
+func TestBookWait_AcquiresWhenReleased(t *testing.T) {
+	h, mc, mux := setupWaitHandler()
+	mc.BookApp(context.Background(), "argocd", "my-app", "alice", k8s.BookOptions{})
+
+	done := make(chan *httptest.ResponseRecorder)
+	go func() { done <- postAs(mux, "/api/book?wait=5s", "bob") }()
+	waitForQueue(t, h, 1)
+
+	if w := postAs(mux, "/api/unbook", "alice"); w.Code != http.StatusOK {
+		t.Fatalf("unbook failed: %d", w.Code)
+	}
+
+	w := <-done
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", w.Code, w.Body.String())
+	}
+	if got := mc.bookings["argocd/my-app"].BookedBy; got != "bob" {
+		t.Fatalf("expected booking held by bob, got %q", got)
+	}
+}
+
+func TestBookWait_NotifiesWaiter(t *testing.T) {
+	rn := &recordingNotifier{}
+	mc := newMockClient()
+	h := New(mc, WithWaitNotifier(rn))
+	h.waitPollInterval = 10 * time.Millisecond
+	mux := http.NewServeMux()
+	h.RegisterRoutes(mux)
+
+	// A free application is booked straight away, without a message.
+	if w := postAs(mux, "/api/book?wait=5s", "alice"); w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d", w.Code)
+	}
+	if len(rn.events) != 0 {
+		t.Fatalf("expected no wait notification, got %+v", rn.events)
+	}
+
+	done := make(chan *httptest.ResponseRecorder)
+	go func() { done <- postAs(mux, "/api/book?wait=5s", "bob") }()
+	waitForQueue(t, h, 1)
+	time.Sleep(30 * time.Millisecond) // let bob find the application booked
+	postAs(mux, "/api/unbook", "alice")
+	if w := <-done; w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", w.Code, w.Body.String())
+	}
+	if len(rn.events) != 1 || rn.events[0].User != "bob" || rn.events[0].AppName != "my-app" {
+		t.Fatalf("expected bob to be told, got %+v", rn.events)
+	}
+}
+
+func TestBookWait_OutlastsWriteTimeout(t *testing.T) {
+	h, mc, mux := setupWaitHandler()
+	mc.BookApp(context.Background(), "argocd", "my-app", "alice", k8s.BookOptions{})
+	srv := httptest.NewUnstartedServer(mux)
+	srv.Config.WriteTimeout = 50 * time.Millisecond
+	srv.Start()
+	defer srv.Close()
+
+	done := make(chan error, 1)
+	go func() {
+		req, _ := http.NewRequest("POST", srv.URL+"/api/book?wait=5s", nil)
+		req.Header.Set(headerAppName, "argocd:my-app")
+		req.Header.Set(headerUsername, "bob")
+		resp, err := http.DefaultClient.Do(req)
+		if err == nil {
+			resp.Body.Close()
+			if resp.StatusCode != http.StatusOK {
+				err = fmt.Errorf("expected 200, got %d", resp.StatusCode)
+			}
+		}
+		done <- err
+	}()
+	waitForQueue(t, h, 1)
+	time.Sleep(100 * time.Millisecond) // past the server write timeout
+
+	if w := postAs(mux, "/api/unbook", "alice"); w.Code != http.StatusOK {
+		t.Fatalf("unbook failed: %d", w.Code)
+	}
+	if err := <-done; err != nil {
+		t.Fatalf("expected the waiting request to get its response, got %v", err)
+	}
+}
+
+func TestBookWait_TimesOut(t *testing.T) {
+	_, mc, mux := setupWaitHandler()
+	mc.BookApp(context.Background(), "argocd", "my-app", "alice", k8s.BookOptions{})
+
+	w := postAs(mux, "/api/book?wait=50ms", "bob")
+	if w.Code != http.StatusConflict {
+		t.Fatalf("expected 409, got %d: %s", w.Code, w.Body.String())
+	}
+}
+
+func TestBookWait_FairOrdering(t *testing.T) {
+	h, mc, mux := setupWaitHandler()
+	mc.BookApp(context.Background(), "argocd", "my-app", "alice", k8s.BookOptions{})
+
+	bobDone := make(chan *httptest.ResponseRecorder)
+	go func() { bobDone <- postAs(mux, "/api/book?wait=5s", "bob") }()
+	waitForQueue(t, h, 1)
+	carolDone := make(chan *httptest.ResponseRecorder)
+	go func() { carolDone <- postAs(mux, "/api/book?wait=5s", "carol") }()
+	waitForQueue(t, h, 2)
+
+	postAs(mux, "/api/unbook", "alice")
+	if w := <-bobDone; w.Code != http.StatusOK {
+		t.Fatalf("expected bob to book first, got %d: %s", w.Code, w.Body.String())
+	}
+
+	select {
+	case w := <-carolDone:
+		t.Fatalf("carol should still be waiting, got %d", w.Code)
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	postAs(mux, "/api/unbook", "bob")
+	if w := <-carolDone; w.Code != http.StatusOK {
+		t.Fatalf("expected carol to book next, got %d: %s", w.Code, w.Body.String())
+	}
+}
+
+func TestBookWait_InvalidDuration(t *testing.T) {
+	_, _, mux := setupWaitHandler()
+
+	if w := postAs(mux, "/api/book?wait=soon", "bob"); w.Code != http.StatusBadRequest {
+		t.Fatalf("expected 400, got %d", w.Code)
+	}
+}
@@ -0,0 +1,173 @@
+// Package idle finds bookings whose application has not been synced for a
+// while since it was booked, and warns about or releases them as the booking
+// policy of the application says.
+package idle
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/behavox/argocd-book-plugin/internal/config"
+	"github.com/behavox/argocd-book-plugin/internal/k8s"
+	"github.com/behavox/argocd-book-plugin/internal/logging"
+	"github.com/behavox/argocd-book-plugin/internal/notify"
+	"github.com/behavox/argocd-book-plugin/internal/policy"
+)
+
+// Client is the part of k8s.Client the Detector uses.
+type Client interface {
+	ListApplications(ctx context.Context, namespace string) ([]k8s.Application, error)
+	UnbookApp(ctx context.Context, namespace, appName, username string, isAdmin bool, opts k8s.UnbookOptions) (released bool, err error)
+}
+
+// Config configures the idle booking job.
+type Config struct {
+	// Namespace to scan; empty means all namespaces.
+	Namespace string
+	// Interval between scans.
+	Interval time.Duration
+}
+
+// Detector periodically checks booked applications against the idleTimeout of
+// their policy. An idle booking is warned about once, or released.
+type Detector struct {
+	cfg      Config
+	client   Client
+	store    *config.Store
+	notifier notify.Notifier
+	now      func() time.Time
+
+	warned map[string]bool // key: namespace/app/bookedAt
+}
+
+// New creates a Detector reading policies from store. notifier may be nil.
+// Call Run to start it.
+func New(cfg Config, client Client, store *config.Store, notifier notify.Notifier) *Detector {
+	if cfg.Interval <= 0 {
+		cfg.Interval = time.Minute
+	}
+	return &Detector{
+		cfg:      cfg,
+		client:   client,
+		store:    store,
+		notifier: notifier,
+		now:      time.Now,
+		warned:   make(map[string]bool),
+	}
+}
+
+// Run scans for idle bookings until ctx is cancelled.
+func (d *Detector) Run(ctx context.Context) {
+	ticker := time.NewTicker(d.cfg.Interval)
+	defer ticker.Stop()
+	for {
+		d.check(ctx)
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (d *Detector) check(ctx context.Context) {
+	cfg := d.store.Config()
+	if !tracksIdle(cfg.Policies) {
+		return
+	}
+	apps, err := d.client.ListApplications(ctx, d.cfg.Namespace)
+	if err != nil {
+		slog.Error("idle: failed to list applications", logging.KeyError, err)
+		return
+	}
+
+	now := d.now()
+	policies := cfg.PolicySet()
+	idle := make(map[string]bool)
+	for _, app := range apps {
+		b := app.Booking
+		if b == nil {
+			continue
+		}
+		p := policies.For(app.Namespace, app.Project)
+		idleFor, ok := idleDuration(app, now)
+		if p.IdleTimeout <= 0 || !ok || idleFor < time.Duration(p.IdleTimeout) {
+			continue
+		}
+		key := app.Namespace + "/" + app.Name + "/" + b.BookedAt
+		idle[key] = true
+		reason := fmt.Sprintf("no sync for %s", idleFor.Truncate(time.Minute))
+		logger := slog.With(logging.KeyUser, b.BookedBy, logging.KeyNamespace, app.Namespace, logging.KeyApp, app.Name, "reason", reason)
+
+		if p.IdleAction == policy.IdleRelease {
+			// Unbooking as the holder fails if the application changed hands
+			// since it was listed: UnbookApp checks the holder again and
+			// patches with a resourceVersion precondition.
+			released, err := d.client.UnbookApp(ctx, app.Namespace, app.Name, b.BookedBy, false, k8s.UnbookOptions{})
+			if err != nil {
+				logger.Error("idle: failed to release booking", logging.KeyError, err)
+				continue
+			}
+			if !released {
+				continue
+			}
+			logger.Info("idle booking released", logging.KeyAction, "release", logging.KeyAudit, true)
+			d.notify(notify.EventReleased, app, reason, now)
+			continue
+		}
+		if !d.warned[key] {
+			logger.Warn("idle booking", logging.KeyAction, "warn")
+			d.notify(notify.EventIdle, app, reason, now)
+			d.warned[key] = true
+		}
+	}
+
+	// Forget bookings that ended or became active again, so they are warned
+	// about afresh once idle.
+	for key := range d.warned {
+		if !idle[key] {
+			delete(d.warned, key)
+		}
+	}
+}
+
+// tracksIdle reports whether any of policies has an idle timeout, so the
+// applications need not be listed otherwise.
+func tracksIdle(policies []policy.Policy) bool {
+	for _, p := range policies {
+		if p.IdleTimeout > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// idleDuration returns how long app has gone without a sync since it was
+// booked. ok is false when the booking time is missing or malformed.
+func idleDuration(app k8s.Application, now time.Time) (d time.Duration, ok bool) {
+	since, err := time.Parse(time.RFC3339, app.Booking.BookedAt)
+	if err != nil {
+		return 0, false
+	}
+	if synced, err := time.Parse(time.RFC3339, app.LastSyncAt); err == nil && synced.After(since) {
+		since = synced
+	}
+	return now.Sub(since), true
+}
+
+func (d *Detector) notify(t notify.EventType, app k8s.Application, reason string, now time.Time) {
+	if d.notifier == nil {
+		return
+	}
+	d.notifier.Notify(notify.Event{
+		Type:      t,
+		AppName:   app.Name,
+		Namespace: app.Namespace,
+		Project:   app.Project,
+		User:      app.Booking.BookedBy,
+		Reason:    reason,
+		Timestamp: now.UTC(),
+	})
+}
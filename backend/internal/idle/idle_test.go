@@ -0,0 +1,144 @@
+package idle
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"testing"
+	"time"
+
+	"github.com/behavox/argocd-book-plugin/internal/config"
+	"github.com/behavox/argocd-book-plugin/internal/k8s"
+	"github.com/behavox/argocd-book-plugin/internal/notify"
+)
+
+type fakeClient struct {
+	apps     []k8s.Application
+	unbooked []string
+}
+
+func (c *fakeClient) ListApplications(_ context.Context, _ string) ([]k8s.Application, error) {
+	return c.apps, nil
+}
+
+func (c *fakeClient) UnbookApp(_ context.Context, namespace, appName, username string, _ bool, _ k8s.UnbookOptions) (bool, error) {
+	for i, a := range c.apps {
+		if a.Namespace == namespace && a.Name == appName {
+			if a.Booking == nil || a.Booking.BookedBy != username {
+				return false, fmt.Errorf("%w: not booked by %s", k8s.ErrForbidden, username)
+			}
+			c.apps[i].Booking = nil
+			c.unbooked = append(c.unbooked, namespace+"/"+appName)
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+type recordingNotifier struct {
+	events []notify.Event
+}
+
+func (n *recordingNotifier) Notify(e notify.Event) {
+	n.events = append(n.events, e)
+}
+
+func newStore(t *testing.T, doc string) *config.Store {
+	t.Helper()
+	c, err := config.Parse([]byte(doc))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return config.NewStore(c)
+}
+
+func bookedApp(project, name, bookedAt, lastSyncAt string) k8s.Application {
+	return k8s.Application{
+		Name:       name,
+		Namespace:  "argocd",
+		Project:    project,
+		LastSyncAt: lastSyncAt,
+		Booking:    &k8s.Booking{AppName: name, Namespace: "argocd", BookedBy: "alice", BookedAt: bookedAt},
+	}
+}
+
+func TestDetector_WarnsOncePerIdleBooking(t *testing.T) {
+	store := newStore(t, `
+policies:
+  - project: staging
+    idleTimeout: 4h
+`)
+	now := time.Date(2026, 1, 15, 18, 0, 0, 0, time.UTC)
+	client := &fakeClient{apps: []k8s.Application{
+		bookedApp("staging", "idle", "2026-01-15T09:00:00Z", "2026-01-15T13:30:00Z"),
+		bookedApp("staging", "synced", "2026-01-15T09:00:00Z", "2026-01-15T15:00:00Z"),
+		bookedApp("staging", "fresh", "2026-01-15T16:00:00Z", ""),
+		bookedApp("dev", "unlimited", "2026-01-01T09:00:00Z", ""),
+		{Name: "free", Namespace: "argocd", Project: "staging"},
+	}}
+	n := &recordingNotifier{}
+	d := New(Config{}, client, store, n)
+	d.now = func() time.Time { return now }
+
+	d.check(context.Background())
+	d.check(context.Background())
+
+	if len(n.events) != 1 {
+		t.Fatalf("expected 1 warning, got %+v", n.events)
+	}
+	e := n.events[0]
+	if e.Type != notify.EventIdle || e.AppName != "idle" || e.User != "alice" || e.Reason != "no sync for 4h30m0s" {
+		t.Fatalf("unexpected event %+v", e)
+	}
+	if len(client.unbooked) != 0 {
+		t.Fatalf("expected nothing released, got %v", client.unbooked)
+	}
+
+	// A sync makes the booking active again; once idle again it is warned about afresh.
+	client.apps[0].LastSyncAt = "2026-01-15T17:00:00Z"
+	d.check(context.Background())
+	now = now.Add(4 * time.Hour)
+	d.check(context.Background())
+	if len(n.events) != 4 {
+		t.Fatalf("expected idle, synced and fresh to be warned about, got %d events", len(n.events))
+	}
+}
+
+func TestDetector_ReleasesPerPolicy(t *testing.T) {
+	store := newStore(t, `
+policies:
+  - project: staging
+    idleTimeout: 2h
+    idleAction: release
+`)
+	now := time.Date(2026, 1, 15, 18, 0, 0, 0, time.UTC)
+	client := &fakeClient{apps: []k8s.Application{
+		bookedApp("staging", "idle", "2026-01-15T09:00:00Z", ""),
+		bookedApp("staging", "busy", "2026-01-15T09:00:00Z", "2026-01-15T17:00:00Z"),
+	}}
+	n := &recordingNotifier{}
+	d := New(Config{}, client, store, n)
+	d.now = func() time.Time { return now }
+
+	var buf bytes.Buffer
+	defer slog.SetDefault(slog.Default())
+	slog.SetDefault(slog.New(slog.NewJSONHandler(&buf, nil)))
+
+	d.check(context.Background())
+
+	if len(client.unbooked) != 1 || client.unbooked[0] != "argocd/idle" {
+		t.Fatalf("expected argocd/idle to be released, got %v", client.unbooked)
+	}
+	if len(n.events) != 1 || n.events[0].Type != notify.EventReleased || n.events[0].Reason != "no sync for 9h0m0s" {
+		t.Fatalf("unexpected events %+v", n.events)
+	}
+	var line map[string]any
+	if err := json.Unmarshal(buf.Bytes(), &line); err != nil {
+		t.Fatalf("expected one log line, got %q", buf.String())
+	}
+	if line["msg"] != "idle booking released" || line["audit"] != true || line["user"] != "alice" {
+		t.Fatalf("expected an audit record of the release, got %v", line)
+	}
+}
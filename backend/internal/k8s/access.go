@@ -0,0 +1,53 @@
+package k8s
+
+import (
+	"context"
+	"fmt"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/dynamic"
+)
+
+var selfSubjectAccessReviewGVR = schema.GroupVersionResource{
+	Group:    "authorization.k8s.io",
+	Version:  "v1",
+	Resource: "selfsubjectaccessreviews",
+}
+
+// CheckApplicationAccess asks the API server, with a SelfSubjectAccessReview,
+// whether the client's own identity may perform verb on Applications in
+// namespace (all namespaces when empty). A denial is returned as an error
+// wrapping ErrForbidden; an unreachable API server as any other error.
+func CheckApplicationAccess(ctx context.Context, dyn dynamic.Interface, namespace, verb string) error {
+	review := &unstructured.Unstructured{Object: map[string]interface{}{
+		"apiVersion": "authorization.k8s.io/v1",
+		"kind":       "SelfSubjectAccessReview",
+		"spec": map[string]interface{}{
+			"resourceAttributes": map[string]interface{}{
+				"group":     applicationGVR.Group,
+				"resource":  applicationGVR.Resource,
+				"verb":      verb,
+				"namespace": namespace,
+			},
+		},
+	}}
+	result, err := dyn.Resource(selfSubjectAccessReviewGVR).Create(ctx, review, metav1.CreateOptions{})
+	if err != nil {
+		return fmt.Errorf("failed to create SelfSubjectAccessReview: %w", err)
+	}
+	allowed, _, _ := unstructured.NestedBool(result.Object, "status", "allowed")
+	if allowed {
+		return nil
+	}
+	reason, _, _ := unstructured.NestedString(result.Object, "status", "reason")
+	if reason == "" {
+		reason = "no RBAC rule allows it"
+	}
+	where := "all namespaces"
+	if namespace != "" {
+		where = "namespace " + namespace
+	}
+	return fmt.Errorf("%w: cannot %s applications.%s in %s: %s", ErrForbidden, verb, applicationGVR.Group, where, reason)
+}
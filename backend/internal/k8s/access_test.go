@@ -0,0 +1,60 @@
+package k8s
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	dynamicfake "k8s.io/client-go/dynamic/fake"
+	k8stesting "k8s.io/client-go/testing"
+)
+
+// reviewReactor answers SelfSubjectAccessReviews, allowing only the verbs in allowed.
+func reviewReactor(allowed map[string]bool) k8stesting.ReactionFunc {
+	return func(action k8stesting.Action) (bool, runtime.Object, error) {
+		review := action.(k8stesting.CreateAction).GetObject().(*unstructured.Unstructured).DeepCopy()
+		verb, _, _ := unstructured.NestedString(review.Object, "spec", "resourceAttributes", "verb")
+		unstructured.SetNestedField(review.Object, allowed[verb], "status", "allowed")
+		return true, review, nil
+	}
+}
+
+func TestCheckApplicationAccess(t *testing.T) {
+	dyn := dynamicfake.NewSimpleDynamicClient(runtime.NewScheme())
+	dyn.PrependReactor("create", "selfsubjectaccessreviews", reviewReactor(map[string]bool{"list": true}))
+
+	if err := CheckApplicationAccess(context.Background(), dyn, "argocd", "list"); err != nil {
+		t.Fatalf("expected list to be allowed, got %v", err)
+	}
+	err := CheckApplicationAccess(context.Background(), dyn, "argocd", "patch")
+	if !errors.Is(err, ErrForbidden) {
+		t.Fatalf("expected ErrForbidden for patch, got %v", err)
+	}
+
+	var verbs []string
+	for _, a := range dyn.Actions() {
+		obj := a.(k8stesting.CreateAction).GetObject().(*unstructured.Unstructured)
+		attrs, _, _ := unstructured.NestedStringMap(obj.Object, "spec", "resourceAttributes")
+		if attrs["group"] != "argoproj.io" || attrs["resource"] != "applications" || attrs["namespace"] != "argocd" {
+			t.Errorf("unexpected resource attributes %v", attrs)
+		}
+		verbs = append(verbs, attrs["verb"])
+	}
+	if len(verbs) != 2 || verbs[0] != "list" || verbs[1] != "patch" {
+		t.Fatalf("expected a review per verb, got %v", verbs)
+	}
+}
+
+func TestCheckApplicationAccess_APIError(t *testing.T) {
+	dyn := dynamicfake.NewSimpleDynamicClient(runtime.NewScheme())
+	dyn.PrependReactor("create", "selfsubjectaccessreviews", func(k8stesting.Action) (bool, runtime.Object, error) {
+		return true, nil, errors.New("connection refused")
+	})
+
+	err := CheckApplicationAccess(context.Background(), dyn, "", "list")
+	if err == nil || errors.Is(err, ErrForbidden) {
+		t.Fatalf("expected a non-forbidden error, got %v", err)
+	}
+}
@@ -0,0 +1,102 @@
+package k8s
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"time"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+
+	"github.com/behavox/argocd-book-plugin/internal/logging"
+)
+
+// AnnotationPausedAutoSync holds the JSON of spec.syncPolicy.automated as it was
+// when a booking paused automated sync, until the policy is restored.
+const AnnotationPausedAutoSync = "booking.argocd.io/paused-auto-sync"
+
+// pauseAutoSync adds to patch, a merge patch with metadata.annotations, the
+// removal of app's automated sync policy and the annotation saving it. It does
+// nothing when app has no automated sync policy or one is saved already.
+func pauseAutoSync(app *unstructured.Unstructured, patch map[string]interface{}) error {
+	if _, paused := app.GetAnnotations()[AnnotationPausedAutoSync]; paused {
+		return nil
+	}
+	automated, found, _ := unstructured.NestedFieldNoCopy(app.Object, "spec", "syncPolicy", "automated")
+	if !found || automated == nil {
+		return nil
+	}
+	saved, err := json.Marshal(automated)
+	if err != nil {
+		return fmt.Errorf("failed to save automated sync policy: %w", err)
+	}
+	annotations := patch["metadata"].(map[string]interface{})["annotations"].(map[string]interface{})
+	annotations[AnnotationPausedAutoSync] = string(saved)
+	patch["spec"] = map[string]interface{}{"syncPolicy": map[string]interface{}{"automated": nil}}
+	return nil
+}
+
+// restoreAutoSync adds to patch, a merge patch with metadata.annotations, the
+// removal of app's AnnotationPausedAutoSync and the restoration of the policy
+// it saved, and reports whether app had one. If automated sync was turned back
+// on while paused, or the saved policy cannot be read, the current policy is
+// kept and only the annotation removed. Restoring sets the resourceVersion
+// precondition, so a policy changed since app was read is not overwritten.
+func restoreAutoSync(app *unstructured.Unstructured, patch map[string]interface{}) bool {
+	saved, paused := app.GetAnnotations()[AnnotationPausedAutoSync]
+	if !paused {
+		return false
+	}
+	metadata := patch["metadata"].(map[string]interface{})
+	metadata["annotations"].(map[string]interface{})[AnnotationPausedAutoSync] = nil
+
+	logger := slog.With(logging.KeyNamespace, app.GetNamespace(), logging.KeyApp, app.GetName())
+	var automated map[string]interface{}
+	if err := json.Unmarshal([]byte(saved), &automated); err != nil || automated == nil {
+		logger.Warn("paused automated sync policy is unreadable, not restoring it", "saved", saved)
+		return true
+	}
+	if current, found, _ := unstructured.NestedFieldNoCopy(app.Object, "spec", "syncPolicy", "automated"); found && current != nil {
+		logger.Warn("automated sync was turned back on while paused, keeping the current policy")
+		return true
+	}
+	metadata["resourceVersion"] = app.GetResourceVersion()
+	patch["spec"] = map[string]interface{}{"syncPolicy": map[string]interface{}{"automated": automated}}
+	return true
+}
+
+// ResumeAutoSync restores the automated sync policy a booking paused once the
+// booking has expired. It does nothing while the application is booked or when
+// nothing is paused.
+func (c *client) ResumeAutoSync(ctx context.Context, namespace, appName string) error {
+	if err := ValidateAppRef(namespace, appName); err != nil {
+		return err
+	}
+	app, err := c.getApp(ctx, namespace, appName)
+	if err != nil {
+		return err
+	}
+	if bookedBy, _ := activeBooking(app, time.Now()); bookedBy != "" {
+		return nil
+	}
+	patch := map[string]interface{}{
+		"metadata": map[string]interface{}{"annotations": map[string]interface{}{}},
+	}
+	if !restoreAutoSync(app, patch) {
+		return nil
+	}
+	patchBytes, err := json.Marshal(patch)
+	if err != nil {
+		return fmt.Errorf("failed to marshal patch: %w", err)
+	}
+	err = c.patchApp(ctx, namespace, appName, patchBytes, false)
+	if apierrors.IsConflict(err) {
+		return fmt.Errorf("%w: application %s/%s was modified concurrently, retry", ErrConflict, namespace, appName)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to patch application %s/%s: %w", namespace, appName, err)
+	}
+	return nil
+}
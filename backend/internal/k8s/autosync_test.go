@@ -0,0 +1,125 @@
+package k8s
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+func newAutoSyncApp() *unstructured.Unstructured {
+	app := newFakeApp("argocd", "my-app", nil)
+	unstructured.SetNestedField(app.Object, map[string]interface{}{"prune": true, "selfHeal": true}, "spec", "syncPolicy", "automated")
+	unstructured.SetNestedStringSlice(app.Object, []string{"CreateNamespace=true"}, "spec", "syncPolicy", "syncOptions")
+	return app
+}
+
+func TestAutoSync_PausedWhileBooked(t *testing.T) {
+	dyn := newFakeDynamic(newAutoSyncApp())
+	c := NewClientFromDynamic(dyn)
+	ctx := context.Background()
+	get := func() *unstructured.Unstructured {
+		t.Helper()
+		app, err := dyn.Resource(applicationGVR).Namespace("argocd").Get(ctx, "my-app", metav1.GetOptions{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return app
+	}
+
+	if _, err := c.BookApp(ctx, "argocd", "my-app", "alice", BookOptions{PauseAutoSync: true}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	app := get()
+	if _, found, _ := unstructured.NestedFieldNoCopy(app.Object, "spec", "syncPolicy", "automated"); found {
+		t.Fatal("expected automated sync to be turned off")
+	}
+	if got := app.GetAnnotations()[AnnotationPausedAutoSync]; got != `{"prune":true,"selfHeal":true}` {
+		t.Fatalf("expected the policy to be saved, got %q", got)
+	}
+	apps, _ := c.ListApplications(ctx, "argocd")
+	if len(apps) != 1 || !apps[0].AutoSyncPaused {
+		t.Fatalf("expected the application to report paused automated sync, got %+v", apps)
+	}
+
+	if _, err := c.UnbookApp(ctx, "argocd", "my-app", "alice", false, UnbookOptions{}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	app = get()
+	automated, _, _ := unstructured.NestedMap(app.Object, "spec", "syncPolicy", "automated")
+	if !reflect.DeepEqual(automated, map[string]interface{}{"prune": true, "selfHeal": true}) {
+		t.Fatalf("expected the policy to be restored exactly, got %v", automated)
+	}
+	if opts, _, _ := unstructured.NestedStringSlice(app.Object, "spec", "syncPolicy", "syncOptions"); len(opts) != 1 {
+		t.Fatalf("expected the rest of the sync policy to be kept, got %v", opts)
+	}
+	if _, paused := app.GetAnnotations()[AnnotationPausedAutoSync]; paused {
+		t.Fatal("expected the saved policy annotation to be removed")
+	}
+}
+
+func TestAutoSync_NotPausedWithoutOption(t *testing.T) {
+	dyn := newFakeDynamic(newAutoSyncApp())
+	c := NewClientFromDynamic(dyn)
+	if _, err := c.BookApp(context.Background(), "argocd", "my-app", "alice", BookOptions{}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	app, _ := dyn.Resource(applicationGVR).Namespace("argocd").Get(context.Background(), "my-app", metav1.GetOptions{})
+	if _, found, _ := unstructured.NestedMap(app.Object, "spec", "syncPolicy", "automated"); !found {
+		t.Fatal("expected automated sync to stay on")
+	}
+}
+
+func TestAutoSync_KeepsPolicyChangedWhilePaused(t *testing.T) {
+	// Someone turned automated sync back on, without pruning, during the booking.
+	app := newFakeApp("argocd", "my-app", map[string]string{
+		AnnotationBookedBy:       "alice",
+		AnnotationBookedAt:       "2026-01-15T10:00:00Z",
+		AnnotationPausedAutoSync: `{"prune":true,"selfHeal":true}`,
+	})
+	unstructured.SetNestedField(app.Object, map[string]interface{}{"selfHeal": true}, "spec", "syncPolicy", "automated")
+	dyn := newFakeDynamic(app)
+	c := NewClientFromDynamic(dyn)
+
+	if _, err := c.UnbookApp(context.Background(), "argocd", "my-app", "alice", false, UnbookOptions{}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	got, _ := dyn.Resource(applicationGVR).Namespace("argocd").Get(context.Background(), "my-app", metav1.GetOptions{})
+	automated, _, _ := unstructured.NestedMap(got.Object, "spec", "syncPolicy", "automated")
+	if !reflect.DeepEqual(automated, map[string]interface{}{"selfHeal": true}) {
+		t.Fatalf("expected the current policy to be kept, got %v", automated)
+	}
+	if _, paused := got.GetAnnotations()[AnnotationPausedAutoSync]; paused {
+		t.Fatal("expected the saved policy annotation to be removed")
+	}
+}
+
+func TestResumeAutoSync(t *testing.T) {
+	paused := func(name, expiresAt string) *unstructured.Unstructured {
+		return newFakeApp("argocd", name, map[string]string{
+			AnnotationBookedBy:       "alice",
+			AnnotationBookedAt:       "2026-01-15T10:00:00Z",
+			AnnotationExpiresAt:      expiresAt,
+			AnnotationPausedAutoSync: `{"prune":false}`,
+		})
+	}
+	dyn := newFakeDynamic(paused("expired", "2026-01-15T12:00:00Z"), paused("active", "2099-01-01T00:00:00Z"))
+	c := NewClientFromDynamic(dyn)
+	ctx := context.Background()
+
+	for _, name := range []string{"expired", "active"} {
+		if err := c.ResumeAutoSync(ctx, "argocd", name); err != nil {
+			t.Fatalf("%s: unexpected error: %v", name, err)
+		}
+	}
+	expired, _ := dyn.Resource(applicationGVR).Namespace("argocd").Get(ctx, "expired", metav1.GetOptions{})
+	if automated, found, _ := unstructured.NestedMap(expired.Object, "spec", "syncPolicy", "automated"); !found || automated["prune"] != false {
+		t.Fatalf("expected the expired booking's policy to be restored, got %v", expired.Object["spec"])
+	}
+	active, _ := dyn.Resource(applicationGVR).Namespace("argocd").Get(ctx, "active", metav1.GetOptions{})
+	if _, found, _ := unstructured.NestedMap(active.Object, "spec", "syncPolicy", "automated"); found {
+		t.Fatal("expected automated sync to stay paused while booked")
+	}
+}
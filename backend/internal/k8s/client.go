@@ -3,20 +3,41 @@ package k8s
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"sort"
+	"strings"
 	"time"
 
+	"go.opentelemetry.io/otel/attribute"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 	"k8s.io/apimachinery/pkg/runtime/schema"
 	"k8s.io/apimachinery/pkg/types"
 	"k8s.io/client-go/dynamic"
 	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/clientcmd"
 )
 
 const (
 	AnnotationBookedBy = "booking.argocd.io/booked-by"
 	AnnotationBookedAt = "booking.argocd.io/booked-at"
+	// AnnotationExpiresAt optionally holds the RFC 3339 time at which the booking lapses.
+	AnnotationExpiresAt = "booking.argocd.io/expires-at"
+	// AnnotationReason optionally holds the free-text reason given by the holder.
+	AnnotationReason = "booking.argocd.io/reason"
+)
+
+// Sentinel errors returned (wrapped) by Client methods. Their text doubles as the
+// message prefix, so "conflict: application already booked by alice" matches ErrConflict.
+var (
+	ErrConflict  = errors.New("conflict")
+	ErrForbidden = errors.New("forbidden")
+	// ErrLimit is returned by BookApp when the user holds BookOptions.MaxHeld
+	// applications already, or when renewing would exceed BookOptions.MaxDuration,
+	// and by TransferApp when the target holds TransferOptions.MaxHeld.
+	ErrLimit = errors.New("booking limit reached")
 )
 
 var applicationGVR = schema.GroupVersionResource{
@@ -27,18 +48,100 @@ var applicationGVR = schema.GroupVersionResource{
 
 // Booking represents the booking state of an Application.
 type Booking struct {
-	AppName   string `json:"appName"`
-	Namespace string `json:"namespace"`
-	BookedBy  string `json:"bookedBy"`
-	BookedAt  string `json:"bookedAt"`
+	AppName   string            `json:"appName"`
+	Namespace string            `json:"namespace"`
+	Project   string            `json:"project,omitempty"`
+	BookedBy  string            `json:"bookedBy"`
+	BookedAt  string            `json:"bookedAt"`
+	ExpiresAt string            `json:"expiresAt,omitempty"`
+	Reason    string            `json:"reason,omitempty"`
+	Labels    map[string]string `json:"labels,omitempty"`
+}
+
+// Application is an Application with its booking state, as returned by ListApplications.
+type Application struct {
+	Name        string            `json:"name"`
+	Namespace   string            `json:"namespace"`
+	Project     string            `json:"project,omitempty"`
+	Labels      map[string]string `json:"labels,omitempty"`
+	Destination Destination       `json:"destination"`
+	// SyncStatus and HealthStatus mirror status.sync.status and status.health.status.
+	SyncStatus   string `json:"syncStatus,omitempty"`
+	HealthStatus string `json:"healthStatus,omitempty"`
+	// LastSyncAt is when the latest sync not started by automated sync began,
+	// in RFC 3339, or empty if there is none on record.
+	LastSyncAt string `json:"lastSyncAt,omitempty"`
+	// AutoSyncPaused is set while a booking has automated sync turned off.
+	AutoSyncPaused bool `json:"autoSyncPaused,omitempty"`
+	// Booking is nil when the application is free.
+	Booking *Booking `json:"booking,omitempty"`
+}
+
+// Destination is the cluster and namespace an Application deploys to.
+type Destination struct {
+	Server    string `json:"server,omitempty"`
+	Name      string `json:"name,omitempty"`
+	Namespace string `json:"namespace,omitempty"`
+}
+
+// BookOptions holds the optional details of a booking.
+type BookOptions struct {
+	// Reason is a free-text note shown to other users.
+	Reason string
+	// Duration, when positive, makes the booking lapse after that long.
+	Duration time.Duration
+	// DefaultDuration stands in for a zero Duration on a new booking. A
+	// renewal without a Duration keeps its current expiry.
+	DefaultDuration time.Duration
+	// MaxDuration, when positive, refuses to renew a booking past booked-at
+	// plus MaxDuration, so it cannot be extended indefinitely.
+	MaxDuration time.Duration
+	// MaxHeld, when positive, refuses a new booking if the user already holds
+	// that many applications across all namespaces.
+	MaxHeld int
+	// PauseAutoSync turns automated sync off for a new booking, saving the
+	// policy in AnnotationPausedAutoSync so unbooking restores it.
+	PauseAutoSync bool
+	// DryRun runs every check and sends the patch as a server-side dry run,
+	// so the API server validates it without persisting anything.
+	DryRun bool
+}
+
+// BookResult describes what BookApp did.
+type BookResult struct {
+	// Changed is false when username already held the application and the
+	// call supplied nothing to update.
+	Changed bool
+	// Duration is the lifetime given to the booking, zero if its expiry was
+	// left unchanged or it does not lapse.
+	Duration time.Duration
+}
+
+// UnbookOptions holds optional parameters for UnbookApp.
+type UnbookOptions struct {
+	// DryRun runs every check and sends the patch as a server-side dry run.
+	DryRun bool
+}
+
+// TransferOptions holds optional parameters for TransferApp.
+type TransferOptions struct {
+	// MaxHeld, when positive, refuses the transfer if the target already
+	// holds that many applications across all namespaces.
+	MaxHeld int
+	// DryRun runs every check and sends the patch as a server-side dry run.
+	DryRun bool
 }
 
 // Client provides operations on ArgoCD Application CR annotations.
 type Client interface {
 	GetBookingStatus(ctx context.Context, namespace, appName string) (bookedBy string, bookedAt time.Time, err error)
-	BookApp(ctx context.Context, namespace, appName, username string) error
-	UnbookApp(ctx context.Context, namespace, appName, username string, isAdmin bool) error
+	BookApp(ctx context.Context, namespace, appName, username string, opts BookOptions) (BookResult, error)
+	UnbookApp(ctx context.Context, namespace, appName, username string, isAdmin bool, opts UnbookOptions) (released bool, err error)
+	TransferApp(ctx context.Context, namespace, appName, username, target string, isAdmin bool, opts TransferOptions) (previous string, err error)
 	ListBookings(ctx context.Context, namespace string) ([]Booking, error)
+	ListExpiredBookings(ctx context.Context, namespace string) ([]Booking, error)
+	ListApplications(ctx context.Context, namespace string) ([]Application, error)
+	ResumeAutoSync(ctx context.Context, namespace, appName string) error
 }
 
 type client struct {
@@ -47,102 +150,319 @@ type client struct {
 
 // NewClient creates a new K8s client using in-cluster config.
 func NewClient() (Client, error) {
+	dynClient, err := NewInClusterDynamic()
+	if err != nil {
+		return nil, err
+	}
+	return &client{dynamic: dynClient}, nil
+}
+
+// NewInClusterDynamic creates a dynamic client using in-cluster config, for
+// components that read other resources than Applications.
+func NewInClusterDynamic() (dynamic.Interface, error) {
 	config, err := rest.InClusterConfig()
 	if err != nil {
 		return nil, fmt.Errorf("failed to get in-cluster config: %w", err)
 	}
+	traceTransport(config)
 	dynClient, err := dynamic.NewForConfig(config)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create dynamic client: %w", err)
 	}
-	return &client{dynamic: dynClient}, nil
+	return dynClient, nil
 }
 
-// NewClientFromDynamic creates a client from an existing dynamic.Interface (for testing).
+// NewClientFromKubeconfig creates a K8s client from a kubeconfig file, honouring the
+// usual loading rules ($KUBECONFIG, ~/.kube/config) when kubeconfig is empty. It also
+// returns the namespace of the selected context, or "" if the context sets none.
+func NewClientFromKubeconfig(kubeconfig, kubeContext string) (Client, string, error) {
+	rules := clientcmd.NewDefaultClientConfigLoadingRules()
+	rules.ExplicitPath = kubeconfig
+	cc := clientcmd.NewNonInteractiveDeferredLoadingClientConfig(rules, &clientcmd.ConfigOverrides{CurrentContext: kubeContext})
+
+	config, err := cc.ClientConfig()
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to load kubeconfig: %w", err)
+	}
+	raw, err := cc.RawConfig()
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to resolve namespace: %w", err)
+	}
+	if kubeContext == "" {
+		kubeContext = raw.CurrentContext
+	}
+	var namespace string
+	if kc := raw.Contexts[kubeContext]; kc != nil {
+		namespace = kc.Namespace
+	}
+	dynClient, err := dynamic.NewForConfig(config)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to create dynamic client: %w", err)
+	}
+	return &client{dynamic: dynClient}, namespace, nil
+}
+
+// NewClientFromDynamic creates a client from an existing dynamic.Interface, shared
+// with other components or faked in tests.
 func NewClientFromDynamic(dynClient dynamic.Interface) Client {
 	return &client{dynamic: dynClient}
 }
 
 func (c *client) GetBookingStatus(ctx context.Context, namespace, appName string) (string, time.Time, error) {
-	app, err := c.dynamic.Resource(applicationGVR).Namespace(namespace).Get(ctx, appName, metav1.GetOptions{})
+	if err := ValidateAppRef(namespace, appName); err != nil {
+		return "", time.Time{}, err
+	}
+	app, err := c.getApp(ctx, namespace, appName)
 	if err != nil {
-		return "", time.Time{}, fmt.Errorf("failed to get application %s/%s: %w", namespace, appName, err)
+		return "", time.Time{}, err
 	}
 
-	annotations := app.GetAnnotations()
-	if annotations == nil {
+	bookedBy, bookedAt := activeBooking(app, time.Now())
+	if bookedBy == "" {
 		return "", time.Time{}, nil
 	}
+	t, _ := time.Parse(time.RFC3339, bookedAt)
+	return bookedBy, t, nil
+}
 
-	bookedBy := annotations[AnnotationBookedBy]
+// BookApp books the application for username. If username already holds it, a
+// call with a reason or duration replaces that reason or expiry, keeping the
+// rest of the booking and booked-at. The MaxHeld check scans current bookings
+// before patching, so two concurrent bookings by the same user may both slip
+// under the limit.
+func (c *client) BookApp(ctx context.Context, namespace, appName, username string, opts BookOptions) (BookResult, error) {
+	if err := ValidateUsername(username); err != nil {
+		return BookResult{}, err
+	}
+	if err := ValidateAppRef(namespace, appName); err != nil {
+		return BookResult{}, err
+	}
+	if err := ValidateBookOptions(opts); err != nil {
+		return BookResult{}, err
+	}
+	app, err := c.getApp(ctx, namespace, appName)
+	if err != nil {
+		return BookResult{}, err
+	}
+	now := time.Now().UTC()
+	bookedBy, bookedAt := activeBooking(app, now)
+	// Checking the limit first fails a queued booking straight away rather than
+	// after it has waited for the application.
+	if bookedBy != username && opts.MaxHeld > 0 {
+		if err := c.checkHeld(ctx, username, opts.MaxHeld); err != nil {
+			return BookResult{}, err
+		}
+	}
+	if bookedBy != "" && bookedBy != username {
+		return BookResult{}, fmt.Errorf("%w: application already booked by %s", ErrConflict, bookedBy)
+	}
+	if bookedBy == username && opts.Reason == "" && opts.Duration == 0 {
+		return BookResult{}, nil // already booked by the same user
+	}
+	if bookedBy == username && opts.Duration > 0 && opts.MaxDuration > 0 {
+		if err := checkRenewal(bookedAt, now, opts); err != nil {
+			return BookResult{}, err
+		}
+	}
+
+	// A renewal only replaces what the call supplies. A new booking drops a
+	// lapsed expiry or reason left by the previous holder; a null value
+	// removes the annotation.
+	annotations := map[string]interface{}{}
 	if bookedBy == "" {
-		return "", time.Time{}, nil
+		annotations[AnnotationBookedBy] = username
+		annotations[AnnotationBookedAt] = now.Format(time.RFC3339)
+		annotations[AnnotationExpiresAt] = nil
+		annotations[AnnotationReason] = nil
+	}
+	d := opts.Duration
+	if bookedBy == "" && d == 0 {
+		d = opts.DefaultDuration
+	}
+	if d > 0 {
+		annotations[AnnotationExpiresAt] = now.Add(d).Format(time.RFC3339)
+	}
+	if opts.Reason != "" {
+		annotations[AnnotationReason] = opts.Reason
+	}
+
+	// The resourceVersion precondition makes the read-check-write atomic: if another
+	// writer booked the app since we read it, the API server rejects the patch.
+	patch := map[string]interface{}{
+		"metadata": map[string]interface{}{
+			"resourceVersion": app.GetResourceVersion(),
+			"annotations":     annotations,
+		},
+	}
+	if bookedBy == "" && opts.PauseAutoSync {
+		if err := pauseAutoSync(app, patch); err != nil {
+			return BookResult{}, err
+		}
+	}
+	patchBytes, err := json.Marshal(patch)
+	if err != nil {
+		return BookResult{}, fmt.Errorf("failed to marshal patch: %w", err)
 	}
 
-	bookedAt, _ := time.Parse(time.RFC3339, annotations[AnnotationBookedAt])
-	return bookedBy, bookedAt, nil
+	err = c.patchApp(ctx, namespace, appName, patchBytes, opts.DryRun)
+	if apierrors.IsConflict(err) {
+		return BookResult{}, fmt.Errorf("%w: application %s/%s was modified concurrently, retry", ErrConflict, namespace, appName)
+	}
+	if err != nil {
+		return BookResult{}, fmt.Errorf("failed to patch application %s/%s: %w", namespace, appName, err)
+	}
+	return BookResult{Changed: true, Duration: d}, nil
 }
 
-func (c *client) BookApp(ctx context.Context, namespace, appName, username string) error {
-	bookedBy, _, err := c.GetBookingStatus(ctx, namespace, appName)
+// checkRenewal returns an ErrLimit error if renewing a booking made at
+// bookedAt for opts.Duration would end it later than opts.MaxDuration after
+// bookedAt.
+func checkRenewal(bookedAt string, now time.Time, opts BookOptions) error {
+	start, err := time.Parse(time.RFC3339, bookedAt)
 	if err != nil {
-		return err
+		return nil // no usable booked-at to measure from
 	}
-	if bookedBy != "" && bookedBy != username {
-		return fmt.Errorf("conflict: application already booked by %s", bookedBy)
+	end := start.Add(opts.MaxDuration)
+	if !now.Add(opts.Duration).After(end) {
+		return nil
 	}
-	if bookedBy == username {
-		return nil // already booked by the same user
+	return fmt.Errorf("%w: the booking started at %s and may last at most %s, until %s",
+		ErrLimit, start.UTC().Format(time.RFC3339), opts.MaxDuration, end.UTC().Format(time.RFC3339))
+}
+
+// checkHeld returns an ErrLimit error listing the applications username holds
+// if there are max or more of them.
+func (c *client) checkHeld(ctx context.Context, username string, max int) error {
+	list, err := c.listApps(ctx, metav1.NamespaceAll)
+	if err != nil {
+		return fmt.Errorf("failed to list applications: %w", err)
 	}
+	now := time.Now()
+	var held []string
+	for i := range list.Items {
+		if bookedBy, _ := activeBooking(&list.Items[i], now); bookedBy == username {
+			held = append(held, list.Items[i].GetNamespace()+"/"+list.Items[i].GetName())
+		}
+	}
+	if len(held) < max {
+		return nil
+	}
+	sort.Strings(held)
+	return fmt.Errorf("%w: %s already holds %d of at most %d applications (%s), unbook one first",
+		ErrLimit, username, len(held), max, strings.Join(held, ", "))
+}
 
-	now := time.Now().UTC().Format(time.RFC3339)
+// UnbookApp releases the application, restoring the automated sync policy
+// the booking paused, if any. released is false if it was not booked.
+func (c *client) UnbookApp(ctx context.Context, namespace, appName, username string, isAdmin bool, opts UnbookOptions) (bool, error) {
+	if err := ValidateUsername(username); err != nil {
+		return false, err
+	}
+	if err := ValidateAppRef(namespace, appName); err != nil {
+		return false, err
+	}
+	app, err := c.getApp(ctx, namespace, appName)
+	if err != nil {
+		return false, err
+	}
+	bookedBy, _ := activeBooking(app, time.Now())
+	if bookedBy == "" {
+		return false, nil // not booked
+	}
+	if bookedBy != username && !isAdmin {
+		return false, fmt.Errorf("%w: application is booked by %s, only they or an admin can unbook", ErrForbidden, bookedBy)
+	}
+
+	// Remove annotations by setting them to null via JSON merge patch. The
+	// resourceVersion precondition keeps a booking made since the read.
 	patch := map[string]interface{}{
 		"metadata": map[string]interface{}{
-			"annotations": map[string]string{
-				AnnotationBookedBy: username,
-				AnnotationBookedAt: now,
+			"resourceVersion": app.GetResourceVersion(),
+			"annotations": map[string]interface{}{
+				AnnotationBookedBy:  nil,
+				AnnotationBookedAt:  nil,
+				AnnotationExpiresAt: nil,
+				AnnotationReason:    nil,
 			},
 		},
 	}
+	restoreAutoSync(app, patch)
 	patchBytes, err := json.Marshal(patch)
 	if err != nil {
-		return fmt.Errorf("failed to marshal patch: %w", err)
+		return false, fmt.Errorf("failed to marshal patch: %w", err)
 	}
 
-	_, err = c.dynamic.Resource(applicationGVR).Namespace(namespace).Patch(
-		ctx, appName, types.MergePatchType, patchBytes, metav1.PatchOptions{},
-	)
+	err = c.patchApp(ctx, namespace, appName, patchBytes, opts.DryRun)
+	if apierrors.IsConflict(err) {
+		return false, fmt.Errorf("%w: application %s/%s was modified concurrently, retry", ErrConflict, namespace, appName)
+	}
 	if err != nil {
-		return fmt.Errorf("failed to patch application %s/%s: %w", namespace, appName, err)
+		return false, fmt.Errorf("failed to patch application %s/%s: %w", namespace, appName, err)
 	}
-	return nil
+	return true, nil
 }
 
-func (c *client) UnbookApp(ctx context.Context, namespace, appName, username string, isAdmin bool) error {
-	bookedBy, _, err := c.GetBookingStatus(ctx, namespace, appName)
+// TransferApp hands the application from its holder to target and returns the
+// previous holder, which equals target if target already held it and nothing
+// changed.
+func (c *client) TransferApp(ctx context.Context, namespace, appName, username, target string, isAdmin bool, opts TransferOptions) (string, error) {
+	if err := ValidateUsername(username); err != nil {
+		return "", err
+	}
+	if err := ValidateUsername(target); err != nil {
+		return "", err
+	}
+	if err := ValidateAppRef(namespace, appName); err != nil {
+		return "", err
+	}
+	app, err := c.getApp(ctx, namespace, appName)
 	if err != nil {
-		return err
+		return "", err
 	}
+	bookedBy, _ := activeBooking(app, time.Now())
 	if bookedBy == "" {
-		return nil // not booked
+		return "", fmt.Errorf("%w: application is not booked", ErrConflict)
 	}
 	if bookedBy != username && !isAdmin {
-		return fmt.Errorf("forbidden: application is booked by %s, only they or an admin can unbook", bookedBy)
+		return "", fmt.Errorf("%w: application is booked by %s, only they or an admin can transfer it", ErrForbidden, bookedBy)
+	}
+	if bookedBy == target {
+		return bookedBy, nil // already held by the target
+	}
+	if opts.MaxHeld > 0 {
+		if err := c.checkHeld(ctx, target, opts.MaxHeld); err != nil {
+			return "", err
+		}
 	}
 
-	// Remove annotations by setting them to null via JSON merge patch
-	patch := []byte(`{"metadata":{"annotations":{"` + AnnotationBookedBy + `":null,"` + AnnotationBookedAt + `":null}}}`)
-	_, err = c.dynamic.Resource(applicationGVR).Namespace(namespace).Patch(
-		ctx, appName, types.MergePatchType, patch, metav1.PatchOptions{},
-	)
+	now := time.Now().UTC().Format(time.RFC3339)
+	patch := map[string]interface{}{
+		"metadata": map[string]interface{}{
+			"resourceVersion": app.GetResourceVersion(),
+			"annotations": map[string]interface{}{
+				AnnotationBookedBy: target,
+				AnnotationBookedAt: now,
+				AnnotationReason:   nil, // the reason was the previous holder's
+			},
+		},
+	}
+	patchBytes, err := json.Marshal(patch)
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal patch: %w", err)
+	}
+
+	err = c.patchApp(ctx, namespace, appName, patchBytes, opts.DryRun)
+	if apierrors.IsConflict(err) {
+		return "", fmt.Errorf("%w: application %s/%s was modified concurrently, retry", ErrConflict, namespace, appName)
+	}
 	if err != nil {
-		return fmt.Errorf("failed to patch application %s/%s: %w", namespace, appName, err)
+		return "", fmt.Errorf("failed to patch application %s/%s: %w", namespace, appName, err)
 	}
-	return nil
+	return bookedBy, nil
 }
 
 func (c *client) ListBookings(ctx context.Context, namespace string) ([]Booking, error) {
-	list, err := c.dynamic.Resource(applicationGVR).Namespace(namespace).List(ctx, metav1.ListOptions{})
+	list, err := c.listApps(ctx, namespace)
 	if err != nil {
 		return nil, fmt.Errorf("failed to list applications in %s: %w", namespace, err)
 	}
@@ -157,19 +477,173 @@ func (c *client) ListBookings(ctx context.Context, namespace string) ([]Booking,
 	return bookings, nil
 }
 
-func extractBooking(app *unstructured.Unstructured) *Booking {
+// ListExpiredBookings returns the bookings in namespace whose expires-at has
+// passed while their annotations are still set. An empty namespace lists all
+// namespaces.
+func (c *client) ListExpiredBookings(ctx context.Context, namespace string) ([]Booking, error) {
+	list, err := c.listApps(ctx, namespace)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list applications in %s: %w", namespace, err)
+	}
+
+	now := time.Now()
+	var bookings []Booking
+	for i := range list.Items {
+		app := &list.Items[i]
+		if app.GetAnnotations()[AnnotationBookedBy] == "" {
+			continue
+		}
+		if bookedBy, _ := activeBooking(app, now); bookedBy == "" {
+			bookings = append(bookings, *bookingOf(app))
+		}
+	}
+	return bookings, nil
+}
+
+// ListApplications returns every Application in namespace, booked or not. An
+// empty namespace lists all namespaces.
+func (c *client) ListApplications(ctx context.Context, namespace string) ([]Application, error) {
+	list, err := c.listApps(ctx, namespace)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list applications in %s: %w", namespace, err)
+	}
+
+	apps := make([]Application, 0, len(list.Items))
+	for _, item := range list.Items {
+		apps = append(apps, extractApplication(&item))
+	}
+	return apps, nil
+}
+
+// getApp, patchApp and listApps are the only Application API calls, each traced
+// with its own span.
+
+func (c *client) getApp(ctx context.Context, namespace, appName string) (*unstructured.Unstructured, error) {
+	ctx, span := startSpan(ctx, "get application", namespace, appName)
+	app, err := c.dynamic.Resource(applicationGVR).Namespace(namespace).Get(ctx, appName, metav1.GetOptions{})
+	endSpan(span, err)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get application %s/%s: %w", namespace, appName, err)
+	}
+	return app, nil
+}
+
+// patchApp applies a JSON merge patch to the application, as a server-side
+// dry run when dryRun is set. The error is the API server's, unwrapped, so
+// callers can inspect it.
+func (c *client) patchApp(ctx context.Context, namespace, appName string, patch []byte, dryRun bool) error {
+	ctx, span := startSpan(ctx, "patch application", namespace, appName)
+	var opts metav1.PatchOptions
+	if dryRun {
+		opts.DryRun = []string{metav1.DryRunAll}
+		span.SetAttributes(attribute.Bool("k8s.dry_run", true))
+	}
+	_, err := c.dynamic.Resource(applicationGVR).Namespace(namespace).Patch(
+		ctx, appName, types.MergePatchType, patch, opts,
+	)
+	endSpan(span, err)
+	return err
+}
+
+func (c *client) listApps(ctx context.Context, namespace string) (*unstructured.UnstructuredList, error) {
+	ctx, span := startSpan(ctx, "list applications", namespace, "")
+	list, err := c.dynamic.Resource(applicationGVR).Namespace(namespace).List(ctx, metav1.ListOptions{})
+	endSpan(span, err)
+	return list, err
+}
+
+// activeBooking returns the holder and booked-at annotation of app. A booking whose
+// expires-at time has passed is treated as released and yields empty strings.
+func activeBooking(app *unstructured.Unstructured, now time.Time) (bookedBy, bookedAt string) {
 	annotations := app.GetAnnotations()
-	if annotations == nil {
-		return nil
+	if annotations == nil || annotations[AnnotationBookedBy] == "" {
+		return "", ""
 	}
-	bookedBy := annotations[AnnotationBookedBy]
-	if bookedBy == "" {
+	if v := annotations[AnnotationExpiresAt]; v != "" {
+		if expiresAt, err := time.Parse(time.RFC3339, v); err == nil && !now.Before(expiresAt) {
+			return "", ""
+		}
+	}
+	return annotations[AnnotationBookedBy], annotations[AnnotationBookedAt]
+}
+
+func extractBooking(app *unstructured.Unstructured) *Booking {
+	if bookedBy, _ := activeBooking(app, time.Now()); bookedBy == "" {
 		return nil
 	}
+	return bookingOf(app)
+}
+
+// bookingOf returns the booking recorded in the annotations of app, whether or
+// not it has expired.
+func bookingOf(app *unstructured.Unstructured) *Booking {
+	annotations := app.GetAnnotations()
+	project, _, _ := unstructured.NestedString(app.Object, "spec", "project")
 	return &Booking{
 		AppName:   app.GetName(),
 		Namespace: app.GetNamespace(),
-		BookedBy:  bookedBy,
+		Project:   project,
+		BookedBy:  annotations[AnnotationBookedBy],
 		BookedAt:  annotations[AnnotationBookedAt],
+		ExpiresAt: annotations[AnnotationExpiresAt],
+		Reason:    annotations[AnnotationReason],
+		Labels:    app.GetLabels(),
+	}
+}
+
+func extractApplication(app *unstructured.Unstructured) Application {
+	str := func(fields ...string) string {
+		v, _, _ := unstructured.NestedString(app.Object, fields...)
+		return v
+	}
+	_, paused := app.GetAnnotations()[AnnotationPausedAutoSync]
+	return Application{
+		Name:      app.GetName(),
+		Namespace: app.GetNamespace(),
+		Project:   str("spec", "project"),
+		Labels:    app.GetLabels(),
+		Destination: Destination{
+			Server:    str("spec", "destination", "server"),
+			Name:      str("spec", "destination", "name"),
+			Namespace: str("spec", "destination", "namespace"),
+		},
+		SyncStatus:     str("status", "sync", "status"),
+		HealthStatus:   str("status", "health", "status"),
+		LastSyncAt:     lastSync(app),
+		AutoSyncPaused: paused,
+		Booking:        extractBooking(app),
+	}
+}
+
+// lastSync returns the start of the latest sync of app recorded in
+// status.operationState or status.history, skipping syncs initiated by
+// automated sync, or "" if there is none.
+func lastSync(app *unstructured.Unstructured) string {
+	var latest time.Time
+	consider := func(entry map[string]interface{}, field string) {
+		v, _, _ := unstructured.NestedString(entry, field)
+		if t, err := time.Parse(time.RFC3339, v); err == nil && t.After(latest) {
+			latest = t
+		}
+	}
+	automated := func(entry map[string]interface{}, fields ...string) bool {
+		v, _, _ := unstructured.NestedBool(entry, fields...)
+		return v
+	}
+
+	if op, found, _ := unstructured.NestedMap(app.Object, "status", "operationState"); found &&
+		!automated(op, "operation", "initiatedBy", "automated") {
+		consider(op, "startedAt")
+	}
+	history, _, _ := unstructured.NestedSlice(app.Object, "status", "history")
+	for _, h := range history {
+		if entry, ok := h.(map[string]interface{}); ok && !automated(entry, "initiatedBy", "automated") {
+			consider(entry, "deployStartedAt")
+			consider(entry, "deployedAt")
+		}
+	}
+	if latest.IsZero() {
+		return ""
 	}
+	return latest.UTC().Format(time.RFC3339)
 }
@@ -2,12 +2,22 @@ package k8s
 
 import (
 	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
 	"testing"
+	"time"
 
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/dynamic"
 	dynamicfake "k8s.io/client-go/dynamic/fake"
+	k8stesting "k8s.io/client-go/testing"
 )
 
 func newFakeApp(namespace, name string, annotations map[string]string) *unstructured.Unstructured {
@@ -25,15 +35,17 @@ func newFakeApp(namespace, name string, annotations map[string]string) *unstruct
 	return app
 }
 
-func newFakeClient(objects ...runtime.Object) Client {
-	scheme := runtime.NewScheme()
-	fakeDyn := dynamicfake.NewSimpleDynamicClientWithCustomListKinds(scheme,
+func newFakeDynamic(objects ...runtime.Object) *dynamicfake.FakeDynamicClient {
+	return dynamicfake.NewSimpleDynamicClientWithCustomListKinds(runtime.NewScheme(),
 		map[schema.GroupVersionResource]string{
 			applicationGVR: "ApplicationList",
 		},
 		objects...,
 	)
-	return NewClientFromDynamic(fakeDyn)
+}
+
+func newFakeClient(objects ...runtime.Object) Client {
+	return NewClientFromDynamic(newFakeDynamic(objects...))
 }
 
 func TestGetBookingStatus_NotBooked(t *testing.T) {
@@ -81,7 +93,7 @@ func TestBookApp_Success(t *testing.T) {
 	app := newFakeApp("argocd", "my-app", nil)
 	c := newFakeClient(app)
 
-	err := c.BookApp(context.Background(), "argocd", "my-app", "alice")
+	_, err := c.BookApp(context.Background(), "argocd", "my-app", "alice", BookOptions{})
 	if err != nil {
 		t.Fatalf("unexpected error: %v", err)
 	}
@@ -108,7 +120,7 @@ func TestBookApp_AlreadyBookedBySameUser(t *testing.T) {
 	})
 	c := newFakeClient(app)
 
-	err := c.BookApp(context.Background(), "argocd", "my-app", "alice")
+	_, err := c.BookApp(context.Background(), "argocd", "my-app", "alice", BookOptions{})
 	if err != nil {
 		t.Fatalf("unexpected error: %v", err)
 	}
@@ -121,7 +133,7 @@ func TestBookApp_Conflict(t *testing.T) {
 	})
 	c := newFakeClient(app)
 
-	err := c.BookApp(context.Background(), "argocd", "my-app", "bob")
+	_, err := c.BookApp(context.Background(), "argocd", "my-app", "bob", BookOptions{})
 	if err == nil {
 		t.Fatal("expected conflict error")
 	}
@@ -134,10 +146,19 @@ func TestUnbookApp_ByBooker(t *testing.T) {
 	})
 	c := newFakeClient(app)
 
-	err := c.UnbookApp(context.Background(), "argocd", "my-app", "alice", false)
+	released, err := c.UnbookApp(context.Background(), "argocd", "my-app", "alice", false, UnbookOptions{})
 	if err != nil {
 		t.Fatalf("unexpected error: %v", err)
 	}
+	if !released {
+		t.Fatal("expected the booking to be released")
+	}
+
+	// Unbooking a free application is a no-op.
+	released, err = c.UnbookApp(context.Background(), "argocd", "my-app", "alice", false, UnbookOptions{})
+	if err != nil || released {
+		t.Fatalf("expected nothing released, got %v, %v", released, err)
+	}
 }
 
 func TestUnbookApp_ByOtherUser_Forbidden(t *testing.T) {
@@ -147,7 +168,7 @@ func TestUnbookApp_ByOtherUser_Forbidden(t *testing.T) {
 	})
 	c := newFakeClient(app)
 
-	err := c.UnbookApp(context.Background(), "argocd", "my-app", "bob", false)
+	_, err := c.UnbookApp(context.Background(), "argocd", "my-app", "bob", false, UnbookOptions{})
 	if err == nil {
 		t.Fatal("expected forbidden error")
 	}
@@ -160,12 +181,35 @@ func TestUnbookApp_ByAdmin(t *testing.T) {
 	})
 	c := newFakeClient(app)
 
-	err := c.UnbookApp(context.Background(), "argocd", "my-app", "bob", true)
+	_, err := c.UnbookApp(context.Background(), "argocd", "my-app", "bob", true, UnbookOptions{})
 	if err != nil {
 		t.Fatalf("unexpected error: %v", err)
 	}
 }
 
+func TestUnbookApp_ConcurrentModification(t *testing.T) {
+	app := newFakeApp("argocd", "my-app", map[string]string{
+		AnnotationBookedBy: "alice",
+		AnnotationBookedAt: "2026-01-15T10:00:00Z",
+	})
+	c := newFakeClient(app)
+
+	var patch []byte
+	dynClient := c.(*client).dynamic.(*dynamicfake.FakeDynamicClient)
+	dynClient.PrependReactor("patch", "applications", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		patch = action.(k8stesting.PatchAction).GetPatch()
+		return true, nil, apierrors.NewConflict(applicationGVR.GroupResource(), "my-app", nil)
+	})
+
+	_, err := c.UnbookApp(context.Background(), "argocd", "my-app", "alice", false, UnbookOptions{})
+	if !errors.Is(err, ErrConflict) {
+		t.Fatalf("expected conflict error, got %v", err)
+	}
+	if !strings.Contains(string(patch), `"resourceVersion"`) {
+		t.Fatalf("expected the patch to carry a resourceVersion precondition, got %s", patch)
+	}
+}
+
 func TestListBookings(t *testing.T) {
 	app1 := newFakeApp("argocd", "app1", map[string]string{
 		AnnotationBookedBy: "alice",
@@ -187,3 +231,466 @@ func TestListBookings(t *testing.T) {
 	}
 }
 
+func TestListExpiredBookings(t *testing.T) {
+	booked := func(name, expiresAt string) *unstructured.Unstructured {
+		return newFakeApp("argocd", name, map[string]string{
+			AnnotationBookedBy:  "alice",
+			AnnotationBookedAt:  "2026-01-15T10:00:00Z",
+			AnnotationExpiresAt: expiresAt,
+		})
+	}
+	c := newFakeClient(booked("lapsed", "2026-01-15T12:00:00Z"), booked("active", "2099-01-01T00:00:00Z"),
+		booked("open-ended", ""), newFakeApp("argocd", "free", nil))
+
+	bookings, err := c.ListExpiredBookings(context.Background(), "argocd")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(bookings) != 1 || bookings[0].AppName != "lapsed" || bookings[0].BookedBy != "alice" || bookings[0].ExpiresAt != "2026-01-15T12:00:00Z" {
+		t.Fatalf("expected only the lapsed booking, got %+v", bookings)
+	}
+}
+
+func TestListBookings_IncludesProjectExpiryAndLabels(t *testing.T) {
+	app := newFakeApp("argocd", "app1", map[string]string{
+		AnnotationBookedBy:  "alice",
+		AnnotationBookedAt:  "2026-01-15T10:00:00Z",
+		AnnotationExpiresAt: "2099-01-15T12:00:00Z",
+	})
+	unstructured.SetNestedField(app.Object, "staging", "spec", "project")
+	app.SetLabels(map[string]string{"team": "payments"})
+	c := newFakeClient(app)
+
+	bookings, err := c.ListBookings(context.Background(), "argocd")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(bookings) != 1 {
+		t.Fatalf("expected 1 booking, got %d", len(bookings))
+	}
+	if bookings[0].Project != "staging" || bookings[0].ExpiresAt != "2099-01-15T12:00:00Z" || bookings[0].Labels["team"] != "payments" {
+		t.Fatalf("unexpected booking: %+v", bookings[0])
+	}
+}
+
+func TestBookApp_MaxDurationCapsRenewals(t *testing.T) {
+	bookedAt := time.Now().UTC().Add(-90 * time.Minute).Format(time.RFC3339)
+	c := newFakeClient(newFakeApp("argocd", "my-app", map[string]string{
+		AnnotationBookedBy: "alice",
+		AnnotationBookedAt: bookedAt,
+	}))
+	ctx := context.Background()
+
+	if _, err := c.BookApp(ctx, "argocd", "my-app", "alice", BookOptions{Duration: time.Hour, MaxDuration: 2 * time.Hour}); !errors.Is(err, ErrLimit) {
+		t.Fatalf("expected a limit error, got %v", err)
+	}
+	// Without a duration the renewal leaves the expiry alone, so the cap and
+	// the default do not apply.
+	for _, opts := range []BookOptions{
+		{DefaultDuration: time.Hour, MaxDuration: 2 * time.Hour},
+		{DefaultDuration: time.Hour, MaxDuration: 2 * time.Hour, Reason: "still testing"},
+	} {
+		res, err := c.BookApp(ctx, "argocd", "my-app", "alice", opts)
+		if err != nil {
+			t.Fatalf("%+v: unexpected error: %v", opts, err)
+		}
+		if res.Duration != 0 {
+			t.Fatalf("%+v: expected no new expiry, got %s", opts, res.Duration)
+		}
+	}
+	bookings, _ := c.ListBookings(ctx, "argocd")
+	if len(bookings) != 1 || bookings[0].ExpiresAt != "" || bookings[0].Reason != "still testing" {
+		t.Fatalf("expected the reason set and no expiry, got %+v", bookings)
+	}
+	if _, err := c.BookApp(ctx, "argocd", "my-app", "alice", BookOptions{Duration: 20 * time.Minute, MaxDuration: 2 * time.Hour}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	bookings, _ = c.ListBookings(ctx, "argocd")
+	if len(bookings) != 1 || bookings[0].BookedAt != bookedAt || bookings[0].ExpiresAt == "" {
+		t.Fatalf("expected the renewal to keep booked-at and set an expiry, got %+v", bookings)
+	}
+}
+
+func TestTransferApp_ByHolder(t *testing.T) {
+	app := newFakeApp("argocd", "my-app", map[string]string{
+		AnnotationBookedBy: "alice",
+		AnnotationBookedAt: "2026-01-15T10:00:00Z",
+	})
+	c := newFakeClient(app)
+
+	previous, err := c.TransferApp(context.Background(), "argocd", "my-app", "alice", "bob", false, TransferOptions{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if previous != "alice" {
+		t.Fatalf("expected previous holder alice, got %q", previous)
+	}
+	bookedBy, _, _ := c.GetBookingStatus(context.Background(), "argocd", "my-app")
+	if bookedBy != "bob" {
+		t.Fatalf("expected bookedBy=bob, got %q", bookedBy)
+	}
+}
+
+func TestTransferApp_ByOtherUser_Forbidden(t *testing.T) {
+	app := newFakeApp("argocd", "my-app", map[string]string{
+		AnnotationBookedBy: "alice",
+		AnnotationBookedAt: "2026-01-15T10:00:00Z",
+	})
+	c := newFakeClient(app)
+
+	if _, err := c.TransferApp(context.Background(), "argocd", "my-app", "carol", "bob", false, TransferOptions{}); err == nil {
+		t.Fatal("expected forbidden error")
+	}
+	if _, err := c.TransferApp(context.Background(), "argocd", "my-app", "carol", "bob", true, TransferOptions{}); err != nil {
+		t.Fatalf("expected admin transfer to succeed, got %v", err)
+	}
+}
+
+func TestTransferApp_NotBooked(t *testing.T) {
+	app := newFakeApp("argocd", "my-app", nil)
+	c := newFakeClient(app)
+
+	if _, err := c.TransferApp(context.Background(), "argocd", "my-app", "alice", "bob", false, TransferOptions{}); err == nil {
+		t.Fatal("expected conflict error for unbooked app")
+	}
+}
+
+func TestTransferApp_MaxHeld(t *testing.T) {
+	held := func(name, user string) *unstructured.Unstructured {
+		return newFakeApp("argocd", name, map[string]string{
+			AnnotationBookedBy: user,
+			AnnotationBookedAt: "2026-01-15T10:00:00Z",
+		})
+	}
+	c := newFakeClient(held("a", "alice"), held("b", "bob"))
+	ctx := context.Background()
+
+	_, err := c.TransferApp(ctx, "argocd", "a", "alice", "bob", false, TransferOptions{MaxHeld: 1})
+	if !errors.Is(err, ErrLimit) {
+		t.Fatalf("expected a limit error, got %v", err)
+	}
+	if _, err := c.TransferApp(ctx, "argocd", "a", "alice", "bob", false, TransferOptions{MaxHeld: 2}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
+
+func TestTransferApp_ConcurrentModification(t *testing.T) {
+	app := newFakeApp("argocd", "my-app", map[string]string{
+		AnnotationBookedBy: "alice",
+		AnnotationBookedAt: "2026-01-15T10:00:00Z",
+	})
+	c := newFakeClient(app)
+
+	var patch []byte
+	dynClient := c.(*client).dynamic.(*dynamicfake.FakeDynamicClient)
+	dynClient.PrependReactor("patch", "applications", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		patch = action.(k8stesting.PatchAction).GetPatch()
+		return true, nil, apierrors.NewConflict(applicationGVR.GroupResource(), "my-app", nil)
+	})
+
+	_, err := c.TransferApp(context.Background(), "argocd", "my-app", "alice", "bob", false, TransferOptions{})
+	if !errors.Is(err, ErrConflict) {
+		t.Fatalf("expected conflict error, got %v", err)
+	}
+	if !strings.Contains(string(patch), `"resourceVersion"`) {
+		t.Fatalf("expected the patch to carry a resourceVersion precondition, got %s", patch)
+	}
+}
+
+func TestBookApp_ExpiredBookingIsFree(t *testing.T) {
+	app := newFakeApp("argocd", "my-app", map[string]string{
+		AnnotationBookedBy:  "alice",
+		AnnotationBookedAt:  "2026-01-15T10:00:00Z",
+		AnnotationExpiresAt: "2026-01-15T12:00:00Z",
+	})
+	c := newFakeClient(app)
+
+	bookedBy, _, err := c.GetBookingStatus(context.Background(), "argocd", "my-app")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if bookedBy != "" {
+		t.Fatalf("expected expired booking to read as free, got %q", bookedBy)
+	}
+	if _, err := c.BookApp(context.Background(), "argocd", "my-app", "bob", BookOptions{}); err != nil {
+		t.Fatalf("expected bob to book the expired app, got %v", err)
+	}
+	bookedBy, _, _ = c.GetBookingStatus(context.Background(), "argocd", "my-app")
+	if bookedBy != "bob" {
+		t.Fatalf("expected bookedBy=bob, got %q", bookedBy)
+	}
+}
+
+func TestBookApp_ConcurrentModification(t *testing.T) {
+	app := newFakeApp("argocd", "my-app", nil)
+	c := newFakeClient(app)
+
+	dynClient := c.(*client).dynamic.(*dynamicfake.FakeDynamicClient)
+	dynClient.PrependReactor("patch", "applications", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		return true, nil, apierrors.NewConflict(applicationGVR.GroupResource(), "my-app", nil)
+	})
+
+	_, err := c.BookApp(context.Background(), "argocd", "my-app", "alice", BookOptions{})
+	if !errors.Is(err, ErrConflict) {
+		t.Fatalf("expected conflict error, got %v", err)
+	}
+}
+
+func TestBookApp_ReasonAndDuration(t *testing.T) {
+	c := newFakeClient(newFakeApp("argocd", "my-app", nil))
+
+	opts := BookOptions{Reason: "load test", Duration: 2 * time.Hour}
+	if _, err := c.BookApp(context.Background(), "argocd", "my-app", "alice", opts); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	bookings, err := c.ListBookings(context.Background(), "argocd")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(bookings) != 1 || bookings[0].Reason != "load test" {
+		t.Fatalf("expected a booking with a reason, got %+v", bookings)
+	}
+	expiresAt, err := time.Parse(time.RFC3339, bookings[0].ExpiresAt)
+	if err != nil {
+		t.Fatalf("expected an expiry, got %q", bookings[0].ExpiresAt)
+	}
+	if d := time.Until(expiresAt); d < 119*time.Minute || d > 2*time.Hour {
+		t.Fatalf("expected expiry about 2h from now, got %s", d)
+	}
+}
+
+func TestBookApp_DefaultDuration(t *testing.T) {
+	c := newFakeClient(newFakeApp("argocd", "my-app", nil))
+
+	res, err := c.BookApp(context.Background(), "argocd", "my-app", "alice", BookOptions{DefaultDuration: time.Hour})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if res.Duration != time.Hour {
+		t.Fatalf("expected the default duration, got %s", res.Duration)
+	}
+	bookings, _ := c.ListBookings(context.Background(), "argocd")
+	if len(bookings) != 1 || bookings[0].ExpiresAt == "" {
+		t.Fatalf("expected a booking with an expiry, got %+v", bookings)
+	}
+}
+
+func TestBookApp_SameUserUpdatesDetails(t *testing.T) {
+	c := newFakeClient(newFakeApp("argocd", "my-app", map[string]string{
+		AnnotationBookedBy:  "alice",
+		AnnotationBookedAt:  "2026-01-15T10:00:00Z",
+		AnnotationExpiresAt: "2099-01-01T00:00:00Z",
+	}))
+
+	if _, err := c.BookApp(context.Background(), "argocd", "my-app", "alice", BookOptions{Reason: "extended"}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	bookings, _ := c.ListBookings(context.Background(), "argocd")
+	if len(bookings) != 1 {
+		t.Fatalf("expected 1 booking, got %d", len(bookings))
+	}
+	b := bookings[0]
+	if b.BookedAt != "2026-01-15T10:00:00Z" || b.Reason != "extended" || b.ExpiresAt != "2099-01-01T00:00:00Z" {
+		t.Fatalf("expected booked-at and expiry kept and the reason set, got %+v", b)
+	}
+
+	// A duration alone replaces the expiry and keeps the reason.
+	if _, err := c.BookApp(context.Background(), "argocd", "my-app", "alice", BookOptions{Duration: time.Hour}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	bookings, _ = c.ListBookings(context.Background(), "argocd")
+	if b := bookings[0]; b.Reason != "extended" || b.ExpiresAt == "2099-01-01T00:00:00Z" {
+		t.Fatalf("expected the reason kept and the expiry replaced, got %+v", b)
+	}
+}
+
+func TestBookApp_MaxHeld(t *testing.T) {
+	held := func(ns, name string) *unstructured.Unstructured {
+		return newFakeApp(ns, name, map[string]string{
+			AnnotationBookedBy: "alice",
+			AnnotationBookedAt: "2026-01-15T10:00:00Z",
+		})
+	}
+	lapsed := newFakeApp("argocd", "lapsed", map[string]string{
+		AnnotationBookedBy:  "alice",
+		AnnotationBookedAt:  "2026-01-15T10:00:00Z",
+		AnnotationExpiresAt: "2026-01-15T12:00:00Z",
+	})
+	c := newFakeClient(held("argocd", "one"), held("team-a", "two"), lapsed, newFakeApp("argocd", "free", nil))
+	ctx := context.Background()
+
+	_, err := c.BookApp(ctx, "argocd", "free", "alice", BookOptions{MaxHeld: 2})
+	if !errors.Is(err, ErrLimit) {
+		t.Fatalf("expected a limit error, got %v", err)
+	}
+	want := "booking limit reached: alice already holds 2 of at most 2 applications (argocd/one, team-a/two), unbook one first"
+	if err.Error() != want {
+		t.Fatalf("unexpected message %q", err)
+	}
+	// Renewing an application already held does not count against the limit.
+	if _, err := c.BookApp(ctx, "argocd", "one", "alice", BookOptions{MaxHeld: 2, Reason: "renewed"}); err != nil {
+		t.Fatalf("expected alice to renew a held application, got %v", err)
+	}
+	if _, err := c.BookApp(ctx, "argocd", "free", "alice", BookOptions{MaxHeld: 3}); err != nil {
+		t.Fatalf("expected the lapsed booking not to count, got %v", err)
+	}
+	if _, err := c.BookApp(ctx, "argocd", "lapsed", "bob", BookOptions{MaxHeld: 1}); err != nil {
+		t.Fatalf("expected bob to be under the limit, got %v", err)
+	}
+}
+
+func TestListApplications_IncludesFreeApps(t *testing.T) {
+	booked := newFakeApp("argocd", "booked", map[string]string{
+		AnnotationBookedBy: "alice",
+		AnnotationBookedAt: "2026-01-15T10:00:00Z",
+	})
+	free := newFakeApp("argocd", "free", nil)
+	unstructured.SetNestedField(free.Object, "staging", "spec", "project")
+	unstructured.SetNestedField(free.Object, "https://kubernetes.default.svc", "spec", "destination", "server")
+	unstructured.SetNestedField(free.Object, "qa-1", "spec", "destination", "namespace")
+	unstructured.SetNestedField(free.Object, "Synced", "status", "sync", "status")
+	unstructured.SetNestedField(free.Object, "Healthy", "status", "health", "status")
+	c := newFakeClient(booked, free)
+
+	apps, err := c.ListApplications(context.Background(), "argocd")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(apps) != 2 {
+		t.Fatalf("expected 2 applications, got %d", len(apps))
+	}
+	byName := map[string]Application{}
+	for _, a := range apps {
+		byName[a.Name] = a
+	}
+	if b := byName["booked"].Booking; b == nil || b.BookedBy != "alice" {
+		t.Fatalf("expected booked app to carry its booking, got %+v", byName["booked"])
+	}
+	f := byName["free"]
+	if f.Booking != nil || f.Project != "staging" || f.Destination.Namespace != "qa-1" ||
+		f.Destination.Server != "https://kubernetes.default.svc" || f.SyncStatus != "Synced" || f.HealthStatus != "Healthy" {
+		t.Fatalf("unexpected free app: %+v", f)
+	}
+}
+
+func TestLastSync(t *testing.T) {
+	app := newFakeApp("argocd", "my-app", nil)
+	if got := lastSync(app); got != "" {
+		t.Fatalf("expected no sync on record, got %q", got)
+	}
+
+	unstructured.SetNestedSlice(app.Object, []interface{}{
+		map[string]interface{}{"deployStartedAt": "2026-01-15T09:00:00Z", "deployedAt": "2026-01-15T09:01:00Z"},
+		map[string]interface{}{"deployedAt": "2026-01-15T11:00:00Z", "initiatedBy": map[string]interface{}{"automated": true}},
+	}, "status", "history")
+	if got := lastSync(app); got != "2026-01-15T09:01:00Z" {
+		t.Fatalf("expected the latest manual history entry, got %q", got)
+	}
+
+	unstructured.SetNestedMap(app.Object, map[string]interface{}{
+		"startedAt": "2026-01-15T10:00:00Z",
+		"operation": map[string]interface{}{"initiatedBy": map[string]interface{}{"username": "alice"}},
+	}, "status", "operationState")
+	if got := lastSync(app); got != "2026-01-15T10:00:00Z" {
+		t.Fatalf("expected the running operation, got %q", got)
+	}
+
+	unstructured.SetNestedField(app.Object, true, "status", "operationState", "operation", "initiatedBy", "automated")
+	if got := lastSync(app); got != "2026-01-15T09:01:00Z" {
+		t.Fatalf("expected an automated operation to be skipped, got %q", got)
+	}
+}
+
+// patchRecorder records the options of every Application patch.
+type patchRecorder struct {
+	dynamic.Interface
+	opts *[]metav1.PatchOptions
+}
+
+func (p patchRecorder) Resource(gvr schema.GroupVersionResource) dynamic.NamespaceableResourceInterface {
+	return patchRecorderResource{p.Interface.Resource(gvr), p.opts}
+}
+
+type patchRecorderResource struct {
+	dynamic.NamespaceableResourceInterface
+	opts *[]metav1.PatchOptions
+}
+
+func (p patchRecorderResource) Namespace(ns string) dynamic.ResourceInterface {
+	return patchRecorderNamespaced{p.NamespaceableResourceInterface.Namespace(ns), p.opts}
+}
+
+type patchRecorderNamespaced struct {
+	dynamic.ResourceInterface
+	opts *[]metav1.PatchOptions
+}
+
+func (p patchRecorderNamespaced) Patch(ctx context.Context, name string, pt types.PatchType, data []byte, opts metav1.PatchOptions, sub ...string) (*unstructured.Unstructured, error) {
+	*p.opts = append(*p.opts, opts)
+	return p.ResourceInterface.Patch(ctx, name, pt, data, opts, sub...)
+}
+
+func TestClient_DryRun(t *testing.T) {
+	var opts []metav1.PatchOptions
+	c := NewClientFromDynamic(patchRecorder{newFakeDynamic(newFakeApp("argocd", "my-app", nil)), &opts})
+	ctx := context.Background()
+
+	// The fake client applies dry-run patches too, so each call sees the
+	// previous one's result.
+	if _, err := c.BookApp(ctx, "argocd", "my-app", "alice", BookOptions{DryRun: true}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := c.TransferApp(ctx, "argocd", "my-app", "alice", "bob", false, TransferOptions{DryRun: true}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := c.UnbookApp(ctx, "argocd", "my-app", "bob", false, UnbookOptions{DryRun: true}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := c.BookApp(ctx, "argocd", "my-app", "carol", BookOptions{}); err != nil {
+		t.Fatal(err)
+	}
+
+	want := []bool{true, true, true, false}
+	if len(opts) != len(want) {
+		t.Fatalf("expected %d patches, got %d", len(want), len(opts))
+	}
+	for i, dryRun := range want {
+		got := len(opts[i].DryRun) == 1 && opts[i].DryRun[0] == metav1.DryRunAll
+		if got != dryRun {
+			t.Errorf("patch %d: expected dry run %v, got %v", i, dryRun, opts[i].DryRun)
+		}
+	}
+	if _, err := c.BookApp(ctx, "argocd", "my-app", "bob", BookOptions{DryRun: true}); !errors.Is(err, ErrConflict) {
+		t.Errorf("expected the conflict check to run in a dry run, got %v", err)
+	}
+}
+
+func TestNewClientFromKubeconfig_Namespace(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config")
+	os.WriteFile(path, []byte(`apiVersion: v1
+kind: Config
+clusters:
+  - name: c
+    cluster: {server: "https://127.0.0.1:6443"}
+users:
+  - name: u
+contexts:
+  - name: plain
+    context: {cluster: c, user: u}
+  - name: team
+    context: {cluster: c, user: u, namespace: team}
+current-context: plain
+`), 0o600)
+
+	for kubeContext, want := range map[string]string{"": "", "team": "team"} {
+		_, namespace, err := NewClientFromKubeconfig(path, kubeContext)
+		if err != nil {
+			t.Fatalf("%q: unexpected error: %v", kubeContext, err)
+		}
+		if namespace != want {
+			t.Errorf("%q: expected namespace %q, got %q", kubeContext, want, namespace)
+		}
+	}
+}
@@ -0,0 +1,43 @@
+package k8s
+
+import (
+	"context"
+	"net/http"
+
+	"go.opentelemetry.io/contrib/instrumentation/net/http/otelhttp"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+	"k8s.io/client-go/rest"
+)
+
+const tracerName = "github.com/behavox/argocd-book-plugin/internal/k8s"
+
+// startSpan starts a client span for an API call on Applications. appName is
+// empty for calls on a whole namespace.
+func startSpan(ctx context.Context, name, namespace, appName string) (context.Context, trace.Span) {
+	attrs := []attribute.KeyValue{attribute.String("k8s.namespace.name", namespace)}
+	if appName != "" {
+		attrs = append(attrs, attribute.String("argocd.application.name", appName))
+	}
+	// The global TracerProvider is a no-op until tracing is configured.
+	return otel.Tracer(tracerName).Start(ctx, "k8s "+name, trace.WithSpanKind(trace.SpanKindClient), trace.WithAttributes(attrs...))
+}
+
+// endSpan records err, if any, on span and ends it.
+func endSpan(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
+
+// traceTransport adds an HTTP span to every request made with config, so time
+// spent on the wire shows apart from client-side throttling and retries.
+func traceTransport(config *rest.Config) {
+	config.Wrap(func(rt http.RoundTripper) http.RoundTripper {
+		return otelhttp.NewTransport(rt)
+	})
+}
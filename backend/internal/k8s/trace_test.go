@@ -0,0 +1,57 @@
+package k8s
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"k8s.io/apimachinery/pkg/runtime"
+	k8stesting "k8s.io/client-go/testing"
+)
+
+func TestClient_TracesAPICalls(t *testing.T) {
+	exporter := tracetest.NewInMemoryExporter()
+	tp := sdktrace.NewTracerProvider(sdktrace.WithSyncer(exporter))
+	otel.SetTracerProvider(tp)
+	t.Cleanup(func() { tp.Shutdown(context.Background()) })
+
+	dyn := newFakeDynamic(newFakeApp("argocd", "my-app", nil))
+	c := NewClientFromDynamic(dyn)
+	ctx, parent := tp.Tracer("test").Start(context.Background(), "request")
+	if _, err := c.BookApp(ctx, "argocd", "my-app", "alice", BookOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	dyn.PrependReactor("list", "applications", func(k8stesting.Action) (bool, runtime.Object, error) {
+		return true, nil, errors.New("connection refused")
+	})
+	c.ListBookings(ctx, "argocd")
+	parent.End()
+
+	spans := exporter.GetSpans()
+	var names []string
+	for _, s := range spans[:len(spans)-1] {
+		names = append(names, s.Name)
+		if s.Parent.SpanID() != parent.SpanContext().SpanID() {
+			t.Errorf("expected %s to be a child of the request span", s.Name)
+		}
+	}
+	if len(names) != 3 || names[0] != "k8s get application" || names[1] != "k8s patch application" || names[2] != "k8s list applications" {
+		t.Fatalf("unexpected spans %v", names)
+	}
+	if spans[2].Status.Code != codes.Error || len(spans[2].Events) == 0 {
+		t.Errorf("expected the failed list to record its error, got %+v", spans[2].Status)
+	}
+	var app string
+	for _, a := range spans[0].Attributes {
+		if a.Key == "argocd.application.name" {
+			app = a.Value.AsString()
+		}
+	}
+	if app != "my-app" {
+		t.Errorf("expected the application name attribute, got %v", spans[0].Attributes)
+	}
+}
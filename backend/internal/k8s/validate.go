@@ -0,0 +1,73 @@
+package k8s
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"unicode"
+
+	"k8s.io/apimachinery/pkg/util/validation"
+)
+
+// ErrInvalid is returned (wrapped) when a Client method is called with malformed input.
+var ErrInvalid = errors.New("invalid")
+
+const (
+	maxUsernameLength = 256
+	maxReasonLength   = 1024
+)
+
+// ValidateAppRef checks that namespace and appName are valid Kubernetes object names.
+func ValidateAppRef(namespace, appName string) error {
+	if errs := validation.IsDNS1123Label(namespace); len(errs) > 0 {
+		return fmt.Errorf("%w: namespace %q: %s", ErrInvalid, namespace, strings.Join(errs, "; "))
+	}
+	if errs := validation.IsDNS1123Subdomain(appName); len(errs) > 0 {
+		return fmt.Errorf("%w: application name %q: %s", ErrInvalid, appName, strings.Join(errs, "; "))
+	}
+	return nil
+}
+
+// ValidateUsername checks that username can be stored as a booking holder.
+func ValidateUsername(username string) error {
+	if strings.TrimSpace(username) == "" {
+		return fmt.Errorf("%w: username must not be empty", ErrInvalid)
+	}
+	if len(username) > maxUsernameLength {
+		return fmt.Errorf("%w: username longer than %d characters", ErrInvalid, maxUsernameLength)
+	}
+	if strings.IndexFunc(username, unicode.IsControl) >= 0 {
+		return fmt.Errorf("%w: username contains control characters", ErrInvalid)
+	}
+	return nil
+}
+
+// ValidateBookOptions checks the optional details of a booking. The reason may span
+// lines but must not hold other control characters.
+func ValidateBookOptions(opts BookOptions) error {
+	if len(opts.Reason) > maxReasonLength {
+		return fmt.Errorf("%w: reason longer than %d characters", ErrInvalid, maxReasonLength)
+	}
+	if strings.IndexFunc(opts.Reason, func(r rune) bool { return unicode.IsControl(r) && r != '\n' && r != '\t' }) >= 0 {
+		return fmt.Errorf("%w: reason contains control characters", ErrInvalid)
+	}
+	if opts.Duration < 0 || opts.DefaultDuration < 0 || opts.MaxDuration < 0 {
+		return fmt.Errorf("%w: duration must not be negative", ErrInvalid)
+	}
+	return nil
+}
+
+// ParseAppRef parses an application reference of the form "namespace:name" or
+// "namespace/name". A bare name resolves to defaultNamespace.
+func ParseAppRef(ref, defaultNamespace string) (namespace, appName string, err error) {
+	namespace, appName = defaultNamespace, ref
+	if ns, name, ok := strings.Cut(ref, ":"); ok {
+		namespace, appName = ns, name
+	} else if ns, name, ok := strings.Cut(ref, "/"); ok {
+		namespace, appName = ns, name
+	}
+	if err := ValidateAppRef(namespace, appName); err != nil {
+		return "", "", err
+	}
+	return namespace, appName, nil
+}
@@ -0,0 +1,75 @@
+package k8s
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseAppRef(t *testing.T) {
+	tests := []struct {
+		ref, wantNS, wantApp string
+		wantErr              bool
+	}{
+		{"my-app", "argocd", "my-app", false},
+		{"team:my-app", "team", "my-app", false},
+		{"team/my-app", "team", "my-app", false},
+		{"Team/my-app", "", "", true},
+		{"team:", "", "", true},
+		{"team/my_app", "", "", true},
+	}
+	for _, tt := range tests {
+		ns, app, err := ParseAppRef(tt.ref, "argocd")
+		if (err != nil) != tt.wantErr {
+			t.Errorf("ParseAppRef(%q): unexpected error %v", tt.ref, err)
+			continue
+		}
+		if err != nil && !errors.Is(err, ErrInvalid) {
+			t.Errorf("ParseAppRef(%q): expected ErrInvalid, got %v", tt.ref, err)
+		}
+		if ns != tt.wantNS || app != tt.wantApp {
+			t.Errorf("ParseAppRef(%q) = %q, %q; want %q, %q", tt.ref, ns, app, tt.wantNS, tt.wantApp)
+		}
+	}
+}
+
+func TestValidateUsername(t *testing.T) {
+	for _, u := range []string{"alice", "alice@example.com", "CN=Alice Smith"} {
+		if err := ValidateUsername(u); err != nil {
+			t.Errorf("ValidateUsername(%q): unexpected error %v", u, err)
+		}
+	}
+	for _, u := range []string{"", "   ", "alice\nbob"} {
+		if err := ValidateUsername(u); !errors.Is(err, ErrInvalid) {
+			t.Errorf("ValidateUsername(%q): expected ErrInvalid, got %v", u, err)
+		}
+	}
+}
+
+func TestBookApp_InvalidInput(t *testing.T) {
+	c := newFakeClient(newFakeApp("argocd", "my-app", nil))
+
+	if _, err := c.BookApp(context.Background(), "argocd", "my-app", "", BookOptions{}); !errors.Is(err, ErrInvalid) {
+		t.Fatalf("expected ErrInvalid for empty username, got %v", err)
+	}
+	if _, err := c.BookApp(context.Background(), "argocd", "My_App", "alice", BookOptions{}); !errors.Is(err, ErrInvalid) {
+		t.Fatalf("expected ErrInvalid for bad app name, got %v", err)
+	}
+}
+
+func TestValidateBookOptions(t *testing.T) {
+	valid := []BookOptions{{}, {Reason: "load test\nsee ticket"}, {Duration: time.Hour}}
+	for _, o := range valid {
+		if err := ValidateBookOptions(o); err != nil {
+			t.Errorf("ValidateBookOptions(%+v): unexpected error %v", o, err)
+		}
+	}
+	invalid := []BookOptions{{Reason: strings.Repeat("x", maxReasonLength+1)}, {Reason: "bell\a"}, {Duration: -time.Minute}}
+	for _, o := range invalid {
+		if err := ValidateBookOptions(o); !errors.Is(err, ErrInvalid) {
+			t.Errorf("ValidateBookOptions(%+v): expected ErrInvalid, got %v", o, err)
+		}
+	}
+}
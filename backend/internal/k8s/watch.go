@@ -0,0 +1,74 @@
+package k8s
+
+import (
+	"context"
+	"fmt"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/watch"
+	"k8s.io/client-go/dynamic"
+)
+
+// ApplicationEvent is an Application seen by WatchApplications.
+type ApplicationEvent struct {
+	Application
+	// SyncInitiator is the user who started the sync operation pending on the
+	// Application, or "" when there is none or automated sync started it.
+	SyncInitiator string
+}
+
+// WatchApplications lists the Applications in namespace (all namespaces when
+// empty), then watches them, calling fn with every Application listed, added or
+// modified. It returns nil when the API server closes the watch, which callers
+// handle by calling it again, and when ctx is cancelled.
+func WatchApplications(ctx context.Context, dynClient dynamic.Interface, namespace string, fn func(ApplicationEvent)) error {
+	c := &client{dynamic: dynClient}
+	list, err := c.listApps(ctx, namespace)
+	if err != nil {
+		return fmt.Errorf("failed to list applications: %w", err)
+	}
+	for i := range list.Items {
+		fn(applicationEvent(&list.Items[i]))
+	}
+
+	watcher, err := dynClient.Resource(applicationGVR).Namespace(namespace).Watch(ctx, metav1.ListOptions{
+		ResourceVersion: list.GetResourceVersion(),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to watch applications: %w", err)
+	}
+	defer watcher.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case ev, ok := <-watcher.ResultChan():
+			if !ok {
+				return nil
+			}
+			if ev.Type == watch.Error {
+				return fmt.Errorf("application watch failed: %v", ev.Object)
+			}
+			obj, isApp := ev.Object.(*unstructured.Unstructured)
+			if isApp && (ev.Type == watch.Added || ev.Type == watch.Modified) {
+				fn(applicationEvent(obj))
+			}
+		}
+	}
+}
+
+func applicationEvent(app *unstructured.Unstructured) ApplicationEvent {
+	return ApplicationEvent{Application: extractApplication(app), SyncInitiator: syncInitiator(app)}
+}
+
+// syncInitiator returns the user in the operation field of app, which ArgoCD
+// sets when a sync is requested and clears once it completes.
+func syncInitiator(app *unstructured.Unstructured) string {
+	if automated, _, _ := unstructured.NestedBool(app.Object, "operation", "initiatedBy", "automated"); automated {
+		return ""
+	}
+	username, _, _ := unstructured.NestedString(app.Object, "operation", "initiatedBy", "username")
+	return username
+}
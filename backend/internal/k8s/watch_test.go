@@ -0,0 +1,77 @@
+package k8s
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/watch"
+	k8stesting "k8s.io/client-go/testing"
+)
+
+func TestWatchApplications(t *testing.T) {
+	dyn := newFakeDynamic(newFakeApp("argocd", "listed", map[string]string{AnnotationBookedBy: "alice"}))
+	watching := make(chan struct{})
+	dyn.PrependWatchReactor("applications", func(action k8stesting.Action) (bool, watch.Interface, error) {
+		defer close(watching)
+		w, err := dyn.Tracker().Watch(applicationGVR, action.GetNamespace())
+		return true, w, err
+	})
+	ctx, cancel := context.WithCancel(context.Background())
+	events := make(chan ApplicationEvent, 10)
+	done := make(chan error, 1)
+	go func() {
+		done <- WatchApplications(ctx, dyn, "", func(ev ApplicationEvent) { events <- ev })
+	}()
+
+	next := func() ApplicationEvent {
+		t.Helper()
+		select {
+		case ev := <-events:
+			return ev
+		case <-time.After(5 * time.Second):
+			t.Fatal("timed out waiting for an event")
+			return ApplicationEvent{}
+		}
+	}
+	if ev := next(); ev.Name != "listed" || ev.Booking == nil || ev.Booking.BookedBy != "alice" || ev.SyncInitiator != "" {
+		t.Fatalf("unexpected listed event %+v", ev)
+	}
+
+	<-watching
+	app := newFakeApp("team-a", "synced", nil)
+	unstructured.SetNestedField(app.Object, "bob", "operation", "initiatedBy", "username")
+	if _, err := dyn.Resource(applicationGVR).Namespace("team-a").Create(ctx, app, metav1.CreateOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	if ev := next(); ev.Namespace != "team-a" || ev.Name != "synced" || ev.SyncInitiator != "bob" {
+		t.Fatalf("unexpected added event %+v", ev)
+	}
+
+	cancel()
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Fatalf("expected a clean return on cancel, got %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("WatchApplications did not return after cancel")
+	}
+}
+
+func TestSyncInitiator(t *testing.T) {
+	app := newFakeApp("argocd", "my-app", nil)
+	if got := syncInitiator(app); got != "" {
+		t.Fatalf("expected no initiator without an operation, got %q", got)
+	}
+	unstructured.SetNestedField(app.Object, "alice", "operation", "initiatedBy", "username")
+	if got := syncInitiator(app); got != "alice" {
+		t.Fatalf("expected alice, got %q", got)
+	}
+	unstructured.SetNestedField(app.Object, true, "operation", "initiatedBy", "automated")
+	if got := syncInitiator(app); got != "" {
+		t.Fatalf("expected automated syncs to be ignored, got %q", got)
+	}
+}
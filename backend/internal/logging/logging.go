@@ -0,0 +1,87 @@
+// Package logging configures the structured logger shared by the backend and
+// carries a request-scoped logger through contexts.
+package logging
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"log/slog"
+	"strings"
+)
+
+// Field names shared by every component, so log lines can be searched by the
+// same keys whichever component wrote them.
+const (
+	KeyRequestID = "request_id"
+	KeyUser      = "user"
+	KeyNamespace = "namespace"
+	KeyApp       = "app"
+	KeyAction    = "action"
+	KeyError     = "error"
+	KeyTraceID   = "trace_id"
+	// KeyAudit, set to true, marks a change the backend made to a booking on
+	// its own, such as an idle release, so audit pipelines can pick it out.
+	KeyAudit = "audit"
+)
+
+// New returns a logger writing to w. level is debug, info, warn or error
+// (default info); format is json or text (default json).
+func New(w io.Writer, level, format string) (*slog.Logger, error) {
+	var lvl slog.Level
+	if level != "" {
+		if err := lvl.UnmarshalText([]byte(level)); err != nil {
+			return nil, fmt.Errorf("invalid log level %q (expected debug, info, warn or error)", level)
+		}
+	}
+	opts := &slog.HandlerOptions{Level: lvl}
+	switch strings.ToLower(format) {
+	case "", "json":
+		return slog.New(slog.NewJSONHandler(w, opts)), nil
+	case "text":
+		return slog.New(slog.NewTextHandler(w, opts)), nil
+	default:
+		return nil, fmt.Errorf("invalid log format %q (expected json or text)", format)
+	}
+}
+
+type loggerKey struct{}
+
+// NewContext returns a copy of ctx carrying l.
+func NewContext(ctx context.Context, l *slog.Logger) context.Context {
+	return context.WithValue(ctx, loggerKey{}, l)
+}
+
+// FromContext returns the logger carried by ctx, or slog.Default.
+func FromContext(ctx context.Context) *slog.Logger {
+	if l, ok := ctx.Value(loggerKey{}).(*slog.Logger); ok {
+		return l
+	}
+	return slog.Default()
+}
+
+// NewRequestID returns a random 16-byte request ID in hex.
+func NewRequestID() string {
+	b := make([]byte, 16)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// ValidRequestID reports whether a caller-supplied request ID is safe to
+// propagate: 1 to 128 letters, digits, '-', '_', '.' or ':'.
+func ValidRequestID(id string) bool {
+	if id == "" || len(id) > 128 {
+		return false
+	}
+	for _, c := range id {
+		switch {
+		case c >= 'a' && c <= 'z', c >= 'A' && c <= 'Z', c >= '0' && c <= '9':
+		case c == '-', c == '_', c == '.', c == ':':
+		default:
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,71 @@
+package logging
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"log/slog"
+	"strings"
+	"testing"
+)
+
+func TestNew(t *testing.T) {
+	var buf bytes.Buffer
+	l, err := New(&buf, "warn", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	l.Info("dropped")
+	l.Warn("kept", KeyUser, "alice")
+
+	var line map[string]interface{}
+	if err := json.Unmarshal(buf.Bytes(), &line); err != nil {
+		t.Fatalf("expected a single JSON line, got %q: %v", buf.String(), err)
+	}
+	if line["msg"] != "kept" || line[KeyUser] != "alice" {
+		t.Fatalf("unexpected log line %v", line)
+	}
+
+	buf.Reset()
+	l, _ = New(&buf, "DEBUG", "text")
+	l.Debug("hello")
+	if !strings.Contains(buf.String(), "msg=hello") {
+		t.Fatalf("expected a text debug line, got %q", buf.String())
+	}
+
+	if _, err := New(&buf, "verbose", "json"); err == nil {
+		t.Error("expected an error for an unknown level")
+	}
+	if _, err := New(&buf, "info", "xml"); err == nil {
+		t.Error("expected an error for an unknown format")
+	}
+}
+
+func TestFromContext(t *testing.T) {
+	if FromContext(context.Background()) != slog.Default() {
+		t.Fatal("expected the default logger without one in the context")
+	}
+	l := slog.New(slog.NewTextHandler(&bytes.Buffer{}, nil))
+	if FromContext(NewContext(context.Background(), l)) != l {
+		t.Fatal("expected the logger from the context")
+	}
+}
+
+func TestRequestID(t *testing.T) {
+	id := NewRequestID()
+	if len(id) != 32 || !ValidRequestID(id) || id == NewRequestID() {
+		t.Fatalf("expected a unique 32-character hex ID, got %q", id)
+	}
+	for id, want := range map[string]bool{
+		"abc-123":                true,
+		"trace:span.1_x":         true,
+		"":                       false,
+		"has space":              false,
+		"new\nline":              false,
+		strings.Repeat("a", 129): false,
+	} {
+		if got := ValidRequestID(id); got != want {
+			t.Errorf("ValidRequestID(%q) = %v, want %v", id, got, want)
+		}
+	}
+}
@@ -0,0 +1,33 @@
+// Package metrics defines the backend's Prometheus metrics and serves them.
+package metrics
+
+import (
+	"net/http"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// Registry holds every metric of the backend, plus the Go runtime and process
+// collectors.
+var Registry = prometheus.NewRegistry()
+
+// ThrottledRequests counts requests rejected by a rate limit.
+var ThrottledRequests = prometheus.NewCounterVec(prometheus.CounterOpts{
+	Name: "booking_throttled_requests_total",
+	Help: "Requests rejected with 429 Too Many Requests, by the rate limit that rejected them (user or global) and action.",
+}, []string{"scope", "action"})
+
+func init() {
+	Registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		ThrottledRequests,
+	)
+}
+
+// Handler serves Registry in the Prometheus text format.
+func Handler() http.Handler {
+	return promhttp.HandlerFor(Registry, promhttp.HandlerOpts{})
+}
@@ -0,0 +1,21 @@
+package metrics
+
+import (
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestHandler(t *testing.T) {
+	ThrottledRequests.WithLabelValues("user", "book").Inc()
+
+	w := httptest.NewRecorder()
+	Handler().ServeHTTP(w, httptest.NewRequest("GET", "/metrics", nil))
+	body := w.Body.String()
+	if !strings.Contains(body, `booking_throttled_requests_total{action="book",scope="user"} 1`) {
+		t.Fatalf("expected the throttled counter, got:\n%s", body)
+	}
+	if !strings.Contains(body, "go_goroutines") {
+		t.Error("expected the Go runtime metrics")
+	}
+}
@@ -0,0 +1,259 @@
+package notify
+
+import (
+	"bytes"
+	"crypto/tls"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net"
+	"net/smtp"
+	"os"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/behavox/argocd-book-plugin/internal/logging"
+)
+
+// SMTPConfig configures the outgoing mail server.
+type SMTPConfig struct {
+	Host     string
+	Port     string
+	Username string
+	Password string
+	From     string
+	// StartTLS upgrades the connection before authenticating. The server must advertise STARTTLS.
+	StartTLS bool
+	Timeout  time.Duration
+}
+
+// Mailer sends plain-text email over SMTP.
+type Mailer struct {
+	cfg SMTPConfig
+}
+
+// NewMailer creates a Mailer for the given server.
+func NewMailer(cfg SMTPConfig) *Mailer {
+	if cfg.Port == "" {
+		cfg.Port = "587"
+	}
+	if cfg.Timeout <= 0 {
+		cfg.Timeout = defaultTimeout
+	}
+	return &Mailer{cfg: cfg}
+}
+
+// Send delivers a single message to one recipient.
+func (m *Mailer) Send(to, subject, body string) error {
+	conn, err := net.DialTimeout("tcp", net.JoinHostPort(m.cfg.Host, m.cfg.Port), m.cfg.Timeout)
+	if err != nil {
+		return fmt.Errorf("failed to connect to SMTP server: %w", err)
+	}
+	conn.SetDeadline(time.Now().Add(m.cfg.Timeout))
+
+	c, err := smtp.NewClient(conn, m.cfg.Host)
+	if err != nil {
+		conn.Close()
+		return fmt.Errorf("failed to start SMTP session: %w", err)
+	}
+	defer c.Close()
+
+	if m.cfg.StartTLS {
+		if ok, _ := c.Extension("STARTTLS"); !ok {
+			return fmt.Errorf("SMTP server %s does not support STARTTLS", m.cfg.Host)
+		}
+		if err := c.StartTLS(&tls.Config{ServerName: m.cfg.Host}); err != nil {
+			return fmt.Errorf("STARTTLS failed: %w", err)
+		}
+	}
+	if m.cfg.Username != "" {
+		if err := c.Auth(smtp.PlainAuth("", m.cfg.Username, m.cfg.Password, m.cfg.Host)); err != nil {
+			return fmt.Errorf("SMTP auth failed: %w", err)
+		}
+	}
+	if err := c.Mail(m.cfg.From); err != nil {
+		return fmt.Errorf("MAIL FROM rejected: %w", err)
+	}
+	if err := c.Rcpt(to); err != nil {
+		return fmt.Errorf("RCPT TO %s rejected: %w", to, err)
+	}
+	w, err := c.Data()
+	if err != nil {
+		return fmt.Errorf("DATA rejected: %w", err)
+	}
+	if _, err := w.Write(buildMessage(m.cfg.From, to, subject, body)); err != nil {
+		return fmt.Errorf("failed to write message: %w", err)
+	}
+	if err := w.Close(); err != nil {
+		return fmt.Errorf("message rejected: %w", err)
+	}
+	return c.Quit()
+}
+
+func buildMessage(from, to, subject, body string) []byte {
+	var b bytes.Buffer
+	fmt.Fprintf(&b, "From: %s\r\n", from)
+	fmt.Fprintf(&b, "To: %s\r\n", to)
+	fmt.Fprintf(&b, "Subject: %s\r\n", subject)
+	fmt.Fprintf(&b, "Date: %s\r\n", time.Now().Format(time.RFC1123Z))
+	b.WriteString("MIME-Version: 1.0\r\n")
+	b.WriteString("Content-Type: text/plain; charset=utf-8\r\n")
+	b.WriteString("\r\n")
+	b.WriteString(strings.ReplaceAll(strings.ReplaceAll(body, "\r\n", "\n"), "\n", "\r\n"))
+	return b.Bytes()
+}
+
+// AddressBook maps ArgoCD usernames to email addresses.
+type AddressBook struct {
+	addresses map[string]string
+	domain    string
+}
+
+// NewAddressBook creates an AddressBook from an explicit mapping. Users missing from the
+// mapping fall back to "<user>@<domain>" when domain is set; usernames that already look
+// like email addresses are used as-is.
+func NewAddressBook(addresses map[string]string, domain string) *AddressBook {
+	return &AddressBook{addresses: addresses, domain: domain}
+}
+
+// LoadAddressBook reads a JSON object of username to email address from path.
+func LoadAddressBook(path, domain string) (*AddressBook, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read email mapping: %w", err)
+	}
+	var addresses map[string]string
+	if err := json.Unmarshal(data, &addresses); err != nil {
+		return nil, fmt.Errorf("failed to parse email mapping %s: %w", path, err)
+	}
+	return NewAddressBook(addresses, domain), nil
+}
+
+// Lookup returns the email address for user, or "" if none is known.
+func (a *AddressBook) Lookup(user string) string {
+	if addr := a.addresses[user]; addr != "" {
+		return addr
+	}
+	if strings.Contains(user, "@") {
+		return user
+	}
+	if a.domain != "" && user != "" {
+		return user + "@" + a.domain
+	}
+	return ""
+}
+
+// ReminderData is passed to the reminder templates.
+type ReminderData struct {
+	User      string
+	AppName   string
+	Namespace string
+	Project   string
+	ExpiresAt time.Time
+	Minutes   int
+	AppURL    string
+}
+
+var (
+	reminderSubject = template.Must(template.New("subject").Parse(
+		`[ArgoCD booking] {{.AppName}} expires in {{.Minutes}} minutes`))
+	reminderBody = template.Must(template.New("body").Parse(`Hi {{.User}},
+
+your booking of {{.Namespace}}/{{.AppName}}{{if .Project}} (project {{.Project}}){{end}} expires in {{.Minutes}} minutes, at {{.ExpiresAt.Format "2006-01-02 15:04 MST"}}.
+
+Book it again before then if you still need it; otherwise it becomes available to others.
+{{if .AppURL}}
+{{.AppURL}}
+{{end}}`))
+)
+
+func renderReminder(d ReminderData) (subject, body string, err error) {
+	var s, b bytes.Buffer
+	if err := reminderSubject.Execute(&s, d); err != nil {
+		return "", "", err
+	}
+	if err := reminderBody.Execute(&b, d); err != nil {
+		return "", "", err
+	}
+	return s.String(), b.String(), nil
+}
+
+// WaitData is passed to the templates of the message sent when a waiting book
+// request gets its application.
+type WaitData struct {
+	User      string
+	AppName   string
+	Namespace string
+	Project   string
+	Reason    string
+	AppURL    string
+}
+
+var (
+	waitSubject = template.Must(template.New("subject").Parse(
+		`[ArgoCD booking] {{.AppName}} is now booked for you`))
+	waitBody = template.Must(template.New("body").Parse(`Hi {{.User}},
+
+{{.Namespace}}/{{.AppName}}{{if .Project}} (project {{.Project}}){{end}} became free and is now booked for you, as you asked when you waited for it.
+{{if .Reason}}
+Reason: {{.Reason}}
+{{end}}{{if .AppURL}}
+{{.AppURL}}
+{{end}}`))
+)
+
+func renderWait(d WaitData) (subject, body string, err error) {
+	var s, b bytes.Buffer
+	if err := waitSubject.Execute(&s, d); err != nil {
+		return "", "", err
+	}
+	if err := waitBody.Execute(&b, d); err != nil {
+		return "", "", err
+	}
+	return s.String(), b.String(), nil
+}
+
+// WaitMailer emails the user of an event that their wait for an application
+// is over, so they need not watch the waiting request. It implements Notifier
+// and sends in the background.
+type WaitMailer struct {
+	sender    Sender
+	addresses *AddressBook
+	argocdURL string
+}
+
+// NewWaitMailer returns a WaitMailer that links applications under argocdURL
+// when it is set.
+func NewWaitMailer(sender Sender, addresses *AddressBook, argocdURL string) *WaitMailer {
+	return &WaitMailer{sender: sender, addresses: addresses, argocdURL: argocdURL}
+}
+
+// Notify emails e.User in the background.
+func (m *WaitMailer) Notify(e Event) {
+	go func() {
+		if err := m.send(e); err != nil {
+			slog.Error("failed to email waiting user", logging.KeyUser, e.User, logging.KeyNamespace, e.Namespace, logging.KeyApp, e.AppName, logging.KeyError, err)
+		}
+	}()
+}
+
+func (m *WaitMailer) send(e Event) error {
+	to := m.addresses.Lookup(e.User)
+	if to == "" {
+		slog.Warn("no email address for waiting user, skipping", logging.KeyUser, e.User, logging.KeyNamespace, e.Namespace, logging.KeyApp, e.AppName)
+		return nil
+	}
+	subject, body, err := renderWait(WaitData{
+		User:      e.User,
+		AppName:   e.AppName,
+		Namespace: e.Namespace,
+		Project:   e.Project,
+		Reason:    e.Reason,
+		AppURL:    AppURL(m.argocdURL, e),
+	})
+	if err != nil {
+		return err
+	}
+	return m.sender.Send(to, subject, body)
+}
@@ -0,0 +1,199 @@
+package notify
+
+import (
+	"bufio"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+)
+
+type sentMail struct {
+	from string
+	to   []string
+	data string
+	auth string
+}
+
+// fakeSMTP is a minimal SMTP server that records accepted messages.
+type fakeSMTP struct {
+	ln       net.Listener
+	mu       sync.Mutex
+	messages []sentMail
+}
+
+func newFakeSMTP(t *testing.T) *fakeSMTP {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("failed to listen: %v", err)
+	}
+	s := &fakeSMTP{ln: ln}
+	go s.serve()
+	t.Cleanup(func() { ln.Close() })
+	return s
+}
+
+func (s *fakeSMTP) hostPort() (string, string) {
+	host, port, _ := net.SplitHostPort(s.ln.Addr().String())
+	return host, port
+}
+
+func (s *fakeSMTP) sent() []sentMail {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]sentMail(nil), s.messages...)
+}
+
+func (s *fakeSMTP) serve() {
+	for {
+		conn, err := s.ln.Accept()
+		if err != nil {
+			return
+		}
+		go s.handle(conn)
+	}
+}
+
+func (s *fakeSMTP) handle(conn net.Conn) {
+	defer conn.Close()
+	r := bufio.NewReader(conn)
+	reply := func(line string) { conn.Write([]byte(line + "\r\n")) }
+
+	var msg sentMail
+	reply("220 fake ESMTP")
+	for {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			return
+		}
+		line = strings.TrimRight(line, "\r\n")
+		cmd := strings.ToUpper(line)
+		switch {
+		case strings.HasPrefix(cmd, "EHLO"):
+			reply("250-fake")
+			reply("250 AUTH PLAIN")
+		case strings.HasPrefix(cmd, "AUTH PLAIN"):
+			msg.auth = strings.TrimSpace(line[len("AUTH PLAIN"):])
+			reply("235 ok")
+		case strings.HasPrefix(cmd, "MAIL FROM:"):
+			msg.from = strings.Trim(line[len("MAIL FROM:"):], "<>")
+			reply("250 ok")
+		case strings.HasPrefix(cmd, "RCPT TO:"):
+			msg.to = append(msg.to, strings.Trim(line[len("RCPT TO:"):], "<>"))
+			reply("250 ok")
+		case cmd == "DATA":
+			reply("354 go ahead")
+			var data strings.Builder
+			for {
+				l, err := r.ReadString('\n')
+				if err != nil {
+					return
+				}
+				if l == ".\r\n" {
+					break
+				}
+				data.WriteString(l)
+			}
+			msg.data = data.String()
+			s.mu.Lock()
+			s.messages = append(s.messages, msg)
+			s.mu.Unlock()
+			msg = sentMail{}
+			reply("250 queued")
+		case cmd == "QUIT":
+			reply("221 bye")
+			return
+		default:
+			reply("250 ok")
+		}
+	}
+}
+
+func TestMailer_Send(t *testing.T) {
+	srv := newFakeSMTP(t)
+	host, port := srv.hostPort()
+	m := NewMailer(SMTPConfig{
+		Host:     host,
+		Port:     port,
+		Username: "booking",
+		Password: "secret",
+		From:     "booking@example.com",
+	})
+
+	if err := m.Send("alice@example.com", "Hello", "line one\nline two"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	sent := srv.sent()
+	if len(sent) != 1 {
+		t.Fatalf("expected 1 message, got %d", len(sent))
+	}
+	if sent[0].from != "booking@example.com" || sent[0].to[0] != "alice@example.com" {
+		t.Fatalf("unexpected envelope: %+v", sent[0])
+	}
+	if sent[0].auth == "" {
+		t.Fatal("expected AUTH PLAIN credentials")
+	}
+	if !strings.Contains(sent[0].data, "Subject: Hello\r\n") || !strings.Contains(sent[0].data, "line one\r\nline two") {
+		t.Fatalf("unexpected message data:\n%s", sent[0].data)
+	}
+}
+
+func TestMailer_StartTLSUnsupported(t *testing.T) {
+	srv := newFakeSMTP(t)
+	host, port := srv.hostPort()
+	m := NewMailer(SMTPConfig{Host: host, Port: port, From: "booking@example.com", StartTLS: true})
+
+	if err := m.Send("alice@example.com", "Hello", "body"); err == nil {
+		t.Fatal("expected error when server lacks STARTTLS")
+	}
+}
+
+func TestAddressBook(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "emails.json")
+	os.WriteFile(path, []byte(`{"alice":"alice.smith@corp.example"}`), 0o600)
+
+	ab, err := LoadAddressBook(path, "example.com")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	tests := map[string]string{
+		"alice":           "alice.smith@corp.example",
+		"bob":             "bob@example.com",
+		"carol@other.org": "carol@other.org",
+	}
+	for user, want := range tests {
+		if got := ab.Lookup(user); got != want {
+			t.Errorf("Lookup(%q) = %q, want %q", user, got, want)
+		}
+	}
+
+	if got := NewAddressBook(nil, "").Lookup("bob"); got != "" {
+		t.Errorf("expected no address without domain, got %q", got)
+	}
+}
+
+func TestWaitMailer_EmailsWaiter(t *testing.T) {
+	srv := newFakeSMTP(t)
+	host, port := srv.hostPort()
+	m := NewWaitMailer(NewMailer(SMTPConfig{Host: host, Port: port, From: "booking@example.com"}),
+		NewAddressBook(nil, "example.com"), "https://argocd.example.com")
+
+	err := m.send(Event{Type: EventBooked, AppName: "my-app", Namespace: "argocd", User: "bob", Reason: "release testing"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	sent := srv.sent()
+	if len(sent) != 1 || sent[0].to[0] != "bob@example.com" {
+		t.Fatalf("expected one message to bob, got %+v", sent)
+	}
+	for _, want := range []string{"my-app is now booked for you", "Reason: release testing", "https://argocd.example.com/applications/argocd/my-app"} {
+		if !strings.Contains(sent[0].data, want) {
+			t.Errorf("expected %q in message:\n%s", want, sent[0].data)
+		}
+	}
+}
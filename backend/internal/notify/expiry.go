@@ -0,0 +1,92 @@
+package notify
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"github.com/behavox/argocd-book-plugin/internal/k8s"
+	"github.com/behavox/argocd-book-plugin/internal/logging"
+)
+
+// ExpiredLister lists bookings that have lapsed. It is satisfied by k8s.Client.
+type ExpiredLister interface {
+	ListExpiredBookings(ctx context.Context, namespace string) ([]k8s.Booking, error)
+}
+
+// ExpiryConfig configures the expiry job.
+type ExpiryConfig struct {
+	// Namespace to scan; empty means all namespaces.
+	Namespace string
+	// Interval between scans.
+	Interval time.Duration
+}
+
+// ExpiryWatcher periodically sends an EventExpired notification for each
+// booking whose expires-at has passed since its previous scan, so every expiry
+// time is reported once. Bookings that expire while the job is not running are
+// not reported.
+type ExpiryWatcher struct {
+	cfg      ExpiryConfig
+	lister   ExpiredLister
+	notifier Notifier
+	now      func() time.Time
+
+	since time.Time // expiries up to this time have been reported
+}
+
+// NewExpiryWatcher creates an ExpiryWatcher. Call Run to start it.
+func NewExpiryWatcher(cfg ExpiryConfig, lister ExpiredLister, notifier Notifier) *ExpiryWatcher {
+	if cfg.Interval <= 0 {
+		cfg.Interval = time.Minute
+	}
+	return &ExpiryWatcher{
+		cfg:      cfg,
+		lister:   lister,
+		notifier: notifier,
+		now:      time.Now,
+		since:    time.Now(),
+	}
+}
+
+// Run scans for expired bookings until ctx is cancelled.
+func (w *ExpiryWatcher) Run(ctx context.Context) {
+	ticker := time.NewTicker(w.cfg.Interval)
+	defer ticker.Stop()
+	for {
+		w.check(ctx)
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (w *ExpiryWatcher) check(ctx context.Context) {
+	now := w.now()
+	bookings, err := w.lister.ListExpiredBookings(ctx, w.cfg.Namespace)
+	if err != nil {
+		// since is kept, so the next scan reports what this one missed.
+		slog.Error("expiry: failed to list expired bookings", logging.KeyError, err)
+		return
+	}
+
+	for _, b := range bookings {
+		expiresAt, err := time.Parse(time.RFC3339, b.ExpiresAt)
+		if err != nil || !expiresAt.After(w.since) || expiresAt.After(now) {
+			continue
+		}
+		slog.Info("booking expired", logging.KeyUser, b.BookedBy, logging.KeyNamespace, b.Namespace, logging.KeyApp, b.AppName)
+		w.notifier.Notify(Event{
+			Type:      EventExpired,
+			AppName:   b.AppName,
+			Namespace: b.Namespace,
+			Project:   b.Project,
+			User:      b.BookedBy,
+			Reason:    b.Reason,
+			Timestamp: expiresAt.UTC(),
+		})
+	}
+	w.since = now
+}
@@ -0,0 +1,48 @@
+package notify
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/behavox/argocd-book-plugin/internal/k8s"
+)
+
+type expiredLister []k8s.Booking
+
+func (l expiredLister) ListExpiredBookings(_ context.Context, _ string) ([]k8s.Booking, error) {
+	return l, nil
+}
+
+type recordingNotifier struct {
+	events []Event
+}
+
+func (n *recordingNotifier) Notify(e Event) {
+	n.events = append(n.events, e)
+}
+
+func TestExpiryWatcher_NotifiesEachExpiryOnce(t *testing.T) {
+	start := time.Date(2026, 1, 15, 10, 0, 0, 0, time.UTC)
+	lister := expiredLister{
+		{AppName: "lapsed", Namespace: "argocd", BookedBy: "alice", ExpiresAt: start.Add(30 * time.Second).Format(time.RFC3339)},
+		{AppName: "stale", Namespace: "argocd", BookedBy: "bob", ExpiresAt: start.Add(-time.Hour).Format(time.RFC3339)},
+	}
+	n := &recordingNotifier{}
+	w := NewExpiryWatcher(ExpiryConfig{}, lister, n)
+	w.since = start
+	now := start.Add(time.Minute)
+	w.now = func() time.Time { return now }
+
+	w.check(context.Background())
+	now = now.Add(time.Minute)
+	w.check(context.Background())
+
+	if len(n.events) != 1 {
+		t.Fatalf("expected exactly one event, got %+v", n.events)
+	}
+	e := n.events[0]
+	if e.Type != EventExpired || e.AppName != "lapsed" || e.User != "alice" {
+		t.Fatalf("unexpected event %+v", e)
+	}
+}
@@ -0,0 +1,223 @@
+package notify
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"strings"
+)
+
+// Format selects the payload shape sent to a webhook target.
+type Format string
+
+const (
+	FormatJSON       Format = "json"
+	FormatSlack      Format = "slack"
+	FormatMattermost Format = "mattermost"
+)
+
+// ParseFormat validates a format name. An empty string yields FormatJSON.
+func ParseFormat(s string) (Format, error) {
+	switch f := Format(strings.ToLower(strings.TrimSpace(s))); f {
+	case "":
+		return FormatJSON, nil
+	case FormatJSON, FormatSlack, FormatMattermost:
+		return f, nil
+	default:
+		return "", fmt.Errorf("unknown webhook format %q", s)
+	}
+}
+
+// ParseTargets parses a comma-separated list of webhook URLs. Each entry may be prefixed
+// with a format and "=", e.g. "slack=https://hooks.slack.com/services/...". Every target
+// receives the given event filter.
+func ParseTargets(s string, events []EventType) ([]Target, error) {
+	var targets []Target
+	for _, entry := range strings.Split(s, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		var format Format = FormatJSON
+		if name, rest, ok := strings.Cut(entry, "="); ok && !strings.Contains(name, "/") {
+			f, err := ParseFormat(name)
+			if err != nil {
+				return nil, err
+			}
+			format, entry = f, rest
+		}
+		if _, err := url.ParseRequestURI(entry); err != nil {
+			return nil, fmt.Errorf("invalid webhook URL %q: %w", entry, err)
+		}
+		targets = append(targets, Target{URL: entry, Events: events, Format: format})
+	}
+	return targets, nil
+}
+
+// AppURL returns the ArgoCD UI link for an application, or "" if baseURL is empty.
+func AppURL(baseURL string, e Event) string {
+	if baseURL == "" {
+		return ""
+	}
+	return strings.TrimRight(baseURL, "/") + "/applications/" + url.PathEscape(e.Namespace) + "/" + url.PathEscape(e.AppName)
+}
+
+// Summary returns a one-line human-readable description of the event.
+func Summary(e Event) string {
+	return summary(e.Type, e.AppName, e.User, e.PreviousUser)
+}
+
+// summary builds the Summary sentence from already rendered parts, so chat
+// formats can pass escaped names and a linked application.
+func summary(t EventType, app, user, previousUser string) string {
+	switch t {
+	case EventBooked:
+		return fmt.Sprintf("%s booked by %s", app, user)
+	case EventUnbooked:
+		return fmt.Sprintf("%s released by %s", app, user)
+	case EventExpired:
+		return fmt.Sprintf("booking of %s by %s expired", app, user)
+	case EventTransferred:
+		return fmt.Sprintf("%s transferred from %s to %s", app, previousUser, user)
+	case EventIdle:
+		return fmt.Sprintf("booking of %s by %s is idle", app, user)
+	case EventReleased:
+		return fmt.Sprintf("%s released from %s", app, user)
+	default:
+		return fmt.Sprintf("%s: %s by %s", app, t, user)
+	}
+}
+
+// markupEscaper escapes the characters Slack and Mattermost treat as markup in
+// message text, so user input such as a reason of "<!channel>" is shown as is.
+var markupEscaper = strings.NewReplacer("&", "&amp;", "<", "&lt;", ">", "&gt;")
+
+func escape(s string) string {
+	return markupEscaper.Replace(s)
+}
+
+var eventEmoji = map[EventType]string{
+	EventBooked:      ":lock:",
+	EventUnbooked:    ":unlock:",
+	EventExpired:     ":hourglass:",
+	EventTransferred: ":arrows_counterclockwise:",
+	EventIdle:        ":zzz:",
+	EventReleased:    ":unlock:",
+}
+
+var eventColor = map[EventType]string{
+	EventBooked:      "#e96d76",
+	EventUnbooked:    "#18be94",
+	EventExpired:     "#f4c030",
+	EventTransferred: "#0dadea",
+	EventIdle:        "#f4c030",
+	EventReleased:    "#18be94",
+}
+
+// formatPayload renders the event in the given format. baseURL is the ArgoCD UI root used for links.
+func formatPayload(f Format, e Event, baseURL string) ([]byte, error) {
+	switch f {
+	case FormatSlack:
+		return json.Marshal(slackPayload(e, baseURL))
+	case FormatMattermost:
+		return json.Marshal(mattermostPayload(e, baseURL))
+	default:
+		return json.Marshal(e)
+	}
+}
+
+type slackText struct {
+	Type string `json:"type"`
+	Text string `json:"text"`
+}
+
+type slackBlock struct {
+	Type     string      `json:"type"`
+	Text     *slackText  `json:"text,omitempty"`
+	Fields   []slackText `json:"fields,omitempty"`
+	Elements []slackText `json:"elements,omitempty"`
+}
+
+type slackMessage struct {
+	Text   string       `json:"text"`
+	Blocks []slackBlock `json:"blocks"`
+}
+
+func slackPayload(e Event, baseURL string) slackMessage {
+	app := "*" + escape(e.AppName) + "*"
+	if link := AppURL(baseURL, e); link != "" {
+		app = "*<" + link + "|" + escape(e.AppName) + ">*"
+	}
+	headline := summary(e.Type, app, escape(e.User), escape(e.PreviousUser))
+
+	fields := []slackText{
+		{Type: "mrkdwn", Text: "*User:*\n" + escape(e.User)},
+		{Type: "mrkdwn", Text: "*Namespace:*\n" + escape(e.Namespace)},
+	}
+	if e.Project != "" {
+		fields = append(fields, slackText{Type: "mrkdwn", Text: "*Project:*\n" + escape(e.Project)})
+	}
+	if e.PreviousUser != "" {
+		fields = append(fields, slackText{Type: "mrkdwn", Text: "*Previous holder:*\n" + escape(e.PreviousUser)})
+	}
+
+	blocks := []slackBlock{
+		{Type: "section", Text: &slackText{Type: "mrkdwn", Text: eventEmoji[e.Type] + " " + headline}},
+		{Type: "section", Fields: fields},
+	}
+	if e.Reason != "" {
+		blocks = append(blocks, slackBlock{
+			Type:     "context",
+			Elements: []slackText{{Type: "mrkdwn", Text: "*Reason:* " + escape(e.Reason)}},
+		})
+	}
+	return slackMessage{Text: escape(Summary(e)), Blocks: blocks}
+}
+
+type mattermostField struct {
+	Title string `json:"title"`
+	Value string `json:"value"`
+	Short bool   `json:"short"`
+}
+
+type mattermostAttachment struct {
+	Fallback  string            `json:"fallback"`
+	Color     string            `json:"color,omitempty"`
+	Title     string            `json:"title"`
+	TitleLink string            `json:"title_link,omitempty"`
+	Text      string            `json:"text,omitempty"`
+	Fields    []mattermostField `json:"fields"`
+}
+
+type mattermostMessage struct {
+	Text        string                 `json:"text"`
+	Attachments []mattermostAttachment `json:"attachments"`
+}
+
+func mattermostPayload(e Event, baseURL string) mattermostMessage {
+	fields := []mattermostField{
+		{Title: "User", Value: escape(e.User), Short: true},
+		{Title: "Namespace", Value: escape(e.Namespace), Short: true},
+	}
+	if e.Project != "" {
+		fields = append(fields, mattermostField{Title: "Project", Value: escape(e.Project), Short: true})
+	}
+	if e.PreviousUser != "" {
+		fields = append(fields, mattermostField{Title: "Previous holder", Value: escape(e.PreviousUser), Short: true})
+	}
+	if e.Reason != "" {
+		fields = append(fields, mattermostField{Title: "Reason", Value: escape(e.Reason)})
+	}
+
+	text := Summary(e)
+	return mattermostMessage{
+		Text: eventEmoji[e.Type] + " " + escape(text),
+		Attachments: []mattermostAttachment{{
+			Fallback:  text,
+			Color:     eventColor[e.Type],
+			Title:     escape(e.AppName),
+			TitleLink: AppURL(baseURL, e),
+			Fields:    fields,
+		}},
+	}
+}
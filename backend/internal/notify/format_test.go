@@ -0,0 +1,122 @@
+package notify
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestParseTargets(t *testing.T) {
+	targets, err := ParseTargets("https://a.example/hook, slack=https://hooks.slack.com/x?a=b ,mattermost=http://mm/hooks/y", nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(targets) != 3 {
+		t.Fatalf("expected 3 targets, got %d", len(targets))
+	}
+	want := []Target{
+		{URL: "https://a.example/hook", Format: FormatJSON},
+		{URL: "https://hooks.slack.com/x?a=b", Format: FormatSlack},
+		{URL: "http://mm/hooks/y", Format: FormatMattermost},
+	}
+	for i, w := range want {
+		if targets[i].URL != w.URL || targets[i].Format != w.Format {
+			t.Errorf("target %d: expected %+v, got %+v", i, w, targets[i])
+		}
+	}
+
+	if _, err := ParseTargets("teams=https://x", nil); err == nil {
+		t.Fatal("expected error for unknown format")
+	}
+}
+
+func TestSlackPayload(t *testing.T) {
+	e := Event{Type: EventBooked, AppName: "my-app", Namespace: "argocd", Project: "staging", User: "alice", Reason: "release testing"}
+	body, err := formatPayload(FormatSlack, e, "https://argocd.example.com/")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	var msg slackMessage
+	if err := json.Unmarshal(body, &msg); err != nil {
+		t.Fatalf("invalid payload: %v", err)
+	}
+	if msg.Text != "my-app booked by alice" {
+		t.Fatalf("unexpected fallback text %q", msg.Text)
+	}
+	if len(msg.Blocks) != 3 {
+		t.Fatalf("expected 3 blocks, got %d", len(msg.Blocks))
+	}
+	if !strings.Contains(msg.Blocks[0].Text.Text, "<https://argocd.example.com/applications/argocd/my-app|my-app>") {
+		t.Fatalf("expected app link in headline, got %q", msg.Blocks[0].Text.Text)
+	}
+	if !strings.Contains(msg.Blocks[2].Elements[0].Text, "release testing") {
+		t.Fatalf("expected reason block, got %+v", msg.Blocks[2])
+	}
+}
+
+func TestSlackPayload_EscapesUserInput(t *testing.T) {
+	e := Event{Type: EventBooked, AppName: "book", Namespace: "argocd", User: "alice", Reason: "<!channel> R&D"}
+	msg := slackPayload(e, "https://argocd.example.com")
+
+	if got := msg.Blocks[0].Text.Text; got != ":lock: *<https://argocd.example.com/applications/argocd/book|book>* booked by alice" {
+		t.Fatalf("unexpected headline %q", got)
+	}
+	if got := msg.Blocks[2].Elements[0].Text; got != "*Reason:* &lt;!channel&gt; R&amp;D" {
+		t.Fatalf("expected the reason to be escaped, got %q", got)
+	}
+}
+
+func TestMattermostPayload(t *testing.T) {
+	e := Event{Type: EventTransferred, AppName: "my-app", Namespace: "argocd", User: "bob", PreviousUser: "alice"}
+	body, err := formatPayload(FormatMattermost, e, "https://argocd.example.com")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	var msg mattermostMessage
+	if err := json.Unmarshal(body, &msg); err != nil {
+		t.Fatalf("invalid payload: %v", err)
+	}
+	if !strings.Contains(msg.Text, "my-app transferred from alice to bob") {
+		t.Fatalf("unexpected text %q", msg.Text)
+	}
+	a := msg.Attachments[0]
+	if a.TitleLink != "https://argocd.example.com/applications/argocd/my-app" {
+		t.Fatalf("unexpected title link %q", a.TitleLink)
+	}
+}
+
+func TestWebhook_SlackTarget(t *testing.T) {
+	bodies := make(chan []byte, 1)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		b, _ := io.ReadAll(r.Body)
+		bodies <- b
+	}))
+	defer srv.Close()
+
+	w := NewWebhook(WebhookConfig{Targets: []Target{{URL: srv.URL, Format: FormatSlack}}})
+	w.Notify(Event{Type: EventUnbooked, AppName: "my-app", Namespace: "argocd", User: "alice"})
+	w.Close()
+
+	var msg slackMessage
+	if err := json.Unmarshal(<-bodies, &msg); err != nil {
+		t.Fatalf("invalid payload: %v", err)
+	}
+	if msg.Text != "my-app released by alice" || len(msg.Blocks) == 0 {
+		t.Fatalf("unexpected slack message: %+v", msg)
+	}
+}
+
+func TestMattermostPayload_EscapesUserInput(t *testing.T) {
+	e := Event{Type: EventBooked, AppName: "my-app", Namespace: "argocd", User: "alice", Reason: "<!channel>"}
+	msg := mattermostPayload(e, "")
+
+	fields := msg.Attachments[0].Fields
+	if got := fields[len(fields)-1].Value; got != "&lt;!channel&gt;" {
+		t.Fatalf("expected the reason to be escaped, got %q", got)
+	}
+}
@@ -0,0 +1,64 @@
+package notify
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// EventType identifies a booking state change.
+type EventType string
+
+const (
+	EventBooked      EventType = "booked"
+	EventUnbooked    EventType = "unbooked"
+	EventExpired     EventType = "expired"
+	EventTransferred EventType = "transferred"
+	// EventIdle warns that a booking is idle; EventReleased reports that the
+	// backend released it. Reason says why.
+	EventIdle     EventType = "idle"
+	EventReleased EventType = "released"
+)
+
+var knownEvents = map[EventType]bool{
+	EventBooked:      true,
+	EventUnbooked:    true,
+	EventExpired:     true,
+	EventTransferred: true,
+	EventIdle:        true,
+	EventReleased:    true,
+}
+
+// Event describes a booking state change delivered to notifiers.
+type Event struct {
+	Type         EventType `json:"type"`
+	AppName      string    `json:"appName"`
+	Namespace    string    `json:"namespace"`
+	Project      string    `json:"project,omitempty"`
+	User         string    `json:"user"`
+	PreviousUser string    `json:"previousUser,omitempty"`
+	Reason       string    `json:"reason,omitempty"`
+	Timestamp    time.Time `json:"timestamp"`
+}
+
+// Notifier delivers booking events. Implementations must not block the caller.
+type Notifier interface {
+	Notify(e Event)
+}
+
+// ParseEventTypes parses a comma-separated list of event types. An empty string yields nil, meaning all events.
+func ParseEventTypes(s string) ([]EventType, error) {
+	var types []EventType
+	for _, part := range strings.Split(s, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		t := EventType(part)
+		if !knownEvents[t] {
+			return nil, fmt.Errorf("unknown event type %q", part)
+		}
+		types = append(types, t)
+	}
+	return types, nil
+}
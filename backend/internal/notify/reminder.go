@@ -0,0 +1,131 @@
+package notify
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"github.com/behavox/argocd-book-plugin/internal/k8s"
+	"github.com/behavox/argocd-book-plugin/internal/logging"
+)
+
+// BookingLister lists current bookings. It is satisfied by k8s.Client.
+type BookingLister interface {
+	ListBookings(ctx context.Context, namespace string) ([]k8s.Booking, error)
+}
+
+// Sender delivers a single email. It is satisfied by *Mailer.
+type Sender interface {
+	Send(to, subject, body string) error
+}
+
+// ReminderConfig configures the expiry reminder job.
+type ReminderConfig struct {
+	// Namespace to scan; empty means all namespaces.
+	Namespace string
+	// Before is how long ahead of expiry the holder is warned.
+	Before time.Duration
+	// Interval between scans.
+	Interval time.Duration
+	// ArgoCDURL is the ArgoCD UI base URL used to link applications.
+	ArgoCDURL string
+}
+
+// Reminder periodically emails booking holders whose bookings are about to expire.
+// Each booking is reminded at most once per expiry time.
+type Reminder struct {
+	cfg       ReminderConfig
+	lister    BookingLister
+	sender    Sender
+	addresses *AddressBook
+	now       func() time.Time
+
+	sent map[string]time.Time // key: namespace/app/expiresAt, value: expiry
+}
+
+// NewReminder creates a Reminder. Call Run to start it.
+func NewReminder(cfg ReminderConfig, lister BookingLister, sender Sender, addresses *AddressBook) *Reminder {
+	if cfg.Before <= 0 {
+		cfg.Before = 15 * time.Minute
+	}
+	if cfg.Interval <= 0 {
+		cfg.Interval = time.Minute
+	}
+	return &Reminder{
+		cfg:       cfg,
+		lister:    lister,
+		sender:    sender,
+		addresses: addresses,
+		now:       time.Now,
+		sent:      make(map[string]time.Time),
+	}
+}
+
+// Run scans for expiring bookings until ctx is cancelled.
+func (r *Reminder) Run(ctx context.Context) {
+	ticker := time.NewTicker(r.cfg.Interval)
+	defer ticker.Stop()
+	for {
+		r.check(ctx)
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (r *Reminder) check(ctx context.Context) {
+	bookings, err := r.lister.ListBookings(ctx, r.cfg.Namespace)
+	if err != nil {
+		slog.Error("reminder: failed to list bookings", logging.KeyError, err)
+		return
+	}
+
+	now := r.now()
+	for key, expiry := range r.sent {
+		if now.After(expiry) {
+			delete(r.sent, key)
+		}
+	}
+
+	for _, b := range bookings {
+		if b.ExpiresAt == "" {
+			continue
+		}
+		expiresAt, err := time.Parse(time.RFC3339, b.ExpiresAt)
+		if err != nil || !expiresAt.After(now) || expiresAt.Sub(now) > r.cfg.Before {
+			continue
+		}
+		key := b.Namespace + "/" + b.AppName + "/" + b.ExpiresAt
+		if _, ok := r.sent[key]; ok {
+			continue
+		}
+
+		to := r.addresses.Lookup(b.BookedBy)
+		if to == "" {
+			slog.Warn("reminder: no email address, skipping", logging.KeyUser, b.BookedBy, logging.KeyNamespace, b.Namespace, logging.KeyApp, b.AppName)
+			r.sent[key] = expiresAt
+			continue
+		}
+
+		subject, body, err := renderReminder(ReminderData{
+			User:      b.BookedBy,
+			AppName:   b.AppName,
+			Namespace: b.Namespace,
+			Project:   b.Project,
+			ExpiresAt: expiresAt,
+			Minutes:   int(expiresAt.Sub(now).Round(time.Minute) / time.Minute),
+			AppURL:    AppURL(r.cfg.ArgoCDURL, Event{AppName: b.AppName, Namespace: b.Namespace}),
+		})
+		if err != nil {
+			slog.Error("reminder: failed to render message", logging.KeyError, err)
+			continue
+		}
+		if err := r.sender.Send(to, subject, body); err != nil {
+			slog.Error("reminder: failed to send email", "to", to, logging.KeyNamespace, b.Namespace, logging.KeyApp, b.AppName, logging.KeyError, err)
+			continue
+		}
+		r.sent[key] = expiresAt
+	}
+}
@@ -0,0 +1,51 @@
+package notify
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/behavox/argocd-book-plugin/internal/k8s"
+)
+
+type staticLister []k8s.Booking
+
+func (l staticLister) ListBookings(_ context.Context, _ string) ([]k8s.Booking, error) {
+	return l, nil
+}
+
+func TestReminder_EmailsExpiringBookingsOnce(t *testing.T) {
+	srv := newFakeSMTP(t)
+	host, port := srv.hostPort()
+	mailer := NewMailer(SMTPConfig{Host: host, Port: port, From: "booking@example.com"})
+
+	now := time.Date(2026, 1, 15, 10, 0, 0, 0, time.UTC)
+	lister := staticLister{
+		{AppName: "soon", Namespace: "argocd", BookedBy: "alice", ExpiresAt: now.Add(10 * time.Minute).Format(time.RFC3339)},
+		{AppName: "later", Namespace: "argocd", BookedBy: "bob", ExpiresAt: now.Add(2 * time.Hour).Format(time.RFC3339)},
+		{AppName: "forever", Namespace: "argocd", BookedBy: "carol"},
+		{AppName: "lapsed", Namespace: "argocd", BookedBy: "dave", ExpiresAt: now.Add(-time.Minute).Format(time.RFC3339)},
+	}
+
+	r := NewReminder(ReminderConfig{Before: 15 * time.Minute, ArgoCDURL: "https://argocd.example.com"},
+		lister, mailer, NewAddressBook(nil, "example.com"))
+	r.now = func() time.Time { return now }
+
+	r.check(context.Background())
+	r.check(context.Background())
+
+	sent := srv.sent()
+	if len(sent) != 1 {
+		t.Fatalf("expected exactly 1 reminder, got %d", len(sent))
+	}
+	if sent[0].to[0] != "alice@example.com" {
+		t.Fatalf("expected reminder to alice, got %v", sent[0].to)
+	}
+	if !strings.Contains(sent[0].data, "soon expires in 10 minutes") {
+		t.Fatalf("unexpected subject:\n%s", sent[0].data)
+	}
+	if !strings.Contains(sent[0].data, "https://argocd.example.com/applications/argocd/soon") {
+		t.Fatalf("expected app link in body:\n%s", sent[0].data)
+	}
+}
@@ -0,0 +1,214 @@
+package notify
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/behavox/argocd-book-plugin/internal/logging"
+)
+
+const (
+	HeaderSignature = "X-Booking-Signature"
+	HeaderEvent     = "X-Booking-Event"
+
+	defaultQueueSize      = 100
+	defaultWorkers        = 2
+	defaultMaxRetries     = 3
+	defaultInitialBackoff = time.Second
+	defaultTimeout        = 10 * time.Second
+)
+
+// Target is a single webhook receiver.
+type Target struct {
+	URL string
+	// Events restricts delivery to the listed event types. Empty means all events.
+	Events []EventType
+	// Format selects the payload shape. Empty means FormatJSON.
+	Format Format
+}
+
+func (t Target) accepts(e EventType) bool {
+	if len(t.Events) == 0 {
+		return true
+	}
+	for _, want := range t.Events {
+		if want == e {
+			return true
+		}
+	}
+	return false
+}
+
+// WebhookConfig configures a Webhook notifier. Zero values fall back to sensible defaults.
+type WebhookConfig struct {
+	Targets []Target
+	// ArgoCDURL is the ArgoCD UI base URL used to link applications in chat formats.
+	ArgoCDURL string
+	// Secret, when set, is used to sign each payload with HMAC-SHA256.
+	Secret    string
+	QueueSize int
+	Workers   int
+	// MaxRetries is the number of retries after a failed attempt. Negative disables retries.
+	MaxRetries int
+	// InitialBackoff is the delay before the first retry; it doubles on every subsequent retry.
+	InitialBackoff time.Duration
+	Timeout        time.Duration
+}
+
+type delivery struct {
+	target Target
+	event  Event
+}
+
+// Webhook POSTs JSON event payloads to the configured targets from a bounded
+// background queue, so callers never wait on remote receivers.
+type Webhook struct {
+	cfg    WebhookConfig
+	client *http.Client
+	queue  chan delivery
+
+	mu     sync.RWMutex
+	closed bool
+	wg     sync.WaitGroup
+}
+
+// NewWebhook creates a Webhook notifier and starts its delivery workers.
+func NewWebhook(cfg WebhookConfig) *Webhook {
+	if cfg.QueueSize <= 0 {
+		cfg.QueueSize = defaultQueueSize
+	}
+	if cfg.Workers <= 0 {
+		cfg.Workers = defaultWorkers
+	}
+	if cfg.MaxRetries < 0 {
+		cfg.MaxRetries = 0
+	} else if cfg.MaxRetries == 0 {
+		cfg.MaxRetries = defaultMaxRetries
+	}
+	if cfg.InitialBackoff <= 0 {
+		cfg.InitialBackoff = defaultInitialBackoff
+	}
+	if cfg.Timeout <= 0 {
+		cfg.Timeout = defaultTimeout
+	}
+
+	w := &Webhook{
+		cfg:    cfg,
+		client: &http.Client{Timeout: cfg.Timeout},
+		queue:  make(chan delivery, cfg.QueueSize),
+	}
+	for i := 0; i < cfg.Workers; i++ {
+		w.wg.Add(1)
+		go w.run()
+	}
+	return w
+}
+
+// Notify enqueues the event for every matching target. If the queue is full the
+// delivery is dropped and logged rather than blocking the caller.
+func (w *Webhook) Notify(e Event) {
+	w.mu.RLock()
+	defer w.mu.RUnlock()
+	if w.closed {
+		return
+	}
+	for _, t := range w.cfg.Targets {
+		if !t.accepts(e.Type) {
+			continue
+		}
+		select {
+		case w.queue <- delivery{target: t, event: e}:
+		default:
+			slog.Warn("webhook queue full, dropping event", "event", e.Type, logging.KeyNamespace, e.Namespace, logging.KeyApp, e.AppName, "url", t.URL)
+		}
+	}
+}
+
+// Close stops accepting new events and waits for queued deliveries to finish.
+func (w *Webhook) Close() {
+	w.mu.Lock()
+	if w.closed {
+		w.mu.Unlock()
+		return
+	}
+	w.closed = true
+	close(w.queue)
+	w.mu.Unlock()
+	w.wg.Wait()
+}
+
+func (w *Webhook) run() {
+	defer w.wg.Done()
+	for d := range w.queue {
+		if err := w.deliver(d); err != nil {
+			slog.Error("webhook delivery failed", "event", d.event.Type, logging.KeyNamespace, d.event.Namespace,
+				logging.KeyApp, d.event.AppName, "url", d.target.URL, logging.KeyError, err)
+		}
+	}
+}
+
+func (w *Webhook) deliver(d delivery) error {
+	body, err := formatPayload(d.target.Format, d.event, w.cfg.ArgoCDURL)
+	if err != nil {
+		return fmt.Errorf("failed to marshal event: %w", err)
+	}
+
+	backoff := w.cfg.InitialBackoff
+	for attempt := 0; ; attempt++ {
+		retry, err := w.post(d.target.URL, d.event.Type, body)
+		if err == nil {
+			return nil
+		}
+		if !retry || attempt >= w.cfg.MaxRetries {
+			return err
+		}
+		time.Sleep(backoff)
+		backoff *= 2
+	}
+}
+
+// post sends a single request. It reports whether a failed attempt is worth retrying.
+func (w *Webhook) post(url string, eventType EventType, body []byte) (bool, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), w.cfg.Timeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return false, fmt.Errorf("failed to build request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(HeaderEvent, string(eventType))
+	if w.cfg.Secret != "" {
+		req.Header.Set(HeaderSignature, Sign(w.cfg.Secret, body))
+	}
+
+	resp, err := w.client.Do(req)
+	if err != nil {
+		return true, err
+	}
+	resp.Body.Close()
+
+	switch {
+	case resp.StatusCode < 300:
+		return false, nil
+	case resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= 500:
+		return true, fmt.Errorf("receiver returned %s", resp.Status)
+	default:
+		return false, fmt.Errorf("receiver returned %s", resp.Status)
+	}
+}
+
+// Sign returns the signature header value for body: "sha256=" followed by the hex HMAC-SHA256 digest.
+func Sign(secret string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
@@ -0,0 +1,179 @@
+package notify
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+type receiver struct {
+	mu       sync.Mutex
+	events   []Event
+	headers  []http.Header
+	received chan struct{}
+}
+
+func newReceiver(t *testing.T, status func(n int) int) (*receiver, *httptest.Server) {
+	rcv := &receiver{received: make(chan struct{}, 100)}
+	var calls int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := int(atomic.AddInt32(&calls, 1))
+		code := http.StatusOK
+		if status != nil {
+			code = status(n)
+		}
+		if code == http.StatusOK {
+			body, _ := io.ReadAll(r.Body)
+			var e Event
+			if err := json.Unmarshal(body, &e); err != nil {
+				t.Errorf("invalid payload: %v", err)
+			}
+			rcv.mu.Lock()
+			rcv.events = append(rcv.events, e)
+			rcv.headers = append(rcv.headers, r.Header.Clone())
+			rcv.mu.Unlock()
+			rcv.received <- struct{}{}
+		}
+		w.WriteHeader(code)
+	}))
+	t.Cleanup(srv.Close)
+	return rcv, srv
+}
+
+func (r *receiver) wait(t *testing.T, n int) {
+	t.Helper()
+	for i := 0; i < n; i++ {
+		select {
+		case <-r.received:
+		case <-time.After(5 * time.Second):
+			t.Fatalf("timed out waiting for delivery %d", i+1)
+		}
+	}
+}
+
+func TestWebhook_DeliversSignedPayload(t *testing.T) {
+	rcv, srv := newReceiver(t, nil)
+	w := NewWebhook(WebhookConfig{
+		Targets: []Target{{URL: srv.URL}},
+		Secret:  "s3cret",
+	})
+	defer w.Close()
+
+	w.Notify(Event{Type: EventBooked, AppName: "my-app", Namespace: "argocd", User: "alice"})
+	rcv.wait(t, 1)
+
+	rcv.mu.Lock()
+	defer rcv.mu.Unlock()
+	if rcv.events[0].AppName != "my-app" || rcv.events[0].User != "alice" {
+		t.Fatalf("unexpected event: %+v", rcv.events[0])
+	}
+	if got := rcv.headers[0].Get(HeaderEvent); got != string(EventBooked) {
+		t.Fatalf("expected event header %q, got %q", EventBooked, got)
+	}
+	body, _ := json.Marshal(rcv.events[0])
+	if got, want := rcv.headers[0].Get(HeaderSignature), Sign("s3cret", body); got != want {
+		t.Fatalf("expected signature %q, got %q", want, got)
+	}
+}
+
+func TestWebhook_EventFilter(t *testing.T) {
+	rcv, srv := newReceiver(t, nil)
+	w := NewWebhook(WebhookConfig{
+		Targets: []Target{{URL: srv.URL, Events: []EventType{EventUnbooked}}},
+	})
+
+	w.Notify(Event{Type: EventBooked, AppName: "my-app"})
+	w.Notify(Event{Type: EventUnbooked, AppName: "my-app"})
+	w.Close()
+
+	rcv.mu.Lock()
+	defer rcv.mu.Unlock()
+	if len(rcv.events) != 1 || rcv.events[0].Type != EventUnbooked {
+		t.Fatalf("expected only the unbooked event, got %+v", rcv.events)
+	}
+}
+
+func TestWebhook_RetriesOnServerError(t *testing.T) {
+	rcv, srv := newReceiver(t, func(n int) int {
+		if n < 3 {
+			return http.StatusServiceUnavailable
+		}
+		return http.StatusOK
+	})
+	w := NewWebhook(WebhookConfig{
+		Targets:        []Target{{URL: srv.URL}},
+		InitialBackoff: time.Millisecond,
+	})
+	defer w.Close()
+
+	w.Notify(Event{Type: EventBooked, AppName: "my-app"})
+	rcv.wait(t, 1)
+}
+
+func TestWebhook_NoRetryOnClientError(t *testing.T) {
+	var calls int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&calls, 1)
+		w.WriteHeader(http.StatusBadRequest)
+	}))
+	defer srv.Close()
+
+	w := NewWebhook(WebhookConfig{
+		Targets:        []Target{{URL: srv.URL}},
+		InitialBackoff: time.Millisecond,
+	})
+	w.Notify(Event{Type: EventBooked, AppName: "my-app"})
+	w.Close()
+
+	if n := atomic.LoadInt32(&calls); n != 1 {
+		t.Fatalf("expected 1 attempt, got %d", n)
+	}
+}
+
+func TestWebhook_NotifyDoesNotBlockWhenQueueFull(t *testing.T) {
+	block := make(chan struct{})
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-block
+	}))
+	defer srv.Close()
+	defer close(block)
+
+	w := NewWebhook(WebhookConfig{
+		Targets:   []Target{{URL: srv.URL}},
+		QueueSize: 1,
+		Workers:   1,
+	})
+
+	done := make(chan struct{})
+	go func() {
+		for i := 0; i < 10; i++ {
+			w.Notify(Event{Type: EventBooked, AppName: "my-app"})
+		}
+		close(done)
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("Notify blocked on a full queue")
+	}
+}
+
+func TestParseEventTypes(t *testing.T) {
+	types, err := ParseEventTypes("booked, unbooked")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(types) != 2 || types[0] != EventBooked || types[1] != EventUnbooked {
+		t.Fatalf("unexpected types: %v", types)
+	}
+
+	if _, err := ParseEventTypes("booked,bogus"); err == nil {
+		t.Fatal("expected error for unknown event type")
+	}
+}
@@ -0,0 +1,238 @@
+// Package policy holds per-project and per-namespace booking rules, such as a
+// required reason, a maximum booking duration or the groups allowed to book.
+package policy
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+var (
+	// ErrUnprocessable is returned when a booking request breaks a policy rule
+	// the caller can fix, such as a missing reason or a too long duration.
+	ErrUnprocessable = errors.New("booking violates policy")
+	// ErrForbidden is returned when the caller may not book under a policy.
+	ErrForbidden = errors.New("forbidden by policy")
+)
+
+// Duration is a time.Duration written as a Go duration string such as "2h".
+type Duration time.Duration
+
+func (d Duration) MarshalJSON() ([]byte, error) {
+	return json.Marshal(time.Duration(d).String())
+}
+
+func (d *Duration) UnmarshalJSON(b []byte) error {
+	var s string
+	if err := json.Unmarshal(b, &s); err != nil {
+		return fmt.Errorf("expected a duration string such as \"2h\"")
+	}
+	v, err := time.ParseDuration(s)
+	if err != nil {
+		return err
+	}
+	*d = Duration(v)
+	return nil
+}
+
+// IdleAction is what happens to a booking once it is idle.
+type IdleAction string
+
+const (
+	// IdleWarn notifies that the booking is idle, once per booking.
+	IdleWarn IdleAction = "warn"
+	// IdleRelease unbooks the application.
+	IdleRelease IdleAction = "release"
+)
+
+// Policy is the set of rules for the applications matching Project and
+// Namespace. An empty Project or Namespace matches any value.
+type Policy struct {
+	Project   string `json:"project,omitempty"`
+	Namespace string `json:"namespace,omitempty"`
+
+	// RequireReason rejects bookings without a reason.
+	RequireReason bool `json:"requireReason,omitempty"`
+	// MaxDuration caps the duration of a booking; bookings without a duration
+	// get DefaultDuration, or MaxDuration when that is unset.
+	MaxDuration     Duration `json:"maxDuration,omitempty"`
+	DefaultDuration Duration `json:"defaultDuration,omitempty"`
+	// AllowedGroups, when set, limits booking to members of these groups.
+	AllowedGroups []string `json:"allowedGroups,omitempty"`
+	// AdminOverride lets admins unbook or transfer others' bookings. Defaults to true.
+	AdminOverride *bool `json:"adminOverride,omitempty"`
+	// IdleTimeout, when set, marks a booking idle once its application has not
+	// been synced for that long since it was booked; IdleAction, warn by
+	// default, says what happens then.
+	IdleTimeout Duration   `json:"idleTimeout,omitempty"`
+	IdleAction  IdleAction `json:"idleAction,omitempty"`
+	// AutoBook books a free application for the user who starts a manual sync
+	// of it, for AutoBookDuration, when the auto-book controller runs.
+	AutoBook         bool     `json:"autoBook,omitempty"`
+	AutoBookDuration Duration `json:"autoBookDuration,omitempty"`
+	// PauseAutoSync turns automated sync off while an application is booked,
+	// so Git changes by others are not synced over it, and back on afterwards.
+	PauseAutoSync bool `json:"pauseAutoSync,omitempty"`
+}
+
+// DefaultAutoBookDuration is how long an automatic booking lasts when the
+// policy sets no autoBookDuration.
+const DefaultAutoBookDuration = 30 * time.Minute
+
+// specificity ranks how closely p targets an application: project and namespace
+// beat project alone, which beats namespace alone, which beats a catch-all.
+func (p Policy) specificity() int {
+	n := 0
+	if p.Project != "" {
+		n += 2
+	}
+	if p.Namespace != "" {
+		n++
+	}
+	return n
+}
+
+func (p Policy) matches(namespace, project string) bool {
+	return (p.Project == "" || p.Project == project) && (p.Namespace == "" || p.Namespace == namespace)
+}
+
+// scope describes the applications p applies to, for error messages.
+func (p Policy) scope() string {
+	switch {
+	case p.Project != "" && p.Namespace != "":
+		return fmt.Sprintf("project %q in namespace %q", p.Project, p.Namespace)
+	case p.Project != "":
+		return fmt.Sprintf("project %q", p.Project)
+	case p.Namespace != "":
+		return fmt.Sprintf("namespace %q", p.Namespace)
+	}
+	return "all applications"
+}
+
+// AllowsAdminOverride reports whether admins may release or transfer bookings
+// held by others.
+func (p Policy) AllowsAdminOverride() bool {
+	return p.AdminOverride == nil || *p.AdminOverride
+}
+
+// AutoBookTTL returns how long an automatic booking lasts: AutoBookDuration or
+// DefaultAutoBookDuration, capped at MaxDuration.
+func (p Policy) AutoBookTTL() time.Duration {
+	d := time.Duration(p.AutoBookDuration)
+	if d == 0 {
+		d = DefaultAutoBookDuration
+	}
+	if p.MaxDuration > 0 && d > time.Duration(p.MaxDuration) {
+		d = time.Duration(p.MaxDuration)
+	}
+	return d
+}
+
+// Book checks a booking request by a member of groups against p and returns
+// the duration to book for, which applies the policy default when d is zero.
+// Errors wrap ErrForbidden or ErrUnprocessable.
+func (p Policy) Book(reason string, d time.Duration, groups []string) (time.Duration, error) {
+	if len(p.AllowedGroups) > 0 && !memberOf(groups, p.AllowedGroups) {
+		return 0, fmt.Errorf("%w: only members of %s may book applications in %s",
+			ErrForbidden, strings.Join(p.AllowedGroups, ", "), p.scope())
+	}
+	if p.RequireReason && strings.TrimSpace(reason) == "" {
+		return 0, fmt.Errorf("%w: a reason is required to book applications in %s", ErrUnprocessable, p.scope())
+	}
+	if d == 0 {
+		d = time.Duration(p.DefaultDuration)
+		if d == 0 {
+			d = time.Duration(p.MaxDuration)
+		}
+	}
+	if p.MaxDuration > 0 && d > time.Duration(p.MaxDuration) {
+		return 0, fmt.Errorf("%w: bookings in %s may last at most %s",
+			ErrUnprocessable, p.scope(), time.Duration(p.MaxDuration))
+	}
+	return d, nil
+}
+
+// Transfer checks handing a booking over to target against p. The target's
+// groups are not known, so a policy limited to AllowedGroups refuses every
+// transfer with an ErrForbidden error.
+func (p Policy) Transfer(target string) error {
+	if len(p.AllowedGroups) > 0 {
+		return fmt.Errorf("%w: only members of %s may book applications in %s, and the groups of %s are not known",
+			ErrForbidden, strings.Join(p.AllowedGroups, ", "), p.scope(), target)
+	}
+	return nil
+}
+
+func memberOf(groups, allowed []string) bool {
+	for _, g := range groups {
+		for _, a := range allowed {
+			if g == a {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// Set is an ordered list of policies. The zero value and a nil *Set apply no rules.
+type Set struct {
+	policies []Policy
+}
+
+// New validates policies and returns them as a Set.
+func New(policies []Policy) (*Set, error) {
+	seen := map[[2]string]bool{}
+	for i, p := range policies {
+		key := [2]string{p.Project, p.Namespace}
+		if seen[key] {
+			return nil, fmt.Errorf("policy %d: duplicate policy for %s", i, p.scope())
+		}
+		seen[key] = true
+		if p.MaxDuration < 0 || p.DefaultDuration < 0 {
+			return nil, fmt.Errorf("policy %d: durations must not be negative", i)
+		}
+		if p.MaxDuration > 0 && p.DefaultDuration > p.MaxDuration {
+			return nil, fmt.Errorf("policy %d: defaultDuration exceeds maxDuration", i)
+		}
+		if p.IdleTimeout < 0 {
+			return nil, fmt.Errorf("policy %d: idleTimeout must not be negative", i)
+		}
+		switch p.IdleAction {
+		case "", IdleWarn, IdleRelease:
+		default:
+			return nil, fmt.Errorf("policy %d: idleAction must be %q or %q", i, IdleWarn, IdleRelease)
+		}
+		if p.IdleAction != "" && p.IdleTimeout == 0 {
+			return nil, fmt.Errorf("policy %d: idleAction requires idleTimeout", i)
+		}
+		if p.AutoBookDuration < 0 {
+			return nil, fmt.Errorf("policy %d: autoBookDuration must not be negative", i)
+		}
+		if p.AutoBookDuration > 0 && !p.AutoBook {
+			return nil, fmt.Errorf("policy %d: autoBookDuration requires autoBook", i)
+		}
+		if p.MaxDuration > 0 && p.AutoBookDuration > p.MaxDuration {
+			return nil, fmt.Errorf("policy %d: autoBookDuration exceeds maxDuration", i)
+		}
+	}
+	return &Set{policies: policies}, nil
+}
+
+// For returns the most specific policy matching an application, or the zero
+// Policy, which allows everything, when none does.
+func (s *Set) For(namespace, project string) Policy {
+	var best Policy
+	found := false
+	if s == nil {
+		return best
+	}
+	for _, p := range s.policies {
+		if p.matches(namespace, project) && (!found || p.specificity() > best.specificity()) {
+			best, found = p, true
+		}
+	}
+	return best
+}
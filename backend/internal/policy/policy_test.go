@@ -0,0 +1,129 @@
+package policy
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func testPolicies(t *testing.T) *Set {
+	t.Helper()
+	noOverride := false
+	s, err := New([]Policy{
+		{RequireReason: false},
+		{Project: "prod", RequireReason: true, MaxDuration: Duration(2 * time.Hour),
+			AllowedGroups: []string{"sre", "release"}, AdminOverride: &noOverride},
+		{Project: "prod", Namespace: "argocd-eu", MaxDuration: Duration(30 * time.Minute)},
+		{Namespace: "sandbox", DefaultDuration: Duration(8 * time.Hour)},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	return s
+}
+
+func TestFor_MostSpecificPolicyWins(t *testing.T) {
+	s := testPolicies(t)
+
+	tests := []struct {
+		namespace, project string
+		wantMax            time.Duration
+		wantReason         bool
+	}{
+		{"argocd", "prod", 2 * time.Hour, true},
+		{"argocd-eu", "prod", 30 * time.Minute, false},
+		{"sandbox", "prod", 2 * time.Hour, true},
+		{"sandbox", "dev", 0, false},
+		{"argocd", "dev", 0, false},
+	}
+	for _, tt := range tests {
+		p := s.For(tt.namespace, tt.project)
+		if time.Duration(p.MaxDuration) != tt.wantMax || p.RequireReason != tt.wantReason {
+			t.Errorf("%s/%s: unexpected policy %+v", tt.namespace, tt.project, p)
+		}
+	}
+	if d := s.For("sandbox", "dev").DefaultDuration; time.Duration(d) != 8*time.Hour {
+		t.Errorf("expected the sandbox default duration, got %s", time.Duration(d))
+	}
+}
+
+func TestFor_NilSetAllowsEverything(t *testing.T) {
+	var s *Set
+	p := s.For("argocd", "prod")
+	d, err := p.Book("", 0, nil)
+	if err != nil || d != 0 || !p.AllowsAdminOverride() {
+		t.Fatalf("expected no restrictions, got %+v, %s, %v", p, d, err)
+	}
+}
+
+func TestBook(t *testing.T) {
+	prod := testPolicies(t).For("argocd", "prod")
+	sre := []string{"dev", "sre"}
+
+	tests := []struct {
+		name    string
+		reason  string
+		d       time.Duration
+		groups  []string
+		want    time.Duration
+		wantErr error
+	}{
+		{"allowed with reason", "release", time.Hour, sre, time.Hour, nil},
+		{"defaults to the maximum", "release", 0, sre, 2 * time.Hour, nil},
+		{"missing reason", " ", time.Hour, sre, 0, ErrUnprocessable},
+		{"too long", "release", 3 * time.Hour, sre, 0, ErrUnprocessable},
+		{"group not allowed", "release", time.Hour, []string{"dev"}, 0, ErrForbidden},
+	}
+	for _, tt := range tests {
+		d, err := prod.Book(tt.reason, tt.d, tt.groups)
+		if !errors.Is(err, tt.wantErr) || (tt.wantErr != nil) != (err != nil) {
+			t.Errorf("%s: expected %v, got %v", tt.name, tt.wantErr, err)
+		}
+		if d != tt.want {
+			t.Errorf("%s: expected duration %s, got %s", tt.name, tt.want, d)
+		}
+	}
+	if prod.AllowsAdminOverride() {
+		t.Error("expected admin override to be disabled for prod")
+	}
+	if err := prod.Transfer("bob"); !errors.Is(err, ErrForbidden) {
+		t.Errorf("expected a transfer under a group restriction to be forbidden, got %v", err)
+	}
+}
+
+func TestNew_Invalid(t *testing.T) {
+	h := Duration(time.Hour)
+	for _, tc := range []struct {
+		name     string
+		policies []Policy
+	}{
+		{"duplicate", []Policy{{Project: "prod"}, {Project: "prod"}}},
+		{"default above maximum", []Policy{{MaxDuration: h, DefaultDuration: 2 * h}}},
+		{"negative duration", []Policy{{MaxDuration: -h}}},
+		{"negative idle timeout", []Policy{{IdleTimeout: -h}}},
+		{"unknown idle action", []Policy{{IdleTimeout: 4 * h, IdleAction: "delete"}}},
+		{"idle action without timeout", []Policy{{IdleAction: IdleRelease}}},
+		{"auto-book duration without auto-book", []Policy{{AutoBookDuration: h}}},
+		{"negative auto-book duration", []Policy{{AutoBook: true, AutoBookDuration: -h}}},
+		{"auto-book above maximum", []Policy{{AutoBook: true, AutoBookDuration: 2 * h, MaxDuration: h}}},
+	} {
+		if _, err := New(tc.policies); err == nil {
+			t.Errorf("%s: expected an error", tc.name)
+		}
+	}
+}
+
+func TestAutoBookTTL(t *testing.T) {
+	for _, tc := range []struct {
+		p    Policy
+		want time.Duration
+	}{
+		{Policy{AutoBook: true}, DefaultAutoBookDuration},
+		{Policy{AutoBook: true, AutoBookDuration: Duration(10 * time.Minute)}, 10 * time.Minute},
+		{Policy{AutoBook: true, MaxDuration: Duration(15 * time.Minute)}, 15 * time.Minute},
+	} {
+		if got := tc.p.AutoBookTTL(); got != tc.want {
+			t.Errorf("AutoBookTTL(%+v) = %s, want %s", tc.p, got, tc.want)
+		}
+	}
+}
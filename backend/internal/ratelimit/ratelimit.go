@@ -0,0 +1,131 @@
+// Package ratelimit throttles requests with token buckets, one per user and
+// one shared by everybody.
+package ratelimit
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"golang.org/x/time/rate"
+)
+
+// idleTimeout is how long a user's bucket is kept after their last request.
+// By then it has refilled under any sensible limit, so dropping it loses nothing.
+const idleTimeout = 10 * time.Minute
+
+// Limit is a token bucket: PerMinute requests a minute on average, in bursts of
+// up to Burst. A zero PerMinute disables the limit.
+type Limit struct {
+	PerMinute float64 `json:"perMinute"`
+	Burst     int     `json:"burst"`
+}
+
+// Validate checks that l is disabled or allows at least one request.
+func (l Limit) Validate() error {
+	if l.PerMinute < 0 {
+		return fmt.Errorf("perMinute must not be negative")
+	}
+	if l.PerMinute > 0 && l.Burst < 1 {
+		return fmt.Errorf("burst must be at least 1")
+	}
+	return nil
+}
+
+func (l Limit) enabled() bool {
+	return l.PerMinute > 0
+}
+
+func (l Limit) rate() rate.Limit {
+	return rate.Limit(l.PerMinute / 60)
+}
+
+// Scope names the bucket that rejected a request.
+type Scope string
+
+const (
+	ScopeUser   Scope = "user"
+	ScopeGlobal Scope = "global"
+)
+
+// Limiter holds the buckets. Limits are passed on every call, so changing them
+// takes effect immediately and keeps the tokens already spent.
+type Limiter struct {
+	mu        sync.Mutex
+	global    *rate.Limiter
+	users     map[string]*userBucket
+	lastSweep time.Time
+}
+
+type userBucket struct {
+	limiter  *rate.Limiter
+	lastSeen time.Time
+}
+
+// New returns a Limiter with full buckets.
+func New() *Limiter {
+	return &Limiter{users: map[string]*userBucket{}}
+}
+
+// Allow takes a token from user's bucket and from the global one at now. When
+// either is empty it takes nothing and returns the bucket's Scope and how long
+// until a token is available. An empty user is only subject to the global limit.
+func (l *Limiter) Allow(user string, perUser, global Limit, now time.Time) (ok bool, scope Scope, retryAfter time.Duration) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.sweep(now)
+
+	var userRes *rate.Reservation
+	if user != "" && perUser.enabled() {
+		b, found := l.users[user]
+		if !found {
+			b = &userBucket{limiter: rate.NewLimiter(perUser.rate(), perUser.Burst)}
+			l.users[user] = b
+		}
+		b.lastSeen = now
+		userRes = reserve(b.limiter, perUser, now)
+		if d := userRes.DelayFrom(now); d > 0 {
+			userRes.CancelAt(now)
+			return false, ScopeUser, d
+		}
+	}
+
+	if global.enabled() {
+		if l.global == nil {
+			l.global = rate.NewLimiter(global.rate(), global.Burst)
+		}
+		res := reserve(l.global, global, now)
+		if d := res.DelayFrom(now); d > 0 {
+			res.CancelAt(now)
+			if userRes != nil {
+				userRes.CancelAt(now)
+			}
+			return false, ScopeGlobal, d
+		}
+	}
+	return true, "", 0
+}
+
+// reserve applies lim to bucket and reserves a token.
+func reserve(bucket *rate.Limiter, lim Limit, now time.Time) *rate.Reservation {
+	if bucket.Limit() != lim.rate() {
+		bucket.SetLimitAt(now, lim.rate())
+	}
+	if bucket.Burst() != lim.Burst {
+		bucket.SetBurstAt(now, lim.Burst)
+	}
+	return bucket.ReserveN(now, 1)
+}
+
+// sweep drops the buckets of users idle for idleTimeout, at most once a minute.
+func (l *Limiter) sweep(now time.Time) {
+	if now.Sub(l.lastSweep) < time.Minute {
+		return
+	}
+	l.lastSweep = now
+	for user, b := range l.users {
+		if now.Sub(b.lastSeen) >= idleTimeout {
+			delete(l.users, user)
+		}
+	}
+}
@@ -0,0 +1,89 @@
+package ratelimit
+
+import (
+	"testing"
+	"time"
+)
+
+func TestLimiter_PerUser(t *testing.T) {
+	l := New()
+	now := time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC)
+	perUser := Limit{PerMinute: 6, Burst: 2}
+
+	for i := 0; i < 2; i++ {
+		if ok, _, _ := l.Allow("alice", perUser, Limit{}, now); !ok {
+			t.Fatalf("request %d: expected the burst to be allowed", i)
+		}
+	}
+	ok, scope, retry := l.Allow("alice", perUser, Limit{}, now)
+	if ok || scope != ScopeUser || retry != 10*time.Second {
+		t.Fatalf("expected alice to wait 10s, got ok=%v scope=%q retry=%s", ok, scope, retry)
+	}
+	if ok, _, _ := l.Allow("bob", perUser, Limit{}, now); !ok {
+		t.Fatal("expected bob to have a separate bucket")
+	}
+	if ok, _, _ := l.Allow("alice", perUser, Limit{}, now.Add(10*time.Second)); !ok {
+		t.Fatal("expected a token after 10s")
+	}
+}
+
+func TestLimiter_Global(t *testing.T) {
+	l := New()
+	now := time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC)
+	perUser := Limit{PerMinute: 60, Burst: 1}
+	global := Limit{PerMinute: 60, Burst: 2}
+
+	l.Allow("alice", perUser, global, now)
+	l.Allow("bob", perUser, global, now)
+	ok, scope, retry := l.Allow("carol", perUser, global, now)
+	if ok || scope != ScopeGlobal || retry != time.Second {
+		t.Fatalf("expected the global bucket to be empty, got ok=%v scope=%q retry=%s", ok, scope, retry)
+	}
+	// The rejected request did not spend carol's own token.
+	if ok, _, _ := l.Allow("carol", perUser, global, now.Add(time.Second)); !ok {
+		t.Fatal("expected carol to be allowed once the global bucket refilled")
+	}
+	if ok, _, _ := l.Allow("", Limit{}, Limit{}, now); !ok {
+		t.Fatal("expected disabled limits to allow everything")
+	}
+}
+
+func TestLimiter_AppliesChangedLimits(t *testing.T) {
+	l := New()
+	now := time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC)
+	l.Allow("alice", Limit{PerMinute: 60, Burst: 1}, Limit{}, now)
+	if ok, _, _ := l.Allow("alice", Limit{PerMinute: 60, Burst: 1}, Limit{}, now); ok {
+		t.Fatal("expected the bucket to be empty")
+	}
+	ok, _, retry := l.Allow("alice", Limit{PerMinute: 6, Burst: 1}, Limit{}, now)
+	if ok || retry != 10*time.Second {
+		t.Fatalf("expected the lowered rate to apply, got ok=%v retry=%s", ok, retry)
+	}
+}
+
+func TestLimiter_DropsIdleBuckets(t *testing.T) {
+	l := New()
+	now := time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC)
+	perUser := Limit{PerMinute: 1, Burst: 1}
+	l.Allow("alice", perUser, Limit{}, now)
+	l.Allow("bob", perUser, Limit{}, now.Add(idleTimeout))
+	if _, found := l.users["alice"]; found || len(l.users) != 1 {
+		t.Fatalf("expected alice's bucket to be dropped, have %d buckets", len(l.users))
+	}
+}
+
+func TestLimit_Validate(t *testing.T) {
+	for _, tc := range []struct {
+		limit Limit
+		ok    bool
+	}{
+		{Limit{}, true},
+		{Limit{PerMinute: 10, Burst: 5}, true},
+		{Limit{PerMinute: -1}, false},
+		{Limit{PerMinute: 10}, false},
+	} {
+		if err := tc.limit.Validate(); (err == nil) != tc.ok {
+			t.Errorf("Validate(%+v) = %v, want ok=%v", tc.limit, err, tc.ok)
+		}
+	}
+}
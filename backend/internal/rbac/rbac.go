@@ -0,0 +1,225 @@
+// Package rbac evaluates ArgoCD's RBAC policy, the casbin-format policy.csv of
+// the argocd-rbac-cm ConfigMap, so the backend can require ArgoCD permissions
+// such as "applications, sync" before acting on an application.
+package rbac
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"regexp"
+	"sort"
+	"strings"
+	"sync/atomic"
+)
+
+// ConfigMap keys read from argocd-rbac-cm.
+const (
+	keyPolicy        = "policy.csv"
+	keyDefaultPolicy = "policy.default"
+	keyMatchMode     = "policy.matchMode"
+)
+
+// ResourceApplications is the ArgoCD RBAC resource of Applications.
+const ResourceApplications = "applications"
+
+// builtinPolicy mirrors the application rules of ArgoCD's built-in policy.
+const builtinPolicy = `
+p, role:readonly, applications, get, */*, allow
+p, role:admin, applications, create, */*, allow
+p, role:admin, applications, update, */*, allow
+p, role:admin, applications, delete, */*, allow
+p, role:admin, applications, sync, */*, allow
+p, role:admin, applications, override, */*, allow
+p, role:admin, applications, action/*, */*, allow
+g, role:admin, role:readonly
+g, admin, role:admin
+`
+
+type rule struct {
+	subject                  string
+	resource, action, object *regexp.Regexp
+	deny                     bool
+}
+
+// Policy is a parsed RBAC policy. Use Parse to build one.
+type Policy struct {
+	defaultRole string
+	regex       bool
+	rules       []rule
+	roles       map[string][]string // subject -> roles and groups it is assigned
+}
+
+// Parse builds a Policy from the data of argocd-rbac-cm: policy.csv, any
+// policy.<name>.csv overlays (applied in key order), policy.default and
+// policy.matchMode ("glob", the default, or "regex"). ArgoCD's built-in roles
+// role:readonly and role:admin are always defined.
+func Parse(data map[string]string) (*Policy, error) {
+	p := &Policy{defaultRole: strings.TrimSpace(data[keyDefaultPolicy]), roles: map[string][]string{}}
+	switch mode := strings.TrimSpace(data[keyMatchMode]); mode {
+	case "", "glob":
+	case "regex":
+		p.regex = true
+	default:
+		return nil, fmt.Errorf("invalid %s %q (expected glob or regex)", keyMatchMode, mode)
+	}
+
+	var overlays []string
+	for key := range data {
+		if key != keyPolicy && strings.HasPrefix(key, "policy.") && strings.HasSuffix(key, ".csv") {
+			overlays = append(overlays, key)
+		}
+	}
+	sort.Strings(overlays)
+
+	// The built-in rules use glob patterns, so they are added in glob mode.
+	if err := p.addCSV("built-in policy", builtinPolicy, false); err != nil {
+		return nil, err
+	}
+	for _, key := range append([]string{keyPolicy}, overlays...) {
+		if err := p.addCSV(key, data[key], p.regex); err != nil {
+			return nil, err
+		}
+	}
+	return p, nil
+}
+
+func (p *Policy) addCSV(source, text string, regex bool) error {
+	r := csv.NewReader(strings.NewReader(text))
+	r.Comment = '#'
+	r.FieldsPerRecord = -1
+	r.TrimLeadingSpace = true
+	for {
+		rec, err := r.Read()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("%s: %w", source, err)
+		}
+		for i := range rec {
+			rec[i] = strings.TrimSpace(rec[i])
+		}
+		line, _ := r.FieldPos(0)
+		switch rec[0] {
+		case "p":
+			if len(rec) != 5 && len(rec) != 6 {
+				return fmt.Errorf("%s:%d: expected p, subject, resource, action, object[, effect]", source, line)
+			}
+			ru := rule{subject: rec[1]}
+			for i, dst := range []**regexp.Regexp{&ru.resource, &ru.action, &ru.object} {
+				if *dst, err = compile(rec[2+i], regex); err != nil {
+					return fmt.Errorf("%s:%d: %w", source, line, err)
+				}
+			}
+			if len(rec) == 6 {
+				switch rec[5] {
+				case "allow":
+				case "deny":
+					ru.deny = true
+				default:
+					return fmt.Errorf("%s:%d: invalid effect %q (expected allow or deny)", source, line, rec[5])
+				}
+			}
+			p.rules = append(p.rules, ru)
+		case "g":
+			if len(rec) != 3 {
+				return fmt.Errorf("%s:%d: expected g, subject, role", source, line)
+			}
+			p.roles[rec[1]] = append(p.roles[rec[1]], rec[2])
+		default:
+			return fmt.Errorf("%s:%d: unknown policy type %q", source, line, rec[0])
+		}
+	}
+}
+
+// subjects returns sub and every role it inherits, directly or transitively.
+func (p *Policy) subjects(sub string) map[string]bool {
+	seen := map[string]bool{sub: true}
+	queue := []string{sub}
+	for len(queue) > 0 {
+		s := queue[0]
+		queue = queue[1:]
+		for _, role := range p.roles[s] {
+			if !seen[role] {
+				seen[role] = true
+				queue = append(queue, role)
+			}
+		}
+	}
+	return seen
+}
+
+// compile turns a policy pattern into an anchored regexp. In glob mode "*"
+// matches any run of characters, including "/", and "?" exactly one.
+func compile(pattern string, regex bool) (*regexp.Regexp, error) {
+	if !regex {
+		pattern = regexp.QuoteMeta(pattern)
+		pattern = strings.NewReplacer(`\*`, ".*", `\?`, ".").Replace(pattern)
+	}
+	return regexp.Compile("^(?:" + pattern + ")$")
+}
+
+// enforceSubject applies casbin's allow-override-by-deny effect to the rules of
+// sub and its roles.
+func (p *Policy) enforceSubject(sub, resource, action, object string) bool {
+	subjects := p.subjects(sub)
+	allowed := false
+	for _, ru := range p.rules {
+		if !subjects[ru.subject] || !ru.resource.MatchString(resource) || !ru.action.MatchString(action) || !ru.object.MatchString(object) {
+			continue
+		}
+		if ru.deny {
+			return false
+		}
+		allowed = true
+	}
+	return allowed
+}
+
+// Enforce reports whether user, or any of groups, may perform action on object.
+// As in ArgoCD, the default role is checked first, and a deny rule only revokes
+// what the same subject would otherwise be allowed.
+func (p *Policy) Enforce(user string, groups []string, resource, action, object string) bool {
+	if p.defaultRole != "" && p.enforceSubject(p.defaultRole, resource, action, object) {
+		return true
+	}
+	for _, sub := range append([]string{user}, groups...) {
+		if sub != "" && p.enforceSubject(sub, resource, action, object) {
+			return true
+		}
+	}
+	return false
+}
+
+// Enforcer evaluates the current Policy. The policy can be replaced at any time
+// with SetPolicy, e.g. by a Watcher, while requests are being authorised.
+type Enforcer struct {
+	policy           atomic.Pointer[Policy]
+	controlNamespace string
+}
+
+// NewEnforcer returns an Enforcer holding only the built-in policy.
+// controlNamespace is the namespace ArgoCD runs in; Applications outside it
+// are addressed as <project>/<namespace>/<name> in policies.
+func NewEnforcer(controlNamespace string) *Enforcer {
+	e := &Enforcer{controlNamespace: controlNamespace}
+	p, _ := Parse(nil)
+	e.SetPolicy(p)
+	return e
+}
+
+// SetPolicy replaces the policy used by e.
+func (e *Enforcer) SetPolicy(p *Policy) {
+	e.policy.Store(p)
+}
+
+// AllowApp reports whether user, as a member of groups, may perform action on
+// the Application namespace/name in project.
+func (e *Enforcer) AllowApp(user string, groups []string, action, project, namespace, name string) bool {
+	object := project + "/" + name
+	if namespace != e.controlNamespace {
+		object = project + "/" + namespace + "/" + name
+	}
+	return e.policy.Load().Enforce(user, groups, ResourceApplications, action, object)
+}
@@ -0,0 +1,103 @@
+package rbac
+
+import "testing"
+
+var testData = map[string]string{
+	"policy.default": "role:readonly",
+	"policy.csv": `
+# Developers may sync anything in dev, and staging apps except the database.
+p, role:developer, applications, sync, dev/*, allow
+p, role:developer, applications, sync, staging/*, allow
+p, role:developer, applications, sync, staging/db, deny
+g, my-org:developers, role:developer
+g, role:lead, role:developer
+g, carol, role:lead
+p, role:release, applications, *, prod/*/*, allow
+`,
+	"policy.release.csv": `g, my-org:release, role:release`,
+}
+
+func TestEnforce(t *testing.T) {
+	p, err := Parse(testData)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	devs := []string{"my-org:developers"}
+	tests := []struct {
+		name   string
+		user   string
+		groups []string
+		action string
+		object string
+		want   bool
+	}{
+		{"default role can get", "bob", nil, "get", "prod/api", true},
+		{"default role cannot sync", "bob", nil, "sync", "dev/api", false},
+		{"group role can sync", "bob", devs, "sync", "dev/api", true},
+		{"deny overrides allow", "bob", devs, "sync", "staging/db", false},
+		{"transitive role", "carol", nil, "sync", "staging/web", true},
+		{"glob star crosses slashes", "bob", devs, "sync", "dev/team-a/api", true},
+		{"overlay file", "dan", []string{"my-org:release"}, "sync", "prod/prod-ns/api", true},
+		{"overlay object must match", "dan", []string{"my-org:release"}, "sync", "prod/api", false},
+		{"built-in admin", "admin", nil, "sync", "prod/api", true},
+	}
+	for _, tt := range tests {
+		if got := p.Enforce(tt.user, tt.groups, ResourceApplications, tt.action, tt.object); got != tt.want {
+			t.Errorf("%s: expected %t, got %t", tt.name, tt.want, got)
+		}
+	}
+}
+
+func TestParse_RegexMode(t *testing.T) {
+	p, err := Parse(map[string]string{
+		"policy.matchMode": "regex",
+		"policy.csv":       `p, alice, applications, (sync|get), (dev|qa)/.*, allow`,
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !p.Enforce("alice", nil, ResourceApplications, "sync", "qa/api") {
+		t.Error("expected the regex rule to allow qa/api")
+	}
+	if p.Enforce("alice", nil, ResourceApplications, "sync", "prod/api") {
+		t.Error("expected the regex rule to be anchored")
+	}
+}
+
+func TestParse_Invalid(t *testing.T) {
+	for _, data := range []map[string]string{
+		{"policy.csv": "p, alice, applications, sync"},
+		{"policy.csv": "g, alice"},
+		{"policy.csv": "x, alice, role:admin"},
+		{"policy.csv": "p, alice, applications, sync, */*, maybe"},
+		{"policy.matchMode": "exact"},
+		{"policy.matchMode": "regex", "policy.csv": "p, alice, applications, sync, (dev, allow"},
+	} {
+		if _, err := Parse(data); err == nil {
+			t.Errorf("expected an error for %v", data)
+		}
+	}
+}
+
+func TestEnforcer_AllowApp(t *testing.T) {
+	e := NewEnforcer("argocd")
+	if e.AllowApp("bob", nil, "sync", "dev", "argocd", "api") {
+		t.Fatal("expected the built-in policy to deny an unknown user")
+	}
+
+	p, _ := Parse(map[string]string{"policy.csv": `
+p, bob, applications, sync, dev/api, allow
+p, bob, applications, sync, dev/team-a/web, allow
+`})
+	e.SetPolicy(p)
+	if !e.AllowApp("bob", nil, "sync", "dev", "argocd", "api") {
+		t.Error("expected project/name for an application in the control namespace")
+	}
+	if !e.AllowApp("bob", nil, "sync", "dev", "team-a", "web") {
+		t.Error("expected project/namespace/name for an application in another namespace")
+	}
+	if e.AllowApp("bob", nil, "sync", "dev", "team-b", "api") {
+		t.Error("expected a policy for argocd/api not to match team-b/api")
+	}
+}
@@ -0,0 +1,112 @@
+package rbac
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"time"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/fields"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/watch"
+	"k8s.io/client-go/dynamic"
+
+	"github.com/behavox/argocd-book-plugin/internal/logging"
+)
+
+var configMapGVR = schema.GroupVersionResource{Version: "v1", Resource: "configmaps"}
+
+const defaultRetryInterval = 10 * time.Second
+
+// Watcher keeps an Enforcer's policy in sync with the argocd-rbac-cm ConfigMap.
+// A ConfigMap that fails to parse is logged and the previous policy kept; a
+// missing ConfigMap leaves only the built-in policy.
+type Watcher struct {
+	client    dynamic.Interface
+	namespace string
+	name      string
+	enforcer  *Enforcer
+	retry     time.Duration
+}
+
+// NewWatcher returns a Watcher loading the ConfigMap namespace/name into e.
+func NewWatcher(client dynamic.Interface, namespace, name string, e *Enforcer) *Watcher {
+	return &Watcher{client: client, namespace: namespace, name: name, enforcer: e, retry: defaultRetryInterval}
+}
+
+// Load reads the ConfigMap once and applies it, returning its resource version.
+func (w *Watcher) Load(ctx context.Context) (string, error) {
+	obj, err := w.client.Resource(configMapGVR).Namespace(w.namespace).Get(ctx, w.name, metav1.GetOptions{})
+	if apierrors.IsNotFound(err) {
+		slog.Warn("RBAC ConfigMap not found, using the built-in policy", logging.KeyNamespace, w.namespace, "configmap", w.name)
+		p, _ := Parse(nil)
+		w.enforcer.SetPolicy(p)
+		return "", nil
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to get RBAC ConfigMap %s/%s: %w", w.namespace, w.name, err)
+	}
+	w.apply(obj)
+	return obj.GetResourceVersion(), nil
+}
+
+// Run watches the ConfigMap and applies every change until ctx is cancelled,
+// re-listing after errors and when the watch is closed by the API server.
+func (w *Watcher) Run(ctx context.Context) {
+	for ctx.Err() == nil {
+		err := w.watch(ctx)
+		if err == nil {
+			continue
+		}
+		slog.Warn("RBAC policy watch failed, retrying", logging.KeyError, err)
+		select {
+		case <-ctx.Done():
+		case <-time.After(w.retry):
+		}
+	}
+}
+
+func (w *Watcher) watch(ctx context.Context) error {
+	rv, err := w.Load(ctx)
+	if err != nil {
+		return err
+	}
+	watcher, err := w.client.Resource(configMapGVR).Namespace(w.namespace).Watch(ctx, metav1.ListOptions{
+		FieldSelector:   fields.OneTermEqualSelector("metadata.name", w.name).String(),
+		ResourceVersion: rv,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to watch RBAC ConfigMap %s/%s: %w", w.namespace, w.name, err)
+	}
+	defer watcher.Stop()
+
+	for ev := range watcher.ResultChan() {
+		obj, ok := ev.Object.(*unstructured.Unstructured)
+		if !ok || obj.GetName() != w.name {
+			continue
+		}
+		switch ev.Type {
+		case watch.Added, watch.Modified:
+			w.apply(obj)
+		case watch.Deleted:
+			slog.Warn("RBAC ConfigMap deleted, using the built-in policy", logging.KeyNamespace, w.namespace, "configmap", w.name)
+			p, _ := Parse(nil)
+			w.enforcer.SetPolicy(p)
+		}
+	}
+	return nil
+}
+
+func (w *Watcher) apply(obj *unstructured.Unstructured) {
+	data, _, _ := unstructured.NestedStringMap(obj.Object, "data")
+	p, err := Parse(data)
+	if err != nil {
+		slog.Error("invalid RBAC policy, keeping the previous one", logging.KeyNamespace, w.namespace, "configmap", w.name, logging.KeyError, err)
+		return
+	}
+	w.enforcer.SetPolicy(p)
+	slog.Info("loaded RBAC policy", logging.KeyNamespace, w.namespace, "configmap", w.name, "resource_version", obj.GetResourceVersion())
+}
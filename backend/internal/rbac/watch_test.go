@@ -0,0 +1,80 @@
+package rbac
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	dynamicfake "k8s.io/client-go/dynamic/fake"
+)
+
+func newConfigMap(policy string) *unstructured.Unstructured {
+	cm := &unstructured.Unstructured{}
+	cm.SetAPIVersion("v1")
+	cm.SetKind("ConfigMap")
+	cm.SetNamespace("argocd")
+	cm.SetName("argocd-rbac-cm")
+	unstructured.SetNestedStringMap(cm.Object, map[string]string{"policy.csv": policy}, "data")
+	return cm
+}
+
+func waitFor(t *testing.T, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatal("timed out waiting for the policy to change")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestWatcher_ReloadsOnChange(t *testing.T) {
+	dyn := dynamicfake.NewSimpleDynamicClient(runtime.NewScheme(), newConfigMap("p, bob, applications, sync, dev/*, allow"))
+	e := NewEnforcer("argocd")
+	w := NewWatcher(dyn, "argocd", "argocd-rbac-cm", e)
+	w.retry = 10 * time.Millisecond
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	if _, err := w.Load(ctx); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !e.AllowApp("bob", nil, "sync", "dev", "argocd", "api") {
+		t.Fatal("expected the initial policy to be loaded")
+	}
+	go w.Run(ctx)
+
+	cms := dyn.Resource(configMapGVR).Namespace("argocd")
+	// Give Run time to establish its watch; the fake does not replay missed events.
+	time.Sleep(50 * time.Millisecond)
+	cms.Update(ctx, newConfigMap("p, bob, applications, sync, qa/*, allow"), metav1.UpdateOptions{})
+	waitFor(t, func() bool { return e.AllowApp("bob", nil, "sync", "qa", "argocd", "api") })
+	if e.AllowApp("bob", nil, "sync", "dev", "argocd", "api") {
+		t.Fatal("expected the old rule to be gone")
+	}
+
+	// An invalid policy is ignored.
+	cms.Update(ctx, newConfigMap("p, bob"), metav1.UpdateOptions{})
+	time.Sleep(50 * time.Millisecond)
+	if !e.AllowApp("bob", nil, "sync", "qa", "argocd", "api") {
+		t.Fatal("expected the previous policy to be kept")
+	}
+
+	cms.Delete(ctx, "argocd-rbac-cm", metav1.DeleteOptions{})
+	waitFor(t, func() bool { return !e.AllowApp("bob", nil, "sync", "qa", "argocd", "api") })
+}
+
+func TestWatcher_MissingConfigMap(t *testing.T) {
+	dyn := dynamicfake.NewSimpleDynamicClient(runtime.NewScheme())
+	e := NewEnforcer("argocd")
+	if _, err := NewWatcher(dyn, "argocd", "argocd-rbac-cm", e).Load(context.Background()); err != nil {
+		t.Fatalf("expected a missing ConfigMap to fall back to the built-in policy, got %v", err)
+	}
+	if !e.AllowApp("admin", nil, "sync", "prod", "argocd", "api") {
+		t.Fatal("expected the built-in admin role")
+	}
+}
@@ -0,0 +1,60 @@
+package tracing
+
+import (
+	"context"
+	"slices"
+	"testing"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+func TestNewProvider(t *testing.T) {
+	for env, want := range map[string]string{
+		"":                DefaultServiceName,
+		"booking-staging": "booking-staging",
+	} {
+		t.Setenv("OTEL_SERVICE_NAME", env)
+		exporter := tracetest.NewInMemoryExporter()
+		tp, err := NewProvider(exporter)
+		if err != nil {
+			t.Fatal(err)
+		}
+		_, span := tp.Tracer("test").Start(context.Background(), "op")
+		span.End()
+		if err := tp.ForceFlush(context.Background()); err != nil {
+			t.Fatal(err)
+		}
+
+		spans := exporter.GetSpans()
+		tp.Shutdown(context.Background())
+		if len(spans) != 1 || spans[0].Name != "op" {
+			t.Fatalf("expected the batched span to be flushed, got %v", spans)
+		}
+		var service string
+		for _, a := range spans[0].Resource.Attributes() {
+			if a.Key == "service.name" {
+				service = a.Value.AsString()
+			}
+		}
+		if service != want {
+			t.Errorf("OTEL_SERVICE_NAME=%q: expected service.name %q, got %q", env, want, service)
+		}
+	}
+}
+
+func TestSetup_Disabled(t *testing.T) {
+	t.Setenv("OTEL_EXPORTER_OTLP_ENDPOINT", "")
+	t.Setenv("OTEL_EXPORTER_OTLP_TRACES_ENDPOINT", "")
+	shutdown, err := Setup(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := shutdown(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	fields := otel.GetTextMapPropagator().Fields()
+	if !slices.Contains(fields, "traceparent") {
+		t.Fatalf("expected the W3C trace context propagator, got fields %v", fields)
+	}
+}
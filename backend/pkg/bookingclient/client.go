@@ -0,0 +1,457 @@
+// Package bookingclient is a typed Go client for the ArgoCD Book Plugin API
+// described by /api/v1/openapi.json.
+//
+// The client can go through the ArgoCD extension proxy, authenticating with an
+// ArgoCD token (see ProxyURL and WithToken), or call the backend service
+// directly, in which case the caller asserts the user identity itself (see
+// WithIdentity).
+package bookingclient
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	headerAppName    = "Argocd-Application-Name"
+	headerProject    = "Argocd-Project-Name"
+	headerUsername   = "Argocd-Username"
+	headerUserGroups = "Argocd-User-Groups"
+
+	defaultTimeout = 30 * time.Second
+)
+
+// Status is the booking status of a single application.
+type Status struct {
+	Booked   bool   `json:"booked"`
+	BookedBy string `json:"bookedBy,omitempty"`
+	BookedAt string `json:"bookedAt,omitempty"`
+}
+
+// Booking is a booked application as returned by List.
+type Booking struct {
+	AppName   string `json:"appName"`
+	Namespace string `json:"namespace"`
+	Project   string `json:"project,omitempty"`
+	BookedBy  string `json:"bookedBy"`
+	BookedAt  string `json:"bookedAt"`
+	ExpiresAt string `json:"expiresAt,omitempty"`
+	Reason    string `json:"reason,omitempty"`
+}
+
+// BookingList is a page of List results.
+type BookingList struct {
+	Items []Booking `json:"items"`
+	// Continue is passed back in ListOptions to fetch the next page; empty on the last page.
+	Continue string `json:"continue,omitempty"`
+	// Count summarises every matching booking across all pages.
+	Count BookingCount `json:"count"`
+}
+
+// BookingCount totals the bookings matching a List call.
+type BookingCount struct {
+	Total     int            `json:"total"`
+	ByProject map[string]int `json:"byProject"`
+	ByUser    map[string]int `json:"byUser"`
+}
+
+// ListOptions filters, sorts and pages List. Zero values are left to the server defaults.
+type ListOptions struct {
+	Namespace     string
+	User          string
+	Project       string
+	Selector      string // Kubernetes label selector on the Application labels
+	MinAge        time.Duration
+	MaxAge        time.Duration
+	ExpiresWithin time.Duration
+	Sort          string // bookedAt or appName, "-" prefix for descending
+	Limit         int
+	Continue      string
+}
+
+func (o ListOptions) query() url.Values {
+	q := url.Values{}
+	for k, v := range map[string]string{
+		"namespace": o.Namespace, "user": o.User, "project": o.Project,
+		"selector": o.Selector, "sort": o.Sort, "continue": o.Continue,
+	} {
+		if v != "" {
+			q.Set(k, v)
+		}
+	}
+	for k, d := range map[string]time.Duration{"minAge": o.MinAge, "maxAge": o.MaxAge, "expiresWithin": o.ExpiresWithin} {
+		if d > 0 {
+			q.Set(k, d.String())
+		}
+	}
+	if o.Limit > 0 {
+		q.Set("limit", strconv.Itoa(o.Limit))
+	}
+	return q
+}
+
+// Application is an application with its booking state, as returned by Applications.
+type Application struct {
+	Name        string            `json:"name"`
+	Namespace   string            `json:"namespace"`
+	Project     string            `json:"project,omitempty"`
+	Labels      map[string]string `json:"labels,omitempty"`
+	Destination struct {
+		Server    string `json:"server,omitempty"`
+		Name      string `json:"name,omitempty"`
+		Namespace string `json:"namespace,omitempty"`
+	} `json:"destination"`
+	SyncStatus   string   `json:"syncStatus,omitempty"`
+	HealthStatus string   `json:"healthStatus,omitempty"`
+	Booking      *Booking `json:"booking,omitempty"` // nil when free
+}
+
+// ApplicationList is a page of Applications results.
+type ApplicationList struct {
+	Items    []Application `json:"items"`
+	Continue string        `json:"continue,omitempty"`
+	Count    struct {
+		Total     int            `json:"total"`
+		Booked    int            `json:"booked"`
+		Free      int            `json:"free"`
+		ByProject map[string]int `json:"byProject"`
+	} `json:"count"`
+}
+
+// ApplicationOptions filters, sorts and pages Applications.
+type ApplicationOptions struct {
+	ListOptions
+	State  string // "booked" or "free"
+	Sync   string // e.g. "Synced"
+	Health string // e.g. "Healthy"
+}
+
+// BookOptions holds the optional details of Book.
+type BookOptions struct {
+	// Reason is a free-text note shown to other users.
+	Reason string
+	// Duration, when positive, makes the booking lapse after that long.
+	Duration time.Duration
+	// Wait, when positive, makes the server hold the request until the
+	// application is free or Wait elapses, instead of failing with a conflict.
+	Wait time.Duration
+}
+
+// Error is a non-2xx response from the API.
+type Error struct {
+	StatusCode int
+	Message    string
+	// RequestID is the server's X-Request-Id for the request, which locates it
+	// in the server logs.
+	RequestID string
+}
+
+func (e *Error) Error() string {
+	if e.StatusCode >= 500 && e.RequestID != "" {
+		return fmt.Sprintf("%s (HTTP %d, request ID %s)", e.Message, e.StatusCode, e.RequestID)
+	}
+	return fmt.Sprintf("%s (HTTP %d)", e.Message, e.StatusCode)
+}
+
+// IsConflict reports whether err is a 409 response: the application is booked
+// by someone else, is not booked, or was modified concurrently.
+func IsConflict(err error) bool {
+	return hasStatus(err, http.StatusConflict)
+}
+
+// IsForbidden reports whether err is a 403 response.
+func IsForbidden(err error) bool {
+	return hasStatus(err, http.StatusForbidden)
+}
+
+func hasStatus(err error, code int) bool {
+	var apiErr *Error
+	return errors.As(err, &apiErr) && apiErr.StatusCode == code
+}
+
+// Client calls the booking API.
+type Client struct {
+	baseURL   string
+	token     string
+	project   string
+	anchorApp string
+	username  string
+	groups    []string
+	timeout   time.Duration
+	insecure  bool
+	dryRun    bool
+	http      *http.Client
+}
+
+// Option configures a Client.
+type Option func(*Client)
+
+// WithToken authenticates to the ArgoCD extension proxy with an ArgoCD auth token.
+func WithToken(token string) Option {
+	return func(c *Client) { c.token = token }
+}
+
+// WithProject sets the Argocd-Project-Name header, which the ArgoCD proxy requires.
+func WithProject(project string) Option {
+	return func(c *Client) { c.project = project }
+}
+
+// WithAnchorApp sets the application, as "namespace:name", sent in the
+// Argocd-Application-Name header of List, ListAll, Applications and Calendar,
+// which concern no single application. The ArgoCD proxy authorises every
+// request against one application and rejects requests naming none, so these
+// calls need it behind the proxy; any application the caller can read will do.
+func WithAnchorApp(app string) Option {
+	return func(c *Client) { c.anchorApp = app }
+}
+
+// WithIdentity sets the user headers for direct calls to the backend. Behind the
+// ArgoCD proxy these headers are overwritten with the authenticated identity.
+func WithIdentity(username string, groups ...string) Option {
+	return func(c *Client) {
+		c.username = username
+		c.groups = groups
+	}
+}
+
+// WithTimeout sets the per-request timeout (30s by default).
+func WithTimeout(d time.Duration) Option {
+	return func(c *Client) { c.timeout = d }
+}
+
+// WithInsecureSkipVerify disables TLS certificate verification.
+func WithInsecureSkipVerify() Option {
+	return func(c *Client) { c.insecure = true }
+}
+
+// WithDryRun makes Book, Unbook and Transfer run every server-side check,
+// including a dry-run patch of the Application, without changing anything.
+// They fail exactly as the real calls would.
+func WithDryRun() Option {
+	return func(c *Client) { c.dryRun = true }
+}
+
+// WithHTTPClient replaces the default HTTP client. WithTimeout and
+// WithInsecureSkipVerify are ignored when it is used.
+func WithHTTPClient(hc *http.Client) Option {
+	return func(c *Client) { c.http = hc }
+}
+
+// ProxyURL returns the base URL of the named extension behind an ArgoCD server,
+// e.g. ProxyURL("argocd.example.com", "booking").
+func ProxyURL(server, extension string) string {
+	if !strings.Contains(server, "://") {
+		server = "https://" + server
+	}
+	return strings.TrimRight(server, "/") + "/extensions/" + extension
+}
+
+// New creates a Client for the API rooted at baseURL.
+func New(baseURL string, opts ...Option) *Client {
+	c := &Client{
+		baseURL: strings.TrimRight(baseURL, "/"),
+		project: "default",
+		timeout: defaultTimeout,
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+	if c.http == nil {
+		transport := http.DefaultTransport.(*http.Transport).Clone()
+		if c.insecure {
+			transport.TLSClientConfig = &tls.Config{InsecureSkipVerify: true}
+		}
+		c.http = &http.Client{Transport: transport, Timeout: c.timeout}
+	}
+	return c
+}
+
+// Status returns the booking status of app, given as "namespace:name".
+func (c *Client) Status(ctx context.Context, app string) (Status, error) {
+	var s Status
+	err := c.do(ctx, c.http, http.MethodGet, "/api/v1/status", nil, app, nil, &s)
+	return s, err
+}
+
+// Book books app for the caller. With a positive opts.Wait the HTTP timeout is
+// extended by the wait.
+func (c *Client) Book(ctx context.Context, app string, opts BookOptions) error {
+	body := c.mutation()
+	if opts.Reason != "" {
+		body["reason"] = opts.Reason
+	}
+	if opts.Duration > 0 {
+		body["duration"] = opts.Duration.String()
+	}
+	hc := c.http
+	if opts.Wait > 0 {
+		body["wait"] = opts.Wait.String()
+		extended := *c.http
+		if extended.Timeout > 0 {
+			extended.Timeout += opts.Wait
+		}
+		hc = &extended
+	}
+	return c.do(ctx, hc, http.MethodPost, "/api/v1/book", nil, app, body, nil)
+}
+
+// Unbook releases app. Only the holder or an admin may do so.
+func (c *Client) Unbook(ctx context.Context, app string) error {
+	return c.do(ctx, c.http, http.MethodPost, "/api/v1/unbook", nil, app, c.mutation(), nil)
+}
+
+// Transfer hands the booking of app over to user to.
+func (c *Client) Transfer(ctx context.Context, app, to string) error {
+	body := c.mutation()
+	body["to"] = to
+	return c.do(ctx, c.http, http.MethodPost, "/api/v1/transfer", nil, app, body, nil)
+}
+
+// mutation returns the base body of a book, unbook or transfer request.
+func (c *Client) mutation() map[string]interface{} {
+	body := map[string]interface{}{}
+	if c.dryRun {
+		body["dryRun"] = true
+	}
+	return body
+}
+
+// List returns a page of booked applications. Pass the returned Continue in
+// opts.Continue, with the other options unchanged, to fetch the next page.
+// Behind the ArgoCD proxy it needs WithAnchorApp, as do Applications and
+// Calendar.
+func (c *Client) List(ctx context.Context, opts ListOptions) (BookingList, error) {
+	var list BookingList
+	err := c.do(ctx, c.http, http.MethodGet, "/api/v1/list", opts.query(), c.anchorApp, nil, &list)
+	return list, err
+}
+
+// ListAll follows List continuations and returns every matching booking.
+func (c *Client) ListAll(ctx context.Context, opts ListOptions) ([]Booking, error) {
+	var all []Booking
+	for {
+		page, err := c.List(ctx, opts)
+		if err != nil {
+			return nil, err
+		}
+		all = append(all, page.Items...)
+		if page.Continue == "" {
+			return all, nil
+		}
+		opts.Continue = page.Continue
+	}
+}
+
+// Applications returns a page of applications, booked or free, with their
+// destination, sync and health status.
+func (c *Client) Applications(ctx context.Context, opts ApplicationOptions) (ApplicationList, error) {
+	q := opts.query()
+	for k, v := range map[string]string{"state": opts.State, "sync": opts.Sync, "health": opts.Health} {
+		if v != "" {
+			q.Set(k, v)
+		}
+	}
+	var list ApplicationList
+	err := c.do(ctx, c.http, http.MethodGet, "/api/v1/applications", q, c.anchorApp, nil, &list)
+	return list, err
+}
+
+// Calendar returns the iCalendar feed of bookings. Empty filters are ignored.
+func (c *Client) Calendar(ctx context.Context, namespace, project, user string) (string, error) {
+	query := url.Values{}
+	for k, v := range map[string]string{"namespace": namespace, "project": project, "user": user} {
+		if v != "" {
+			query.Set(k, v)
+		}
+	}
+	var raw rawBody
+	err := c.do(ctx, c.http, http.MethodGet, "/api/v1/calendar.ics", query, c.anchorApp, nil, &raw)
+	return string(raw), err
+}
+
+// rawBody receives a response body verbatim instead of decoding it as JSON.
+type rawBody []byte
+
+func (c *Client) do(ctx context.Context, hc *http.Client, method, path string, query url.Values, app string, in, out interface{}) error {
+	u := c.baseURL + path
+	if len(query) > 0 {
+		u += "?" + query.Encode()
+	}
+	var body io.Reader
+	if in != nil {
+		b, err := json.Marshal(in)
+		if err != nil {
+			return fmt.Errorf("failed to encode request: %w", err)
+		}
+		body = bytes.NewReader(b)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, u, body)
+	if err != nil {
+		return err
+	}
+	if in != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if c.token != "" {
+		req.Header.Set("Authorization", "Bearer "+c.token)
+	}
+	if c.project != "" {
+		req.Header.Set(headerProject, c.project)
+	}
+	if c.username != "" {
+		req.Header.Set(headerUsername, c.username)
+	}
+	if len(c.groups) > 0 {
+		req.Header.Set(headerUserGroups, strings.Join(c.groups, ","))
+	}
+	if app != "" {
+		req.Header.Set(headerAppName, app)
+	}
+
+	resp, err := hc.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	respBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return fmt.Errorf("failed to read response: %w", err)
+	}
+	if resp.StatusCode >= 300 {
+		var e struct {
+			Error string `json:"error"`
+		}
+		msg := strings.TrimSpace(string(respBody))
+		if json.Unmarshal(respBody, &e) == nil && e.Error != "" {
+			msg = e.Error
+		}
+		if msg == "" {
+			msg = resp.Status
+		}
+		return &Error{StatusCode: resp.StatusCode, Message: msg, RequestID: resp.Header.Get("X-Request-Id")}
+	}
+
+	switch out := out.(type) {
+	case nil:
+		return nil
+	case *rawBody:
+		*out = respBody
+		return nil
+	default:
+		if err := json.Unmarshal(respBody, out); err != nil {
+			return fmt.Errorf("failed to decode response: %w", err)
+		}
+		return nil
+	}
+}
@@ -0,0 +1,242 @@
+package bookingclient
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	dynamicfake "k8s.io/client-go/dynamic/fake"
+
+	"github.com/behavox/argocd-book-plugin/internal/handler"
+	"github.com/behavox/argocd-book-plugin/internal/k8s"
+)
+
+func newFakeApp(namespace, name string) *unstructured.Unstructured {
+	app := &unstructured.Unstructured{}
+	app.SetGroupVersionKind(schema.GroupVersionKind{Group: "argoproj.io", Version: "v1alpha1", Kind: "Application"})
+	app.SetNamespace(namespace)
+	app.SetName(name)
+	return app
+}
+
+// newServer runs the real handler against a fake Kubernetes API.
+func newServer(t *testing.T, apps ...string) *httptest.Server {
+	var objects []runtime.Object
+	for _, name := range apps {
+		objects = append(objects, newFakeApp("argocd", name))
+	}
+	fakeDyn := dynamicfake.NewSimpleDynamicClientWithCustomListKinds(runtime.NewScheme(),
+		map[schema.GroupVersionResource]string{
+			{Group: "argoproj.io", Version: "v1alpha1", Resource: "applications"}: "ApplicationList",
+		},
+		objects...,
+	)
+	mux := http.NewServeMux()
+	handler.New(k8s.NewClientFromDynamic(fakeDyn)).RegisterRoutes(mux)
+	srv := httptest.NewServer(mux)
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func TestClient_AgainstHandler(t *testing.T) {
+	srv := newServer(t, "app1", "app2")
+	ctx := context.Background()
+	alice := New(srv.URL, WithIdentity("alice"))
+	bob := New(srv.URL, WithIdentity("bob"))
+	admin := New(srv.URL, WithIdentity("root", "ops", "admin"))
+
+	if err := alice.Book(ctx, "argocd:app1", BookOptions{Reason: "demo", Duration: time.Hour}); err != nil {
+		t.Fatalf("book: %v", err)
+	}
+	s, err := bob.Status(ctx, "argocd:app1")
+	if err != nil {
+		t.Fatalf("status: %v", err)
+	}
+	if !s.Booked || s.BookedBy != "alice" {
+		t.Fatalf("unexpected status: %+v", s)
+	}
+
+	if err := bob.Book(ctx, "argocd:app1", BookOptions{}); !IsConflict(err) {
+		t.Fatalf("expected conflict, got %v", err)
+	}
+	if err := bob.Unbook(ctx, "argocd:app1"); !IsForbidden(err) {
+		t.Fatalf("expected forbidden, got %v", err)
+	}
+	bookings, err := bob.ListAll(ctx, ListOptions{Namespace: "argocd"})
+	if err != nil {
+		t.Fatalf("list: %v", err)
+	}
+	if len(bookings) != 1 || bookings[0].Reason != "demo" || bookings[0].ExpiresAt == "" {
+		t.Fatalf("expected reason and expiry in list, got %+v", bookings)
+	}
+
+	if err := alice.Transfer(ctx, "argocd:app1", "bob"); err != nil {
+		t.Fatalf("transfer: %v", err)
+	}
+
+	page, err := bob.List(ctx, ListOptions{Namespace: "argocd", User: "bob", Limit: 10})
+	if err != nil {
+		t.Fatalf("list: %v", err)
+	}
+	if len(page.Items) != 1 || page.Items[0].BookedBy != "bob" || page.Items[0].AppName != "app1" || page.Count.Total != 1 {
+		t.Fatalf("unexpected bookings: %+v", page)
+	}
+
+	apps, err := bob.Applications(ctx, ApplicationOptions{State: "free"})
+	if err != nil {
+		t.Fatalf("applications: %v", err)
+	}
+	if len(apps.Items) != 1 || apps.Items[0].Name != "app2" || apps.Count.Free != 1 {
+		t.Fatalf("unexpected applications: %+v", apps)
+	}
+
+	ics, err := bob.Calendar(ctx, "argocd", "", "bob")
+	if err != nil {
+		t.Fatalf("calendar: %v", err)
+	}
+	if !strings.Contains(ics, "BEGIN:VEVENT") {
+		t.Fatalf("expected an event in calendar:\n%s", ics)
+	}
+
+	if err := admin.Unbook(ctx, "argocd:app1"); err != nil {
+		t.Fatalf("admin unbook: %v", err)
+	}
+}
+
+func TestClient_ProxyHeaders(t *testing.T) {
+	var got http.Header
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = r.Header.Clone()
+		if r.URL.Path != "/extensions/booking/api/v1/status" {
+			t.Errorf("unexpected path %s", r.URL.Path)
+		}
+		w.Write([]byte(`{"booked":false}`))
+	}))
+	defer srv.Close()
+
+	c := New(ProxyURL(srv.URL, "booking"), WithToken("tok"), WithProject("staging"))
+	if _, err := c.Status(context.Background(), "argocd:app1"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got.Get("Authorization") != "Bearer tok" {
+		t.Errorf("expected bearer token, got %q", got.Get("Authorization"))
+	}
+	if got.Get(headerProject) != "staging" || got.Get(headerAppName) != "argocd:app1" {
+		t.Errorf("unexpected proxy headers: %v", got)
+	}
+}
+
+func TestClient_DryRun(t *testing.T) {
+	var bodies []map[string]interface{}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body map[string]interface{}
+		json.NewDecoder(r.Body).Decode(&body)
+		bodies = append(bodies, body)
+		w.Write([]byte(`{"status":"booked","dryRun":true}`))
+	}))
+	defer srv.Close()
+
+	ctx := context.Background()
+	c := New(srv.URL, WithDryRun())
+	c.Book(ctx, "argocd:app1", BookOptions{Reason: "ci"})
+	c.Unbook(ctx, "argocd:app1")
+	c.Transfer(ctx, "argocd:app1", "bob")
+	New(srv.URL).Unbook(ctx, "argocd:app1")
+
+	if len(bodies) != 4 {
+		t.Fatalf("expected 4 requests, got %d", len(bodies))
+	}
+	for i, body := range bodies[:3] {
+		if body["dryRun"] != true {
+			t.Errorf("request %d: expected dryRun in %v", i, body)
+		}
+	}
+	if bodies[0]["reason"] != "ci" || bodies[2]["to"] != "bob" {
+		t.Errorf("expected the other fields to be kept, got %v", bodies)
+	}
+	if _, ok := bodies[3]["dryRun"]; ok {
+		t.Errorf("expected no dryRun without WithDryRun, got %v", bodies[3])
+	}
+}
+
+func TestClient_ErrorCarriesRequestID(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("X-Request-Id", "req-42")
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write([]byte(`{"error":"failed to get booking status","requestId":"req-42"}`))
+	}))
+	defer srv.Close()
+
+	_, err := New(srv.URL).Status(context.Background(), "argocd:app1")
+	e, ok := err.(*Error)
+	if !ok || e.RequestID != "req-42" || !strings.Contains(err.Error(), "request ID req-42") {
+		t.Fatalf("expected an error carrying the request ID, got %v", err)
+	}
+}
+
+func TestClient_AnchorApp(t *testing.T) {
+	var apps []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		apps = append(apps, r.Header.Get(headerAppName))
+		if strings.HasSuffix(r.URL.Path, ".ics") {
+			w.Write([]byte("BEGIN:VCALENDAR\r\nEND:VCALENDAR\r\n"))
+			return
+		}
+		w.Write([]byte(`{"items":[]}`))
+	}))
+	defer srv.Close()
+
+	c := New(ProxyURL(srv.URL, "booking"), WithToken("tok"), WithAnchorApp("argocd:app1"))
+	ctx := context.Background()
+	if _, err := c.List(ctx, ListOptions{}); err != nil {
+		t.Fatalf("list: %v", err)
+	}
+	if _, err := c.Applications(ctx, ApplicationOptions{}); err != nil {
+		t.Fatalf("applications: %v", err)
+	}
+	if _, err := c.Calendar(ctx, "", "", ""); err != nil {
+		t.Fatalf("calendar: %v", err)
+	}
+	if len(apps) != 3 {
+		t.Fatalf("expected 3 requests, got %d", len(apps))
+	}
+	for i, app := range apps {
+		if app != "argocd:app1" {
+			t.Errorf("request %d: expected the anchor application in %s, got %q", i, headerAppName, app)
+		}
+	}
+}
+
+func TestClient_ListAllFollowsContinue(t *testing.T) {
+	srv := newServer(t, "app1", "app2", "app3")
+	ctx := context.Background()
+	for _, app := range []string{"app1", "app2", "app3"} {
+		if err := New(srv.URL, WithIdentity("alice")).Book(ctx, "argocd:"+app, BookOptions{}); err != nil {
+			t.Fatalf("book %s: %v", app, err)
+		}
+	}
+
+	bookings, err := New(srv.URL).ListAll(ctx, ListOptions{Namespace: "argocd", Sort: "appName", Limit: 2})
+	if err != nil {
+		t.Fatalf("list: %v", err)
+	}
+	if len(bookings) != 3 || bookings[0].AppName != "app1" || bookings[2].AppName != "app3" {
+		t.Fatalf("unexpected bookings: %+v", bookings)
+	}
+}
+
+func TestProxyURL(t *testing.T) {
+	if got := ProxyURL("argocd.example.com/", "booking"); got != "https://argocd.example.com/extensions/booking" {
+		t.Fatalf("unexpected proxy URL %q", got)
+	}
+	if got := ProxyURL("http://localhost:8080", "booking"); got != "http://localhost:8080/extensions/booking" {
+		t.Fatalf("unexpected proxy URL %q", got)
+	}
+}
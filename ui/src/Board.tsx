@@ -0,0 +1,162 @@
+import * as React from 'react';
+import { AppRef, Application, Board as BoardData, bookApp, findAnchorApp, getBoard, unbookApp } from './api';
+
+const { useState, useEffect } = React;
+
+const REFRESH_INTERVAL_MS = 30000;
+
+// age renders the time since an RFC 3339 timestamp as "3d 4h", "2h 5m" or "12m".
+function age(since: string): string {
+  const ms = Date.now() - new Date(since).getTime();
+  if (isNaN(ms) || ms < 0) return '';
+  const minutes = Math.floor(ms / 60000);
+  const hours = Math.floor(minutes / 60);
+  const days = Math.floor(hours / 24);
+  if (days > 0) return `${days}d ${hours % 24}h`;
+  if (hours > 0) return `${hours}h ${minutes % 60}m`;
+  return `${minutes}m`;
+}
+
+const cardStyle: React.CSSProperties = {
+  border: '1px solid #dee6eb',
+  borderRadius: '4px',
+  padding: '10px 12px',
+  background: '#fff',
+  display: 'flex',
+  flexDirection: 'column',
+  gap: '4px',
+};
+
+const AppCard: React.FC<{ app: Application; busy: boolean; onToggle: (app: Application) => void }> = ({ app, busy, onToggle }) => {
+  const booking = app.booking;
+  return (
+    <div style={{ ...cardStyle, borderLeft: `4px solid ${booking ? '#e96d76' : '#18be94'}` }}>
+      <div style={{ display: 'flex', justifyContent: 'space-between', alignItems: 'center' }}>
+        <a href={`/applications/${app.namespace}/${app.name}`} style={{ fontWeight: 600 }}>{app.name}</a>
+        <span style={{ color: '#8fa4b5', fontSize: '12px' }}>
+          {[app.syncStatus, app.healthStatus].filter(Boolean).join(' / ')}
+        </span>
+      </div>
+      {booking ? (
+        <div style={{ fontSize: '13px' }}>
+          <i className="fa fa-lock" /> {booking.bookedBy}
+          <span style={{ color: '#8fa4b5' }}> for {age(booking.bookedAt)}</span>
+          {booking.reason && (
+            <div style={{ color: '#495763', whiteSpace: 'pre-wrap' }} title={booking.reason}>{booking.reason}</div>
+          )}
+        </div>
+      ) : (
+        <div style={{ fontSize: '13px', color: '#18be94' }}>
+          <i className="fa fa-unlock" /> Free
+        </div>
+      )}
+      <div>
+        <button
+          className="argo-button argo-button--base"
+          disabled={busy}
+          onClick={() => onToggle(app)}
+          title={booking ? 'Only the holder or an admin can unbook' : 'Book this application for exclusive use'}
+        >
+          {busy ? '...' : booking ? 'Unbook' : 'Book'}
+        </button>
+      </div>
+    </div>
+  );
+};
+
+export const Board: React.FC<any> = () => {
+  const [anchor, setAnchor] = useState<AppRef | null>(null);
+  const [board, setBoard] = useState<BoardData | null>(null);
+  const [groupBy, setGroupBy] = useState('project');
+  const [labelKey, setLabelKey] = useState('');
+  const [busy, setBusy] = useState<string | null>(null);
+  const [error, setError] = useState<string | null>(null);
+
+  const effectiveGroupBy = groupBy === 'label' && labelKey ? `label:${labelKey}` : 'project';
+
+  const refresh = async (target: AppRef | null = anchor) => {
+    if (!target) return;
+    try {
+      setBoard(await getBoard(target, effectiveGroupBy));
+      setError(null);
+    } catch (e: any) {
+      setError(e.message);
+    }
+  };
+
+  useEffect(() => {
+    findAnchorApp()
+      .then((a) => {
+        if (!a) {
+          setError('No applications visible to you');
+          return;
+        }
+        setAnchor(a);
+      })
+      .catch((e: any) => setError(e.message));
+  }, []);
+
+  useEffect(() => {
+    refresh();
+    const timer = setInterval(() => refresh(), REFRESH_INTERVAL_MS);
+    return () => clearInterval(timer);
+  }, [anchor, effectiveGroupBy]);
+
+  const handleToggle = async (app: Application) => {
+    const id = `${app.namespace}:${app.name}`;
+    setBusy(id);
+    try {
+      if (app.booking) {
+        await unbookApp(id, app.project || 'default');
+      } else {
+        await bookApp(id, app.project || 'default');
+      }
+      await refresh();
+    } catch (e: any) {
+      setError(e.message);
+    } finally {
+      setBusy(null);
+    }
+  };
+
+  return (
+    <div style={{ padding: '20px' }}>
+      <div style={{ display: 'flex', alignItems: 'center', gap: '12px', marginBottom: '16px' }}>
+        <h3 style={{ margin: 0 }}>Environments</h3>
+        <label>
+          Group by{' '}
+          <select value={groupBy} onChange={(e) => setGroupBy(e.target.value)}>
+            <option value="project">Project</option>
+            <option value="label">Label</option>
+          </select>
+        </label>
+        {groupBy === 'label' && (
+          <input placeholder="label key, e.g. env" value={labelKey} onChange={(e) => setLabelKey(e.target.value.trim())} />
+        )}
+        <button className="argo-button argo-button--base-o" onClick={() => refresh()}>
+          <i className="fa fa-redo" /> Refresh
+        </button>
+      </div>
+      {error && <div style={{ color: '#e96d76', marginBottom: '12px' }}>{error}</div>}
+      {!board && !error && <span style={{ color: '#8fa4b5' }}>Loading...</span>}
+      {board?.groups.map((g) => (
+        <div key={g.name} style={{ marginBottom: '24px' }}>
+          <h4 style={{ marginBottom: '8px' }}>
+            {g.name || <em>Ungrouped</em>}
+            <span style={{ color: '#8fa4b5', fontWeight: 'normal' }}> · {g.free} free, {g.booked} booked</span>
+          </h4>
+          <div style={{ display: 'grid', gridTemplateColumns: 'repeat(auto-fill, minmax(240px, 1fr))', gap: '12px' }}>
+            {g.applications.map((app) => (
+              <AppCard
+                key={`${app.namespace}/${app.name}`}
+                app={app}
+                busy={busy === `${app.namespace}:${app.name}`}
+                onToggle={handleToggle}
+              />
+            ))}
+          </div>
+        </div>
+      ))}
+    </div>
+  );
+};
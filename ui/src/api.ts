@@ -1,4 +1,5 @@
 const EXTENSION_BASE = '/extensions/booking';
+const API_BASE = `${EXTENSION_BASE}/api/v1`;
 
 export interface BookingStatus {
   booked: boolean;
@@ -6,6 +7,52 @@ export interface BookingStatus {
   bookedAt?: string;
 }
 
+export interface BookOptions {
+  reason?: string;
+  duration?: string; // Go duration, e.g. "2h"
+}
+
+export interface Booking {
+  appName: string;
+  namespace: string;
+  project?: string;
+  bookedBy: string;
+  bookedAt: string;
+  expiresAt?: string;
+  reason?: string;
+}
+
+export interface Application {
+  name: string;
+  namespace: string;
+  project?: string;
+  labels?: Record<string, string>;
+  destination: { server?: string; name?: string; namespace?: string };
+  syncStatus?: string;
+  healthStatus?: string;
+  booking?: Booking;
+}
+
+export interface BoardGroup {
+  name: string; // empty for applications without the grouping label
+  booked: number;
+  free: number;
+  applications: Application[];
+}
+
+export interface Board {
+  groupBy: string;
+  groups: BoardGroup[];
+  generatedAt: string;
+}
+
+// The proxy authorises every extension request against one application, so
+// aggregate calls carry the identity of any application the user can see.
+export interface AppRef {
+  appName: string; // "namespace:name"
+  project: string;
+}
+
 let cachedUsername: string | null = null;
 
 async function getUsername(): Promise<string> {
@@ -25,7 +72,7 @@ function authFetch(url: string, opts: RequestInit = {}): Promise<Response> {
 }
 
 export async function getStatus(appName: string, project: string): Promise<BookingStatus> {
-  const resp = await authFetch(`${EXTENSION_BASE}/api/status`, {
+  const resp = await authFetch(`${API_BASE}/status`, {
     headers: {
       'Argocd-Application-Name': appName,
       'Argocd-Project-Name': project,
@@ -37,15 +84,17 @@ export async function getStatus(appName: string, project: string): Promise<Booki
   return resp.json();
 }
 
-export async function bookApp(appName: string, project: string): Promise<void> {
+export async function bookApp(appName: string, project: string, opts: BookOptions = {}): Promise<void> {
   const username = await getUsername();
-  const resp = await authFetch(`${EXTENSION_BASE}/api/book`, {
+  const resp = await authFetch(`${API_BASE}/book`, {
     method: 'POST',
     headers: {
       'Argocd-Application-Name': appName,
       'Argocd-Project-Name': project,
       'Argocd-Username': username,
+      'Content-Type': 'application/json',
     },
+    body: JSON.stringify(opts),
   });
   if (!resp.ok) {
     const body = await resp.json().catch(() => ({}));
@@ -55,7 +104,7 @@ export async function bookApp(appName: string, project: string): Promise<void> {
 
 export async function unbookApp(appName: string, project: string): Promise<void> {
   const username = await getUsername();
-  const resp = await authFetch(`${EXTENSION_BASE}/api/unbook`, {
+  const resp = await authFetch(`${API_BASE}/unbook`, {
     method: 'POST',
     headers: {
       'Argocd-Application-Name': appName,
@@ -68,3 +117,31 @@ export async function unbookApp(appName: string, project: string): Promise<void>
     throw new Error(body.error || `Failed to unbook: ${resp.statusText}`);
   }
 }
+
+// findAnchorApp returns an application the current user can read, used as the
+// proxy target for requests that are not about a single application.
+export async function findAnchorApp(): Promise<AppRef | null> {
+  const fields = 'items.metadata.name,items.metadata.namespace,items.spec.project';
+  const resp = await authFetch(`/api/v1/applications?fields=${fields}`);
+  if (!resp.ok) {
+    throw new Error(`Failed to list applications: ${resp.statusText}`);
+  }
+  const body = await resp.json();
+  const app = (body.items || [])[0];
+  if (!app) return null;
+  return { appName: `${app.metadata.namespace}:${app.metadata.name}`, project: app.spec.project };
+}
+
+export async function getBoard(anchor: AppRef, groupBy = 'project'): Promise<Board> {
+  const resp = await authFetch(`${API_BASE}/board?groupBy=${encodeURIComponent(groupBy)}`, {
+    headers: {
+      'Argocd-Application-Name': anchor.appName,
+      'Argocd-Project-Name': anchor.project,
+    },
+  });
+  if (!resp.ok) {
+    const body = await resp.json().catch(() => ({}));
+    throw new Error(body.error || `Failed to load board: ${resp.statusText}`);
+  }
+  return resp.json();
+}
@@ -1,3 +1,4 @@
+import { Board } from './Board';
 import { BookButton } from './BookButton';
 import { StatusPanel } from './StatusPanel';
 
@@ -24,4 +25,12 @@ import { StatusPanel } from './StatusPanel';
     'Booking',
     'booking-status',
   );
+
+  // Register the environment board as a top-level page
+  extensionsAPI.registerSystemLevelExtension(
+    Board,
+    'Environments',
+    '/environments',
+    'fa-th-large',
+  );
 })(window);